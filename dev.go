@@ -0,0 +1,188 @@
+//go:build dev
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/confluence/confluencetest"
+	"my-scheduler-go/internal/jira/jiratest"
+	"my-scheduler-go/internal/mattermost/mattermosttest"
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/scheduler"
+)
+
+// devEnvironment 开发模式下在进程内运行的模拟Mattermost、Confluence和Jira服务器
+//
+// 只在使用dev构建标签(go build -tags dev)时编译，发布构建不包含模拟服务器。
+type devEnvironment struct {
+	mattermost   *mattermosttest.Server
+	confluence   *confluencetest.Server
+	jira         *jiratest.Server
+	stopScenario context.CancelFunc
+}
+
+// startDevEnvironment 启动模拟服务器，并将配置中的服务器地址指向它们
+func startDevEnvironment(appConfig *config.AppConfig) (*devEnvironment, error) {
+	env := &devEnvironment{stopScenario: func() {}}
+	var err error
+
+	// 模拟Mattermost服务器，替代真实服务器
+	env.mattermost, err = mattermosttest.NewServer(appConfig.Mattermost.Token)
+	if err != nil {
+		env.Close()
+		return nil, fmt.Errorf("failed to start fake Mattermost server: %v", err)
+	}
+	appConfig.Mattermost.ServerURL = env.mattermost.URL
+	log.Printf("[main] Fake Mattermost server started at %s", env.mattermost.URL)
+
+	// 模拟Confluence服务器，预置配置页面和报告页面
+	env.confluence, err = confluencetest.NewServer(appConfig.Confluence.Username, appConfig.Confluence.Password, appConfig.Confluence.Token)
+	if err != nil {
+		env.Close()
+		return nil, fmt.Errorf("failed to start fake Confluence server: %v", err)
+	}
+	appConfig.Confluence.URL = env.confluence.URL
+	env.confluence.AddPage(appConfig.Confluence.MainPageID, "Scheduler Configuration", "<p></p>")
+	env.confluence.AddPage(appConfig.Confluence.ResultsPage, "Task Execution Report", "<p></p>")
+	log.Printf("[main] Fake Confluence server started at %s", env.confluence.URL)
+
+	// 模拟Jira服务器，预置示例项目SCHED；所有Jira环境都指向它
+	env.jira, err = jiratest.NewServer(appConfig.Jira.Username, appConfig.Jira.Password, appConfig.Jira.Token)
+	if err != nil {
+		env.Close()
+		return nil, fmt.Errorf("failed to start fake Jira server: %v", err)
+	}
+	appConfig.Jira.URL = env.jira.URL
+	for name, jiraEnv := range appConfig.Jira.Environments {
+		jiraEnv.URL, jiraEnv.Username, jiraEnv.Password, jiraEnv.Token = env.jira.URL, appConfig.Jira.Username, appConfig.Jira.Password, appConfig.Jira.Token
+		appConfig.Jira.Environments[name] = jiraEnv
+	}
+	root := env.jira.SeedSampleProject("SCHED", 60)
+	log.Printf("[main] Fake Jira server started at %s (sample root ticket %s)", env.jira.URL, root)
+
+	return env, nil
+}
+
+// Start 创建示例任务，并在模拟Mattermost服务器上回放场景
+func (env *devEnvironment) Start(appConfig *config.AppConfig, sched *scheduler.SchedulerService, configService *scheduler.ConfigurationService) {
+	createExampleTasks(sched)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	env.stopScenario = cancel
+	go runFakeScenario(ctx, env.mattermost, appConfig, configService)
+}
+
+// Close 停止场景回放和所有模拟服务器
+func (env *devEnvironment) Close() {
+	env.stopScenario()
+
+	if env.mattermost != nil {
+		_ = env.mattermost.Close()
+		log.Println("[main] Fake Mattermost server stopped")
+	}
+	if env.confluence != nil {
+		_ = env.confluence.Close()
+		log.Println("[main] Fake Confluence server stopped")
+	}
+	if env.jira != nil {
+		_ = env.jira.Close()
+		log.Println("[main] Fake Jira server stopped")
+	}
+}
+
+// runFakeScenario 在模拟Mattermost服务器上回放配置的场景文件，
+// 未配置时在第一个Mattermost配置的频道中回放内置的演示场景，使事件能匹配配置并生成任务
+func runFakeScenario(ctx context.Context, server *mattermosttest.Server, appConfig *config.AppConfig, configService *scheduler.ConfigurationService) {
+	demoChannel := appConfig.Mattermost.ChannelID
+	for _, configuration := range configService.ConfigurationsOfKind(scheduler.ConfigKindMattermost) {
+		if mmConfig, ok := configuration.(scheduler.MattermostConfig); ok && mmConfig.ChannelID != "" {
+			demoChannel = mmConfig.ChannelID
+			break
+		}
+	}
+	scenario := mattermosttest.DemoScenario(demoChannel)
+	if path := appConfig.Mattermost.FakeScenario; path != "" {
+		loaded, err := mattermosttest.LoadScenario(path)
+		if err != nil {
+			log.Printf("[main] Failed to load fake Mattermost scenario, using demo: %v", err)
+		} else {
+			scenario = loaded
+		}
+	}
+
+	if err := server.RunScenario(ctx, scenario); err != nil && err != context.Canceled {
+		log.Printf("[main] Fake Mattermost scenario stopped: %v", err)
+	}
+}
+
+// createExampleTasks 创建一些示例任务用于开发目的
+func createExampleTasks(sched *scheduler.SchedulerService) {
+	// Example 1: 即时Mattermost任务
+	mattermostTask := &models.Task{
+		Name:     "Mattermost消息处理示例",
+		TaskType: models.TypeImmediate,
+		Status:   models.StatusPending,
+		Priority: models.PriorityHigh,
+		Tags:     []string{"MATTERMOST"},
+		Parameters: map[string]interface{}{
+			"channel_id":   "channel1",
+			"message":      "这是一条测试消息",
+			"forward_type": "notification",
+			"event_type":   "posted",
+			"channel_name": "测试频道",
+			"username":     "测试用户",
+			"notify_admin": "true",
+		},
+	}
+
+	// Example 2: 定时Mattermost任务
+	mattermostScheduledTask := &models.Task{
+		Name:     "Mattermost定时通知示例",
+		TaskType: models.TypeScheduled,
+		CronExpr: "0 0 9 * * *", // 每天早上9点
+		Status:   models.StatusPending,
+		Priority: models.PriorityMedium,
+		Tags:     []string{"MATTERMOST"},
+		Parameters: map[string]interface{}{
+			"channel_id":        "channel1",
+			"message":           "这是一条定时发送的通知",
+			"forward_type":      "channel_message",
+			"target_channel_id": "channel456",
+		},
+	}
+
+	// Example 3: 即时Jira导出任务(导出模拟Jira服务器上的示例问题树)
+	jiraExportTask := &models.Task{
+		Name:     "Jira导出示例",
+		TaskType: models.TypeImmediate,
+		Status:   models.StatusPending,
+		Priority: models.PriorityMedium,
+		Tags:     []string{scheduler.TaskTagJiraExport},
+		Parameters: map[string]interface{}{
+			"key_type":  scheduler.JiraKeyTypeRootTicket,
+			"key_value": "SCHED-1",
+		},
+	}
+
+	// 添加任务
+	err := sched.AddTask(mattermostTask)
+	if err != nil {
+		log.Printf("[main] Failed to add example task 1: %v", err)
+	}
+
+	err = sched.AddTask(mattermostScheduledTask)
+	if err != nil {
+		log.Printf("[main] Failed to add example task 2: %v", err)
+	}
+
+	err = sched.AddTask(jiraExportTask)
+	if err != nil {
+		log.Printf("[main] Failed to add example task 3: %v", err)
+	}
+
+	log.Println("[main] Example tasks created")
+}
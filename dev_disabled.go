@@ -0,0 +1,26 @@
+//go:build !dev
+
+package main
+
+import (
+	"log"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/scheduler"
+)
+
+// devEnvironment 未使用dev构建标签时为空，发布构建不包含模拟服务器
+type devEnvironment struct{}
+
+// startDevEnvironment 发布构建中没有模拟服务器，开发模式下仍连接配置中的服务器
+func startDevEnvironment(appConfig *config.AppConfig) (*devEnvironment, error) {
+	log.Println("[main] Built without the dev tag: fake servers are not available, using the configured servers")
+	return nil, nil
+}
+
+// Start 发布构建中不创建示例任务
+func (env *devEnvironment) Start(appConfig *config.AppConfig, sched *scheduler.SchedulerService, configService *scheduler.ConfigurationService) {
+}
+
+// Close 发布构建中没有需要停止的模拟服务器
+func (env *devEnvironment) Close() {}
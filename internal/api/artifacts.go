@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"my-scheduler-go/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaskArtifacts lists the files produced by a task's runs, oldest first
+func (api *API) GetTaskArtifacts(c *gin.Context) {
+	taskID := c.Param("id")
+	artifacts, err := api.repo.GetTaskArtifacts(taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":   taskID,
+		"artifacts": artifacts,
+	})
+}
+
+// DownloadTaskArtifact sends a task artifact as an attachment
+func (api *API) DownloadTaskArtifact(c *gin.Context) {
+	if api.artifacts == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact storage not enabled"})
+		return
+	}
+
+	path, artifact, err := api.artifacts.Path(c.Param("id"), c.Param("name"))
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact not found"})
+		return
+	}
+
+	c.Header("Content-Type", artifact.ContentType)
+	c.FileAttachment(path, artifact.Name)
+}
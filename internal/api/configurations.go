@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"my-scheduler-go/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configurationEntry wraps a configuration with its kind for JSON output
+type configurationEntry struct {
+	Kind   string                  `json:"kind"`
+	Config scheduler.Configuration `json:"config"`
+}
+
+// GetConfigurations returns the current configuration version, optionally filtered by ?kind=
+func (api *API) GetConfigurations(c *gin.Context) {
+	if api.configService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Configuration service not enabled"})
+		return
+	}
+
+	snapshot := api.configService.CurrentSnapshot()
+	if snapshot == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"version":        0,
+			"configurations": []configurationEntry{},
+		})
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" {
+		if _, ok := scheduler.LookupConfigKind(kind); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown configuration kind: " + kind})
+			return
+		}
+	}
+
+	entries := make([]configurationEntry, 0, len(snapshot.Configurations))
+	for _, config := range snapshot.Configurations {
+		if kind != "" && config.ConfigKind() != kind {
+			continue
+		}
+		entries = append(entries, configurationEntry{Kind: config.ConfigKind(), Config: config})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":        snapshot.Version,
+		"updated_at":     snapshot.Time,
+		"from_cache":     snapshot.FromCache,
+		"last_rejection": api.configService.LastRejection(),
+		"configurations": entries,
+	})
+}
+
+// GetConfigurationHistory returns the retained configuration versions with their changes, newest first
+func (api *API) GetConfigurationHistory(c *gin.Context) {
+	if api.configService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Configuration service not enabled"})
+		return
+	}
+
+	history := api.configService.History()
+	versions := make([]gin.H, 0, len(history))
+	for _, snapshot := range history {
+		changes := snapshot.Changes
+		if changes == nil {
+			changes = []scheduler.ConfigChange{}
+		}
+		versions = append(versions, gin.H{
+			"version": snapshot.Version,
+			"time":    snapshot.Time,
+			"summary": snapshot.Summary(),
+			"changes": changes,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetConfigurationKinds lists the registered configuration kinds with the number of current configurations of each
+func (api *API) GetConfigurationKinds(c *gin.Context) {
+	counts := make(map[string]int)
+	if api.configService != nil {
+		for _, config := range api.configService.GetCurrentConfigurations() {
+			counts[config.ConfigKind()]++
+		}
+	}
+
+	kinds := make([]gin.H, 0)
+	for _, spec := range scheduler.ConfigKinds() {
+		kinds = append(kinds, gin.H{
+			"kind":        spec.Kind,
+			"description": spec.Description,
+			"table":       spec.FromRow != nil,
+			"count":       counts[spec.Kind],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"kinds": kinds})
+}
+
+// RefreshConfigurations fetches the configurations immediately and returns what changed.
+// With ?force=true the debounce and the drop-ratio check are skipped, so an admin can
+// accept a fetched set that removes more configurations than max_drop_ratio allows.
+func (api *API) RefreshConfigurations(c *gin.Context) {
+	if api.configService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Configuration service not enabled"})
+		return
+	}
+
+	force := false
+	if value := c.Query("force"); value != "" {
+		var err error
+		if force, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid force %q, expected true or false", value)})
+			return
+		}
+	}
+
+	var result *scheduler.RefreshResult
+	if force {
+		result = api.configService.ForceRefresh(scheduler.RefreshTriggerManual)
+	} else {
+		result = api.configService.Refresh(scheduler.RefreshTriggerManual)
+	}
+	if result.Changes == nil {
+		result.Changes = []scheduler.ConfigChange{}
+	}
+
+	status := http.StatusOK
+	switch {
+	case result.Rejected:
+		status = http.StatusUnprocessableEntity
+	case result.Error != "":
+		status = http.StatusBadGateway
+	}
+
+	c.JSON(status, gin.H{
+		"time":      result.Time,
+		"trigger":   result.Trigger,
+		"version":   result.Version,
+		"changed":   result.Changed,
+		"debounced": result.Debounced,
+		"forced":    result.Forced,
+		"rejected":  result.Rejected,
+		"error":     result.Error,
+		"summary":   result.Summary(),
+		"changes":   result.Changes,
+	})
+}
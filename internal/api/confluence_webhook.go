@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"my-scheduler-go/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBodySize limits the size of a webhook payload
+const maxWebhookBodySize = 1 << 20
+
+// confluenceWebhookPayload is the part of a Confluence page event the scheduler uses
+type confluenceWebhookPayload struct {
+	Event string `json:"event"`
+	Page  struct {
+		ID    json.Number `json:"id"`
+		Title string      `json:"title"`
+	} `json:"page"`
+}
+
+// HandleConfluenceWebhook triggers a debounced configuration refresh when the configuration page is updated
+func (api *API) HandleConfluenceWebhook(c *gin.Context) {
+	if api.configService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Configuration service not enabled"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	if !api.verifyWebhookSignature(c.GetHeader("X-Hub-Signature"), body) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+
+	var payload confluenceWebhookPayload
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid payload: %v", err)})
+		return
+	}
+
+	pageID := payload.Page.ID.String()
+	if mainPageID := api.config.Confluence.MainPageID; pageID != mainPageID {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored", "page_id": pageID})
+		return
+	}
+
+	log.Printf("[ConfluenceWebhook] %s for configuration page %s, refreshing", payload.Event, pageID)
+	api.configService.RequestRefresh(scheduler.RefreshTriggerWebhook)
+	c.JSON(http.StatusAccepted, gin.H{"status": "refresh scheduled", "page_id": pageID})
+}
+
+// verifyWebhookSignature checks the "sha256=<hex>" HMAC of the body against the configured webhook secret
+func (api *API) verifyWebhookSignature(header string, body []byte) bool {
+	secret := api.config.ConfigSource.WebhookSecret
+	if secret == "" {
+		return false
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEventQueueMetrics returns the depth and drop counters of the Mattermost event queue
+func (api *API) GetEventQueueMetrics(c *gin.Context) {
+	if api.eventListener == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event listener not configured",
+		})
+		return
+	}
+
+	metrics, ok := api.eventListener.QueueMetrics()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event queue not enabled",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
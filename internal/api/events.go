@@ -0,0 +1,152 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"my-scheduler-go/internal/mattermost"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultEventQueryLimit is the number of events returned when no limit is given
+const defaultEventQueryLimit = 100
+
+// eventStore returns the event store of the Mattermost listener, or nil when not enabled
+func (api *API) eventStore() *mattermost.EventStore {
+	if api.eventListener == nil {
+		return nil
+	}
+	return api.eventListener.EventStore()
+}
+
+// GetEvents returns stored Mattermost events, newest first
+//
+// Supported query parameters: type, channel_id, user_id, post_id, outcome,
+// since, until (RFC 3339) and limit.
+func (api *API) GetEvents(c *gin.Context) {
+	store := api.eventStore()
+	if store == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event store not enabled",
+		})
+		return
+	}
+
+	query := mattermost.EventQuery{
+		Type:      mattermost.EventType(c.Query("type")),
+		ChannelID: c.Query("channel_id"),
+		UserID:    c.Query("user_id"),
+		PostID:    c.Query("post_id"),
+		Outcome:   c.Query("outcome"),
+		Limit:     defaultEventQueryLimit,
+	}
+
+	var err error
+	if query.Since, err = parseTimeQuery(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Until, err = parseTimeQuery(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+			return
+		}
+		query.Limit = limit
+	}
+
+	events := store.Query(query)
+	c.JSON(http.StatusOK, gin.H{
+		"total_count": len(events),
+		"data":        events,
+	})
+}
+
+// GetEventByID returns a stored Mattermost event with its decisions and tasks
+func (api *API) GetEventByID(c *gin.Context) {
+	store := api.eventStore()
+	if store == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event store not enabled",
+		})
+		return
+	}
+
+	record, ok := store.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// replayResponse is the replayed event record with the tasks split by whether the replay created them
+type replayResponse struct {
+	mattermost.EventRecord
+	PreviousTaskIDs []string `json:"previous_task_ids"`
+	NewTaskIDs      []string `json:"new_task_ids"`
+}
+
+// ReplayEvent pushes a stored event through the current filters and processors again.
+// Processors do not check the tasks created earlier, so a replay may create duplicates;
+// the response lists the earlier task IDs separately so the caller can delete them.
+func (api *API) ReplayEvent(c *gin.Context) {
+	store := api.eventStore()
+	if store == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event store not enabled",
+		})
+		return
+	}
+
+	id := c.Param("id")
+	previous, ok := store.Get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Event not found",
+		})
+		return
+	}
+
+	record, err := api.eventListener.Replay(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Task IDs are only appended, so the ones recorded before the replay come first
+	newTaskIDs := []string{}
+	if len(record.TaskIDs) > len(previous.TaskIDs) {
+		newTaskIDs = record.TaskIDs[len(previous.TaskIDs):]
+	}
+	c.JSON(http.StatusOK, replayResponse{
+		EventRecord:     record,
+		PreviousTaskIDs: append([]string{}, previous.TaskIDs...),
+		NewTaskIDs:      newTaskIDs,
+	})
+}
+
+// parseTimeQuery parses an optional RFC 3339 time query parameter
+func parseTimeQuery(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected RFC 3339 time", name, value)
+	}
+	return t, nil
+}
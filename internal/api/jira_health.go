@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJiraHealth checks every configured Jira environment; responds 503 when any of them is unhealthy
+func (api *API) GetJiraHealth(c *gin.Context) {
+	if api.jiraService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Jira service not enabled"})
+		return
+	}
+
+	environments := api.jiraService.HealthCheck()
+	status, code := "ok", http.StatusOK
+	for _, env := range environments {
+		if !env.Healthy {
+			status, code = "degraded", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status":       status,
+		"timestamp":    time.Now(),
+		"environments": environments,
+	})
+}
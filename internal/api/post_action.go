@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandlePostAction handles interactive button callbacks from Mattermost
+func (api *API) HandlePostAction(c *gin.Context) {
+	var req mattermost.PostActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	action, _ := req.Context["action"].(string)
+	taskID, _ := req.Context["task_id"].(string)
+	signature, _ := req.Context["signature"].(string)
+
+	if !mattermost.VerifyAction(api.config.Mattermost.ActionSecret, action, taskID, signature) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid action signature",
+		})
+		return
+	}
+
+	var task *models.Task
+	var err error
+	var message string
+
+	switch action {
+	case mattermost.ActionRetry:
+		task, err = api.scheduler.RunTask(taskID)
+		message = "Retry of task **%[2]s** requested by @%[1]s"
+	case mattermost.ActionCancel:
+		task, err = api.scheduler.CancelTask(taskID)
+		message = "Task **%[2]s** cancelled by @%[1]s"
+	case mattermost.ActionApprove:
+		if !api.isApprover(&req) {
+			c.JSON(http.StatusOK, &mattermost.PostActionResponse{
+				EphemeralText: "You are not authorized to approve tasks.",
+			})
+			return
+		}
+		task, err = api.scheduler.ApproveTask(taskID, req.UserName)
+		message = "Task **%[2]s** approved by @%[1]s"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unknown action: %s", action),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusOK, &mattermost.PostActionResponse{
+			EphemeralText: fmt.Sprintf("Unable to %s task %s: %v", action, taskID, err),
+		})
+		return
+	}
+
+	// Replace the original post so the buttons cannot be clicked again
+	c.JSON(http.StatusOK, &mattermost.PostActionResponse{
+		Update: &mattermost.PostActionUpdate{
+			Message: fmt.Sprintf(message, req.UserName, task.Name),
+			Props:   map[string]interface{}{},
+		},
+	})
+}
+
+// isApprover checks whether the clicking user is listed in mattermost.approvers
+func (api *API) isApprover(req *mattermost.PostActionRequest) bool {
+	for _, approver := range api.config.Mattermost.Approvers {
+		if approver == req.UserName || approver == req.UserID {
+			return true
+		}
+	}
+	return false
+}
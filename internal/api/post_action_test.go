@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+	"my-scheduler-go/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testActionSecret = "action-secret"
+
+// newTestRouter returns the router backed by an in-memory repository and a scheduler that is not started
+func newTestRouter(t *testing.T, appConfig *config.AppConfig) (*gin.Engine, repository.TaskRepository, *scheduler.SchedulerService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewInMemoryTaskRepository()
+	sched := scheduler.NewSchedulerService(repo, scheduler.NewTaskExecutor(repo), time.Hour)
+	return SetupRouter(repo, sched, nil, appConfig), repo, sched
+}
+
+// postAction sends a button callback for the task and decodes the response
+func postAction(t *testing.T, router *gin.Engine, userID, userName, action, taskID, signature string) (int, mattermost.PostActionResponse) {
+	t.Helper()
+	body, _ := json.Marshal(mattermost.PostActionRequest{
+		UserID:   userID,
+		UserName: userName,
+		Context:  map[string]interface{}{"action": action, "task_id": taskID, "signature": signature},
+	})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mattermost/actions", bytes.NewReader(body)))
+
+	var response mattermost.PostActionResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+func TestHandlePostActionApprove(t *testing.T) {
+	appConfig := &config.AppConfig{}
+	appConfig.Mattermost.ActionSecret = testActionSecret
+	appConfig.Mattermost.Approvers = []string{"alice", "user-bob"}
+	router, repo, sched := newTestRouter(t, appConfig)
+
+	newTask := func(name string) *models.Task {
+		task := &models.Task{Name: name, TaskType: models.TypeImmediate, Status: models.StatusPending, RequiresApproval: true, Parameters: map[string]interface{}{}}
+		if err := sched.AddTask(task); err != nil {
+			t.Fatalf("add task: %v", err)
+		}
+		return task
+	}
+	approve := func(userID, userName, taskID string) (int, mattermost.PostActionResponse) {
+		return postAction(t, router, userID, userName, mattermost.ActionApprove, taskID, mattermost.SignAction(testActionSecret, mattermost.ActionApprove, taskID))
+	}
+
+	task := newTask("deploy")
+	if code, response := approve("user-mallory", "mallory", task.ID); code != http.StatusOK || !strings.Contains(response.EphemeralText, "not authorized") {
+		t.Errorf("approval by a user not in the approvers = %d %+v", code, response)
+	}
+	if stored, _ := repo.GetTaskByID(task.ID); stored.Status != models.StatusAwaitingApproval || stored.ApprovedBy != "" {
+		t.Fatalf("task after an unauthorized approval = %s by %q", stored.Status, stored.ApprovedBy)
+	}
+
+	code, response := approve("user-alice", "alice", task.ID)
+	if code != http.StatusOK || response.Update == nil || response.Update.Message != "Task **deploy** approved by @alice" {
+		t.Fatalf("approval by username = %d %+v", code, response)
+	}
+	if stored, _ := repo.GetTaskByID(task.ID); stored.ApprovedBy != "alice" || stored.Status != models.StatusQueued {
+		t.Errorf("approved task = %s by %q, want QUEUED by alice", stored.Status, stored.ApprovedBy)
+	}
+
+	// A second click on the same button must not approve or queue the task again
+	if code, response := approve("user-bob", "bob", task.ID); code != http.StatusOK || response.Update != nil || !strings.Contains(response.EphemeralText, "not awaiting approval") {
+		t.Errorf("second approval = %d %+v", code, response)
+	}
+	if stored, _ := repo.GetTaskByID(task.ID); stored.ApprovedBy != "alice" {
+		t.Errorf("second approval changed the approver to %q", stored.ApprovedBy)
+	}
+
+	// Approvers may also be listed by user ID
+	other := newTask("migrate")
+	if code, response := approve("user-bob", "bob", other.ID); code != http.StatusOK || response.Update == nil {
+		t.Errorf("approval by user ID = %d %+v", code, response)
+	}
+}
+
+func TestHandlePostActionSignature(t *testing.T) {
+	appConfig := &config.AppConfig{}
+	appConfig.Mattermost.ActionSecret = testActionSecret
+	appConfig.Mattermost.Approvers = []string{"alice"}
+	router, repo, sched := newTestRouter(t, appConfig)
+
+	task := &models.Task{Name: "deploy", TaskType: models.TypeImmediate, Status: models.StatusPending, RequiresApproval: true, Parameters: map[string]interface{}{}}
+	if err := sched.AddTask(task); err != nil {
+		t.Fatalf("add task: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		action    string
+		signature string
+		code      int
+	}{
+		{name: "signature of another action", action: mattermost.ActionApprove, signature: mattermost.SignAction(testActionSecret, mattermost.ActionCancel, task.ID), code: http.StatusUnauthorized},
+		{name: "signature with another secret", action: mattermost.ActionApprove, signature: mattermost.SignAction("guess", mattermost.ActionApprove, task.ID), code: http.StatusUnauthorized},
+		{name: "missing signature", action: mattermost.ActionApprove, code: http.StatusUnauthorized},
+		{name: "unknown action", action: "delete", signature: mattermost.SignAction(testActionSecret, "delete", task.ID), code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := postAction(t, router, "user-alice", "alice", tt.action, task.ID, tt.signature); code != tt.code {
+				t.Errorf("status = %d, want %d", code, tt.code)
+			}
+		})
+	}
+	if stored, _ := repo.GetTaskByID(task.ID); stored.Status != models.StatusAwaitingApproval {
+		t.Errorf("task after rejected callbacks = %s, want AWAITING_APPROVAL", stored.Status)
+	}
+
+	// Without a configured secret every callback is rejected
+	router, _, _ = newTestRouter(t, &config.AppConfig{})
+	if code, _ := postAction(t, router, "user-alice", "alice", mattermost.ActionApprove, task.ID, mattermost.SignAction("", mattermost.ActionApprove, task.ID)); code != http.StatusUnauthorized {
+		t.Errorf("callback without a configured secret = %d, want 401", code)
+	}
+}
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"log"
 	"net/http"
 	"time"
 
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/mattermost"
 	"my-scheduler-go/internal/models"
 	"my-scheduler-go/internal/repository"
 	"my-scheduler-go/internal/scheduler"
@@ -17,21 +20,61 @@ type API struct {
 	repo             repository.TaskRepository
 	scheduler        *scheduler.SchedulerService
 	reportingService *service.ResultReportingService
+	config           *config.AppConfig
+	eventListener    *mattermost.EventListener
+	configService    *scheduler.ConfigurationService
+	jiraService      *service.JiraService
+	artifacts        *repository.ArtifactStore
+}
+
+// RouterOption configures optional API dependencies
+type RouterOption func(*API)
+
+// WithEventListener exposes the Mattermost event listener's queue metrics and stored events
+func WithEventListener(listener *mattermost.EventListener) RouterOption {
+	return func(api *API) {
+		api.eventListener = listener
+	}
+}
+
+// WithConfigurationService exposes the current configuration and its version history
+func WithConfigurationService(configService *scheduler.ConfigurationService) RouterOption {
+	return func(api *API) {
+		api.configService = configService
+	}
+}
+
+// WithJiraService exposes the health of the configured Jira environments
+func WithJiraService(jiraService *service.JiraService) RouterOption {
+	return func(api *API) {
+		api.jiraService = jiraService
+	}
+}
+
+// WithArtifactStore exposes the files produced by task runs for download
+func WithArtifactStore(artifacts *repository.ArtifactStore) RouterOption {
+	return func(api *API) {
+		api.artifacts = artifacts
+	}
 }
 
 // NewAPI creates a new API handler
-func NewAPI(repo repository.TaskRepository, scheduler *scheduler.SchedulerService, reportingService *service.ResultReportingService) *API {
+func NewAPI(repo repository.TaskRepository, scheduler *scheduler.SchedulerService, reportingService *service.ResultReportingService, appConfig *config.AppConfig) *API {
 	return &API{
 		repo:             repo,
 		scheduler:        scheduler,
 		reportingService: reportingService,
+		config:           appConfig,
 	}
 }
 
 // SetupRouter sets up the API routes
-func SetupRouter(repo repository.TaskRepository, scheduler *scheduler.SchedulerService, reportingService *service.ResultReportingService) *gin.Engine {
+func SetupRouter(repo repository.TaskRepository, scheduler *scheduler.SchedulerService, reportingService *service.ResultReportingService, appConfig *config.AppConfig, options ...RouterOption) *gin.Engine {
 	r := gin.Default()
-	api := NewAPI(repo, scheduler, reportingService)
+	api := NewAPI(repo, scheduler, reportingService, appConfig)
+	for _, option := range options {
+		option(api)
+	}
 
 	// Task management endpoints
 	r.GET("/tasks", api.GetAllTasks)
@@ -42,6 +85,10 @@ func SetupRouter(repo repository.TaskRepository, scheduler *scheduler.SchedulerS
 	r.PUT("/tasks/:id", api.UpdateTask)
 	r.DELETE("/tasks/:id", api.DeleteTask)
 
+	// Task artifact endpoints
+	r.GET("/tasks/:id/artifacts", api.GetTaskArtifacts)
+	r.GET("/tasks/:id/artifacts/:name", api.DownloadTaskArtifact)
+
 	// Task history endpoint
 	r.GET("/task_history", api.GetTaskHistory)
 
@@ -51,6 +98,29 @@ func SetupRouter(repo repository.TaskRepository, scheduler *scheduler.SchedulerS
 	// Add a new endpoint for immediate reporting
 	r.POST("/tasks/:id/report", api.GenerateTaskReport)
 
+	// Mattermost slash command endpoint (/sched)
+	r.POST("/mattermost/command", api.HandleSlashCommand)
+
+	// Mattermost interactive button callback endpoint
+	r.POST("/mattermost/actions", api.HandlePostAction)
+
+	// Configuration endpoints
+	r.GET("/configurations", api.GetConfigurations)
+	r.GET("/configurations/history", api.GetConfigurationHistory)
+	r.GET("/configurations/kinds", api.GetConfigurationKinds)
+	r.POST("/configurations/refresh", api.RefreshConfigurations)
+
+	// Confluence page-updated webhook (triggers a configuration refresh)
+	r.POST("/webhooks/confluence", api.HandleConfluenceWebhook)
+
+	// Mattermost event queue metrics
+	r.GET("/metrics/events", api.GetEventQueueMetrics)
+
+	// Stored Mattermost events and replay
+	r.GET("/events", api.GetEvents)
+	r.GET("/events/:id", api.GetEventByID)
+	r.POST("/events/:id/replay", api.ReplayEvent)
+
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -58,6 +128,7 @@ func SetupRouter(repo repository.TaskRepository, scheduler *scheduler.SchedulerS
 			"timestamp": time.Now(),
 		})
 	})
+	r.GET("/health/jira", api.GetJiraHealth)
 
 	return r
 }
@@ -184,6 +255,13 @@ func (api *API) DeleteTask(c *gin.Context) {
 		return
 	}
 
+	// Remove the files produced by the task
+	if api.artifacts != nil {
+		if err := api.artifacts.DeleteAll(id); err != nil {
+			log.Printf("[API] Failed to delete artifacts of task %s: %v", id, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Task deleted successfully",
 	})
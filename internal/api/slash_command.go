@@ -0,0 +1,239 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Mattermost slash command response types
+const (
+	responseTypeEphemeral = "ephemeral"
+	responseTypeInChannel = "in_channel"
+)
+
+// maxListedTasks limits the number of tasks returned by `/sched list`
+const maxListedTasks = 20
+
+// SlashCommandRequest is the form payload Mattermost sends for a slash command
+type SlashCommandRequest struct {
+	ChannelID   string `form:"channel_id"`
+	ChannelName string `form:"channel_name"`
+	Command     string `form:"command"`
+	ResponseURL string `form:"response_url"`
+	TeamDomain  string `form:"team_domain"`
+	TeamID      string `form:"team_id"`
+	Text        string `form:"text"`
+	Token       string `form:"token"`
+	TriggerID   string `form:"trigger_id"`
+	UserID      string `form:"user_id"`
+	UserName    string `form:"user_name"`
+}
+
+// SlashCommandResponse is the JSON response understood by Mattermost
+type SlashCommandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// HandleSlashCommand implements the Mattermost slash command protocol for /sched
+func (api *API) HandleSlashCommand(c *gin.Context) {
+	var req SlashCommandRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if !api.verifySlashCommandToken(c, req.Token) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid slash command token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.executeSlashCommand(&req))
+}
+
+// verifySlashCommandToken checks the token sent in the form body or Authorization header
+func (api *API) verifySlashCommandToken(c *gin.Context, formToken string) bool {
+	expected := api.config.Mattermost.SlashCommandToken
+	if expected == "" {
+		return false
+	}
+
+	token := formToken
+	if token == "" {
+		token = strings.TrimPrefix(c.GetHeader("Authorization"), "Token ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// executeSlashCommand dispatches the sub-command and builds the response
+func (api *API) executeSlashCommand(req *SlashCommandRequest) *SlashCommandResponse {
+	text := strings.TrimSpace(req.Text)
+	subCommand, args := text, ""
+	if idx := strings.IndexAny(text, " \t"); idx >= 0 {
+		subCommand, args = text[:idx], strings.TrimSpace(text[idx+1:])
+	}
+
+	switch strings.ToLower(subCommand) {
+	case "list":
+		return ephemeral(api.slashList(args))
+	case "status":
+		return ephemeral(api.slashStatus(args))
+	case "run":
+		return api.slashRun(args, req)
+	case "create":
+		return api.slashCreate(args, req)
+	case "", "help":
+		return ephemeral(slashHelp(req.Command))
+	default:
+		return ephemeral(fmt.Sprintf("Unknown sub-command `%s`.\n\n%s", subCommand, slashHelp(req.Command)))
+	}
+}
+
+// slashList lists tasks, optionally filtered by status
+func (api *API) slashList(args string) string {
+	var tasks []*models.Task
+	if args != "" {
+		tasks = api.repo.GetTasksByStatus(models.TaskStatus(strings.ToUpper(args)))
+	} else {
+		tasks = api.repo.GetAllTasks()
+	}
+
+	if len(tasks) == 0 {
+		return "No tasks found."
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**%d task(s)**\n\n", len(tasks)))
+	sb.WriteString("| ID | Name | Type | Status | Priority |\n")
+	sb.WriteString("|:---|:-----|:-----|:-------|:---------|\n")
+	for i, task := range tasks {
+		if i == maxListedTasks {
+			sb.WriteString(fmt.Sprintf("\n_%d more not shown_", len(tasks)-maxListedTasks))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s | %s |\n",
+			task.ID, task.Name, task.TaskType, task.Status, task.Priority))
+	}
+
+	return sb.String()
+}
+
+// slashStatus shows the details of a single task
+func (api *API) slashStatus(id string) string {
+	if id == "" {
+		return "Usage: `status <task-id>`"
+	}
+
+	task, err := api.repo.GetTaskByID(id)
+	if err != nil {
+		return fmt.Sprintf("Task `%s` not found.", id)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**%s** (`%s`)\n\n", task.Name, task.ID))
+	sb.WriteString(fmt.Sprintf("- Status: %s\n", task.Status))
+	sb.WriteString(fmt.Sprintf("- Type: %s\n", task.TaskType))
+	sb.WriteString(fmt.Sprintf("- Priority: %s\n", task.Priority))
+	if task.CronExpr != "" {
+		sb.WriteString(fmt.Sprintf("- Cron: `%s`\n", task.CronExpr))
+	}
+	if task.Owner != "" {
+		sb.WriteString(fmt.Sprintf("- Owner: %s\n", task.Owner))
+	}
+	sb.WriteString(fmt.Sprintf("- Created: %s\n", task.CreatedAt.Format(time.RFC3339)))
+	if !task.StartTime.IsZero() {
+		sb.WriteString(fmt.Sprintf("- Started: %s\n", task.StartTime.Format(time.RFC3339)))
+	}
+	if !task.EndTime.IsZero() {
+		sb.WriteString(fmt.Sprintf("- Finished: %s\n", task.EndTime.Format(time.RFC3339)))
+	}
+	if result, ok := task.ExecutionResult["result"]; ok {
+		sb.WriteString(fmt.Sprintf("- Result: %v\n", result))
+	}
+
+	return sb.String()
+}
+
+// slashRun queues an existing task for immediate execution
+func (api *API) slashRun(id string, req *SlashCommandRequest) *SlashCommandResponse {
+	if id == "" {
+		return ephemeral("Usage: `run <task-id>`")
+	}
+
+	task, err := api.scheduler.RunTask(id)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("Unable to run task `%s`: %v", id, err))
+	}
+
+	return inChannel(fmt.Sprintf("@%s queued task **%s** (`%s`) for execution.", req.UserName, task.Name, task.ID))
+}
+
+// slashCreate creates a new task from `key:value` arguments
+func (api *API) slashCreate(args string, req *SlashCommandRequest) *SlashCommandResponse {
+	task, err := scheduler.ParseTaskCommand(args)
+	if err == nil {
+		err = scheduler.ValidateDependencies(api.repo, task)
+	}
+	if err != nil {
+		return ephemeral(fmt.Sprintf("Unable to create task: %v\n\n%s", err, slashHelp(req.Command)))
+	}
+
+	task.Owner = req.UserName
+	task.Tags = append(task.Tags, "SLASH_COMMAND")
+	task.Metadata = map[string]interface{}{
+		"source":     "mattermost_slash_command",
+		"channel_id": req.ChannelID,
+		"team_id":    req.TeamID,
+		"user_id":    req.UserID,
+	}
+
+	if err := api.scheduler.AddTask(task); err != nil {
+		return ephemeral(fmt.Sprintf("Unable to create task: %v", err))
+	}
+
+	text := fmt.Sprintf("@%s created %s task **%s** (`%s`)", req.UserName, strings.ToLower(string(task.TaskType)), task.Name, task.ID)
+	if task.CronExpr != "" {
+		text += fmt.Sprintf(" with schedule `%s`", task.CronExpr)
+	}
+
+	return inChannel(text + ".")
+}
+
+// slashHelp returns the usage text
+func slashHelp(command string) string {
+	if command == "" {
+		command = "/sched"
+	}
+
+	return fmt.Sprintf("**Usage**\n\n"+
+		"- `%[1]s list [status]` - list tasks\n"+
+		"- `%[1]s status <task-id>` - show task details\n"+
+		"- `%[1]s run <task-id>` - run a task now\n"+
+		"- `%[1]s create <name> [cron:\"0 0 9 * * *\"] [priority:high] [tags:a,b] [depends:<task-id>] [param.key=value]` - create a task\n", command)
+}
+
+func ephemeral(text string) *SlashCommandResponse {
+	return &SlashCommandResponse{ResponseType: responseTypeEphemeral, Text: text}
+}
+
+func inChannel(text string) *SlashCommandResponse {
+	return &SlashCommandResponse{ResponseType: responseTypeInChannel, Text: text}
+}
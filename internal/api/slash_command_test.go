@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSlashToken = "slash-token"
+
+// newSlashRouter returns a router accepting testSlashToken
+func newSlashRouter(t *testing.T) (*gin.Engine, repository.TaskRepository) {
+	t.Helper()
+	appConfig := &config.AppConfig{}
+	appConfig.Mattermost.SlashCommandToken = testSlashToken
+	router, repo, _ := newTestRouter(t, appConfig)
+	return router, repo
+}
+
+// slashCommand posts the form as Mattermost does and decodes the response
+func slashCommand(t *testing.T, router *gin.Engine, form url.Values, authorization string) (int, SlashCommandResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mattermost/command", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	var response SlashCommandResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+// sched runs `/sched <text>` as alice with a valid token
+func sched(t *testing.T, router *gin.Engine, text string) SlashCommandResponse {
+	t.Helper()
+	code, response := slashCommand(t, router, url.Values{
+		"token":      {testSlashToken},
+		"command":    {"/sched"},
+		"text":       {text},
+		"user_id":    {"user-alice"},
+		"user_name":  {"alice"},
+		"channel_id": {"ch-ops"},
+		"team_id":    {"team-a"},
+	}, "")
+	if code != http.StatusOK {
+		t.Fatalf("/sched %s: status %d", text, code)
+	}
+	return response
+}
+
+// addTask stores a task without dispatching it
+func addTask(t *testing.T, repo repository.TaskRepository, task *models.Task) *models.Task {
+	t.Helper()
+	if err := repo.AddTask(task); err != nil {
+		t.Fatalf("add task: %v", err)
+	}
+	return task
+}
+
+func TestHandleSlashCommandToken(t *testing.T) {
+	router, _ := newSlashRouter(t)
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		code          int
+	}{
+		{name: "form token", token: testSlashToken, code: http.StatusOK},
+		{name: "authorization header", authorization: "Token " + testSlashToken, code: http.StatusOK},
+		{name: "wrong token", token: "guess", code: http.StatusUnauthorized},
+		// The form token takes precedence over the header
+		{name: "wrong form token with a valid header", token: "guess", authorization: "Token " + testSlashToken, code: http.StatusUnauthorized},
+		{name: "missing token", code: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"text": {"help"}}
+			if tt.token != "" {
+				form.Set("token", tt.token)
+			}
+			if code, _ := slashCommand(t, router, form, tt.authorization); code != tt.code {
+				t.Errorf("status = %d, want %d", code, tt.code)
+			}
+		})
+	}
+
+	// Without a configured token every request is rejected, including one with an empty token
+	unconfigured, _, _ := newTestRouter(t, &config.AppConfig{})
+	if code, _ := slashCommand(t, unconfigured, url.Values{"token": {""}, "text": {"list"}}, ""); code != http.StatusUnauthorized {
+		t.Errorf("request without a configured token = %d, want 401", code)
+	}
+}
+
+func TestSlashCommandList(t *testing.T) {
+	router, repo := newSlashRouter(t)
+	if response := sched(t, router, "list"); response.ResponseType != responseTypeEphemeral || response.Text != "No tasks found." {
+		t.Errorf("list without tasks = %+v", response)
+	}
+
+	older := addTask(t, repo, &models.Task{Name: "older", TaskType: models.TypeImmediate, Status: models.StatusPending})
+	newer := addTask(t, repo, &models.Task{Name: "newer", TaskType: models.TypeScheduled, Status: models.StatusDone})
+	older.CreatedAt = newer.CreatedAt.Add(-time.Minute)
+
+	response := sched(t, router, "list")
+	if response.ResponseType != responseTypeEphemeral || !strings.HasPrefix(response.Text, "**2 task(s)**") {
+		t.Fatalf("list = %+v", response)
+	}
+	if strings.Index(response.Text, "newer") > strings.Index(response.Text, "older") {
+		t.Errorf("list is not sorted newest first:\n%s", response.Text)
+	}
+	if !strings.Contains(response.Text, fmt.Sprintf("| `%s` | newer | SCHEDULED | DONE | MEDIUM |", newer.ID)) {
+		t.Errorf("list row of %s missing:\n%s", newer.ID, response.Text)
+	}
+
+	// The status filter is case-insensitive
+	response = sched(t, router, "list pending")
+	if !strings.HasPrefix(response.Text, "**1 task(s)**") || !strings.Contains(response.Text, older.ID) || strings.Contains(response.Text, newer.ID) {
+		t.Errorf("list pending = %s", response.Text)
+	}
+
+	for i := 0; i < maxListedTasks; i++ {
+		addTask(t, repo, &models.Task{Name: fmt.Sprintf("bulk-%d", i), TaskType: models.TypeImmediate})
+	}
+	if response := sched(t, router, "list"); !strings.Contains(response.Text, "_2 more not shown_") {
+		t.Errorf("list of %d tasks does not mention the hidden ones:\n%s", maxListedTasks+2, response.Text)
+	}
+}
+
+func TestSlashCommandStatus(t *testing.T) {
+	router, repo := newSlashRouter(t)
+	task := addTask(t, repo, &models.Task{
+		Name:            "nightly",
+		TaskType:        models.TypeScheduled,
+		CronExpr:        "0 0 2 * * *",
+		Owner:           "bob",
+		Status:          models.StatusDone,
+		ExecutionResult: map[string]interface{}{"result": "exported 42 issues"},
+	})
+
+	response := sched(t, router, "status "+task.ID)
+	if response.ResponseType != responseTypeEphemeral {
+		t.Errorf("status response type = %s", response.ResponseType)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("**nightly** (`%s`)", task.ID),
+		"- Status: DONE",
+		"- Cron: `0 0 2 * * *`",
+		"- Owner: bob",
+		"- Result: exported 42 issues",
+	} {
+		if !strings.Contains(response.Text, want) {
+			t.Errorf("status is missing %q:\n%s", want, response.Text)
+		}
+	}
+
+	if response := sched(t, router, "status"); response.Text != "Usage: `status <task-id>`" {
+		t.Errorf("status without an ID = %q", response.Text)
+	}
+	if response := sched(t, router, "status missing"); response.Text != "Task `missing` not found." {
+		t.Errorf("status of an unknown task = %q", response.Text)
+	}
+}
+
+func TestSlashCommandRun(t *testing.T) {
+	router, repo := newSlashRouter(t)
+	task := addTask(t, repo, &models.Task{Name: "report", TaskType: models.TypeImmediate, Status: models.StatusPending})
+
+	response := sched(t, router, "run "+task.ID)
+	if response.ResponseType != responseTypeInChannel || response.Text != fmt.Sprintf("@alice queued task **report** (`%s`) for execution.", task.ID) {
+		t.Errorf("run = %+v", response)
+	}
+	if stored, _ := repo.GetTaskByID(task.ID); stored.Status != models.StatusQueued {
+		t.Errorf("task after run = %s, want QUEUED", stored.Status)
+	}
+
+	held := addTask(t, repo, &models.Task{Name: "deploy", TaskType: models.TypeImmediate, Status: models.StatusAwaitingApproval, RequiresApproval: true})
+	tests := map[string]string{
+		"run":                 "Usage: `run <task-id>`",
+		"run " + task.ID:      "is already QUEUED",
+		"run " + held.ID:      "is awaiting approval",
+		"run missing-task-id": "Unable to run task `missing-task-id`",
+	}
+	for text, want := range tests {
+		response := sched(t, router, text)
+		if response.ResponseType != responseTypeEphemeral || !strings.Contains(response.Text, want) {
+			t.Errorf("/sched %s = %+v, want an ephemeral %q", text, response, want)
+		}
+	}
+}
+
+func TestSlashCommandCreate(t *testing.T) {
+	router, repo := newSlashRouter(t)
+	dependency := addTask(t, repo, &models.Task{Name: "extract", TaskType: models.TypeImmediate})
+
+	response := sched(t, router, fmt.Sprintf(`create Nightly backup cron:"0 0 2 * * *" priority:high tags:ops,db depends:%s param.database=main`, dependency.ID))
+	if response.ResponseType != responseTypeInChannel || !strings.HasPrefix(response.Text, "@alice created scheduled task **Nightly backup**") ||
+		!strings.HasSuffix(response.Text, " with schedule `0 0 2 * * *`.") {
+		t.Fatalf("create = %+v", response)
+	}
+
+	var created *models.Task
+	for _, task := range repo.GetAllTasks() {
+		if task.Name == "Nightly backup" {
+			created = task
+		}
+	}
+	if created == nil {
+		t.Fatal("created task not stored")
+	}
+	if created.Owner != "alice" || created.Priority != models.PriorityHigh || created.CronExpr != "0 0 2 * * *" {
+		t.Errorf("created task = %+v", created)
+	}
+	if !reflect.DeepEqual(created.Tags, []string{"ops", "db", "SLASH_COMMAND"}) || !reflect.DeepEqual(created.Dependencies, []string{dependency.ID}) {
+		t.Errorf("tags = %v, dependencies = %v", created.Tags, created.Dependencies)
+	}
+	if created.Parameters["database"] != "main" || created.Metadata["channel_id"] != "ch-ops" || created.Metadata["user_id"] != "user-alice" {
+		t.Errorf("parameters = %v, metadata = %v", created.Parameters, created.Metadata)
+	}
+
+	response = sched(t, router, "create Ping")
+	if response.ResponseType != responseTypeInChannel || !strings.HasPrefix(response.Text, "@alice created immediate task **Ping**") {
+		t.Errorf("create of an immediate task = %+v", response)
+	}
+
+	tests := map[string]string{
+		"create x priority:asap":   "invalid priority",
+		"create x depends:missing": `unknown dependency "missing"`,
+		"create cron:@hourly":      "task name is required",
+	}
+	for text, want := range tests {
+		response := sched(t, router, text)
+		if response.ResponseType != responseTypeEphemeral || !strings.HasPrefix(response.Text, "Unable to create task: ") ||
+			!strings.Contains(response.Text, want) || !strings.Contains(response.Text, "**Usage**") {
+			t.Errorf("/sched %s = %+v, want an ephemeral %q with the usage", text, response, want)
+		}
+	}
+	if got := len(repo.GetAllTasks()); got != 3 {
+		t.Errorf("%d tasks stored, want the rejected commands to store nothing", got)
+	}
+}
+
+func TestSlashCommandHelp(t *testing.T) {
+	router, _ := newSlashRouter(t)
+	if response := sched(t, router, ""); response.ResponseType != responseTypeEphemeral || !strings.Contains(response.Text, "`/sched list [status]`") {
+		t.Errorf("help = %+v", response)
+	}
+	if response := sched(t, router, "frobnicate now"); !strings.HasPrefix(response.Text, "Unknown sub-command `frobnicate`.") || !strings.Contains(response.Text, "**Usage**") {
+		t.Errorf("unknown sub-command = %+v", response)
+	}
+}
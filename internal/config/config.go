@@ -20,10 +20,16 @@ type AppConfig struct {
 	} `mapstructure:"scheduler"`
 
 	// Jira configuration
+	// The top-level settings define the environment named "default"; further named
+	// instances go under environments.
 	Jira struct {
-		URL      string `mapstructure:"url"`
-		Username string `mapstructure:"username"`
-		Password string `mapstructure:"password"`
+		JiraEnvironment `mapstructure:",squash"`
+		// DefaultEnvironment - environment used when a task or configuration names none (empty uses "default")
+		DefaultEnvironment string `mapstructure:"default_environment"`
+		// Environments - named Jira instances; names are case-insensitive
+		Environments map[string]JiraEnvironment `mapstructure:"environments"`
+		// Export - settings of JIRA_TASK_EXP tasks
+		Export JiraExportConfig `mapstructure:"export"`
 	} `mapstructure:"jira"`
 
 	// Confluence configuration
@@ -33,6 +39,10 @@ type AppConfig struct {
 		Password    string `mapstructure:"password"`
 		MainPageID  string `mapstructure:"main_page_id"`
 		ResultsPage string `mapstructure:"task_result_page_id"`
+		// Token - personal access token; used instead of username/password when set
+		Token string `mapstructure:"token"`
+		// MaxRetries - retries of a page update after a version conflict
+		MaxRetries int `mapstructure:"max_retries"`
 	} `mapstructure:"confluence"`
 
 	// Mattermost configuration
@@ -41,8 +51,68 @@ type AppConfig struct {
 		Token             string `mapstructure:"token"`
 		ChannelID         string `mapstructure:"channel_id"`
 		ReconnectInterval int    `mapstructure:"reconnect_interval"`
+		// AdminChannelID - channel receiving configuration change summaries (empty uses channel_id)
+		AdminChannelID string `mapstructure:"admin_channel_id"`
+		// SlashCommandToken - token issued by Mattermost for the /sched slash command
+		SlashCommandToken string `mapstructure:"slash_command_token"`
+		// ActionCallbackURL - public URL of the interactive button callback endpoint
+		ActionCallbackURL string `mapstructure:"action_callback_url"`
+		// ActionSecret - secret used to sign interactive button contexts
+		ActionSecret string `mapstructure:"action_secret"`
+		// Approvers - user names or IDs allowed to approve tasks
+		Approvers []string `mapstructure:"approvers"`
+		// ThreadStatusUpdates - reply with task progress in the thread of the source post
+		ThreadStatusUpdates bool `mapstructure:"thread_status_updates"`
+		// MaxFileSize - size limit in bytes for forwarded file attachments
+		MaxFileSize int64 `mapstructure:"max_file_size"`
+		// FakeScenario - scenario file replayed by the fake server in development mode (empty uses the built-in demo)
+		FakeScenario string `mapstructure:"fake_scenario"`
+		// EventQueue - bounded queue between the WebSocket reader and event processing
+		EventQueue struct {
+			Capacity int    `mapstructure:"capacity"`
+			Workers  int    `mapstructure:"workers"`
+			Overflow string `mapstructure:"overflow"` // block, drop_oldest or spill
+			SpillDir string `mapstructure:"spill_dir"`
+		} `mapstructure:"event_queue"`
+		// EventStore - persisted log of received events used by GET /events and replay
+		EventStore struct {
+			Path           string `mapstructure:"path"` // empty uses <storage.path>/mattermost_events.json
+			RetentionHours int    `mapstructure:"retention_hours"`
+			MaxEvents      int    `mapstructure:"max_events"`
+		} `mapstructure:"event_store"`
+		// WatchChannels / WatchTeams - channels and whole teams watched in addition to channel_id
+		// and the channels referenced by the fetched Mattermost configurations
+		WatchChannels []string `mapstructure:"watch_channels"`
+		WatchTeams    []string `mapstructure:"watch_teams"`
+		// EventFilters - which events the listener processes (empty processes post and user events of the watched channels)
+		EventFilters EventFilterConfig `mapstructure:"event_filters"`
 	} `mapstructure:"mattermost"`
 
+	// Routing configuration
+	Routing struct {
+		// RulesFile - YAML file with ordered event routing rules (empty disables static rules)
+		RulesFile string `mapstructure:"rules_file"`
+	} `mapstructure:"routing"`
+
+	// Configuration source (Mattermost configurations and routing rules fetched at runtime)
+	ConfigSource struct {
+		ConfigSourceSpec `mapstructure:",squash"`
+		// CacheFile - last known good configurations, loaded at startup (empty uses <storage.path>/configurations.json)
+		CacheFile string `mapstructure:"cache_file"`
+		// MaxDropRatio - reject a fetched set that removes more than this fraction of the current configurations
+		// (unset uses 0.5, 0 rejects any removal, 1 disables the check)
+		MaxDropRatio *float64 `mapstructure:"max_drop_ratio"`
+		// MaxRowErrorRatio - reject a fetched set when more than this fraction of the rows failed validation
+		// (unset uses 0.2, 0 rejects any row error, 1 disables the check)
+		MaxRowErrorRatio *float64 `mapstructure:"max_row_error_ratio"`
+		// RefreshInterval - seconds between scheduled fetches (0 uses 180)
+		RefreshInterval int `mapstructure:"refresh_interval"`
+		// RefreshDebounce - minimum seconds between manual or webhook triggered fetches (0 uses 5)
+		RefreshDebounce int `mapstructure:"refresh_debounce"`
+		// WebhookSecret - shared secret for the Confluence page-updated webhook signature (empty disables the webhook)
+		WebhookSecret string `mapstructure:"webhook_secret"`
+	} `mapstructure:"config_source"`
+
 	// Log configuration
 	Log struct {
 		Level       string `mapstructure:"level"`
@@ -55,6 +125,8 @@ type AppConfig struct {
 	// Storage configuration
 	Storage struct {
 		Path string `mapstructure:"path"`
+		// MaxArtifactsPerTask - task artifacts kept per task under <path>/artifacts; older ones are deleted (0 uses 10)
+		MaxArtifactsPerTask int `mapstructure:"max_artifacts_per_task"`
 	} `mapstructure:"storage"`
 
 	// Reporting configuration
@@ -68,6 +140,88 @@ type AppConfig struct {
 	} `mapstructure:"reporting"`
 }
 
+// EventFilterConfig describes a Mattermost event filter.
+// All conditions set on the same node must match (AND); all/any/not combine nested filters.
+type EventFilterConfig struct {
+	All             []EventFilterConfig `mapstructure:"all"`
+	Any             []EventFilterConfig `mapstructure:"any"`
+	Not             *EventFilterConfig  `mapstructure:"not"`
+	Channels        []string            `mapstructure:"channels"`
+	EventTypes      []string            `mapstructure:"event_types"`
+	Users           []string            `mapstructure:"users"` // user IDs or usernames
+	Teams           []string            `mapstructure:"teams"`
+	ChannelTypes    []string            `mapstructure:"channel_types"` // O, P, D or G
+	Pattern         string              `mapstructure:"pattern"`       // regular expression matched against the message
+	ExcludeBotPosts bool                `mapstructure:"exclude_bot_posts"`
+	// Watched - match the dynamic set of watched channels and teams
+	Watched bool `mapstructure:"watched"`
+}
+
+// IsEmpty reports whether the filter sets no condition at all
+func (f EventFilterConfig) IsEmpty() bool {
+	return len(f.All) == 0 && len(f.Any) == 0 && f.Not == nil &&
+		len(f.Channels) == 0 && len(f.EventTypes) == 0 && len(f.Users) == 0 &&
+		len(f.Teams) == 0 && len(f.ChannelTypes) == 0 && f.Pattern == "" && !f.ExcludeBotPosts && !f.Watched
+}
+
+// Configuration source types
+const (
+	ConfigSourceConfluence = "confluence"
+	ConfigSourceFile       = "file"
+	ConfigSourceHTTP       = "http"
+	ConfigSourceGit        = "git"
+	ConfigSourceComposite  = "composite"
+)
+
+// JiraEnvironment holds the connection settings of one Jira instance
+type JiraEnvironment struct {
+	URL      string `mapstructure:"url"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Token - personal access token; used instead of username/password when set
+	Token string `mapstructure:"token"`
+	// FieldMappings - logical field names to the instance's field IDs (e.g. story_points: customfield_10002)
+	FieldMappings map[string]string `mapstructure:"field_mappings"`
+	// RateLimit - maximum requests per second sent to the instance (0 = unlimited)
+	RateLimit float64 `mapstructure:"rate_limit"`
+}
+
+// JiraExportConfig holds the defaults of JIRA_TASK_EXP tasks; tasks can override each of them
+type JiraExportConfig struct {
+	// Fields - exported columns: field IDs or field_mappings names (empty uses summary, status, assignee, ...)
+	Fields []string `mapstructure:"fields"`
+	// Formats - artifact formats: xlsx, csv and/or json (empty uses xlsx and csv)
+	Formats []string `mapstructure:"formats"`
+	// GroupBy - how XLSX rows are split into sheets: issue_type (default) or project
+	GroupBy string `mapstructure:"group_by"`
+}
+
+// ConfigSourceSpec describes where configurations are fetched from.
+// Only the fields of the selected type are used.
+type ConfigSourceSpec struct {
+	// Type - confluence (default), file, http, git or composite
+	Type string `mapstructure:"type"`
+	// Name - label used in logs (defaults to the type)
+	Name string `mapstructure:"name"`
+	// Path - file: YAML or JSON configuration document
+	Path string `mapstructure:"path"`
+	// Watch - file: reload as soon as the file changes
+	Watch bool `mapstructure:"watch"`
+	// URL - http: endpoint returning a JSON configuration document
+	URL string `mapstructure:"url"`
+	// Token - http: bearer token sent with the request
+	Token string `mapstructure:"token"`
+	// Timeout - http: request timeout in seconds
+	Timeout int `mapstructure:"timeout"`
+	// Dir - git: checked-out repository; Pattern selects the documents inside it
+	Dir     string `mapstructure:"dir"`
+	Pattern string `mapstructure:"pattern"`
+	// Pull - git: run "git pull --ff-only" before reading
+	Pull bool `mapstructure:"pull"`
+	// Sources - composite: sources merged in order of precedence (first wins on the same kind and id)
+	Sources []ConfigSourceSpec `mapstructure:"sources"`
+}
+
 // LoadConfig loads configuration from the specified file path
 func LoadConfig(path string) (*AppConfig, error) {
 	viper.SetConfigFile(path)
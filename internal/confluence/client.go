@@ -0,0 +1,269 @@
+// Package confluence 实现Confluence REST API(/rest/api/content)的客户端
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 页面内容的表示格式
+const (
+	RepresentationStorage = "storage" // XHTML存储格式
+	RepresentationWiki    = "wiki"    // Wiki标记，由Confluence转换为存储格式
+)
+
+// DefaultExpand 获取页面时默认展开的字段
+var DefaultExpand = []string{"body.storage", "version", "space"}
+
+// defaultMaxRetries 更新页面发生版本冲突时的默认重试次数
+const defaultMaxRetries = 3
+
+// APIError 表示Confluence REST API返回的错误
+type APIError struct {
+	StatusCode int    `json:"statusCode"`
+	Reason     string `json:"reason"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("confluence api error %d (%s): %s", e.StatusCode, e.Reason, e.Message)
+}
+
+// IsNotFound 判断错误是否表示页面不存在
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict 判断错误是否表示版本冲突(页面已被其他人修改)
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsUnauthorized 判断错误是否表示认证失败或没有权限
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// hasStatus 判断错误是否是指定状态码的APIError
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// Page 表示Confluence页面(content)
+type Page struct {
+	ID      string  `json:"id"`
+	Type    string  `json:"type"`
+	Status  string  `json:"status"`
+	Title   string  `json:"title"`
+	Space   *Space  `json:"space,omitempty"`
+	Version Version `json:"version"`
+	Body    Body    `json:"body"`
+	Links   Links   `json:"_links"`
+}
+
+// Space 表示页面所在的空间
+type Space struct {
+	Key  string `json:"key"`
+	Name string `json:"name,omitempty"`
+}
+
+// Version 表示页面版本
+type Version struct {
+	Number  int    `json:"number"`
+	When    string `json:"when,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Body 页面内容，按表示格式区分
+type Body struct {
+	Storage *Content `json:"storage,omitempty"`
+	Wiki    *Content `json:"wiki,omitempty"`
+}
+
+// Content 某种表示格式的页面内容
+type Content struct {
+	Value          string `json:"value"`
+	Representation string `json:"representation"`
+}
+
+// Links 页面相关链接
+type Links struct {
+	Base   string `json:"base,omitempty"`
+	WebUI  string `json:"webui,omitempty"`
+	TinyUI string `json:"tinyui,omitempty"`
+}
+
+// StorageValue 返回存储格式的页面内容，未展开body.storage时为空
+func (p *Page) StorageValue() string {
+	if p.Body.Storage == nil {
+		return ""
+	}
+	return p.Body.Storage.Value
+}
+
+// URL 返回页面的浏览地址
+func (p *Page) URL() string {
+	if p.Links.WebUI == "" {
+		return ""
+	}
+	return p.Links.Base + p.Links.WebUI
+}
+
+// Client 是Confluence REST API的客户端
+//
+// 设置了Token时使用个人访问令牌(Bearer)认证，否则使用用户名和密码的Basic认证。
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	Token      string
+	MaxRetries int // 更新页面发生版本冲突时的重试次数
+	HTTPClient *http.Client
+}
+
+// NewClient 创建REST客户端，baseURL为Confluence的根地址(如https://confluence.example.com)
+func NewClient(baseURL, username, password, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Username:   username,
+		Password:   password,
+		Token:      token,
+		MaxRetries: defaultMaxRetries,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetPage 获取页面，expand为空时展开DefaultExpand中的字段
+func (c *Client) GetPage(pageID string, expand ...string) (*Page, error) {
+	if len(expand) == 0 {
+		expand = DefaultExpand
+	}
+
+	path := "/content/" + url.PathEscape(pageID) + "?expand=" + url.QueryEscape(strings.Join(expand, ","))
+	var page Page
+	if err := c.doJSON(http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdatePage 更新页面标题和内容，版本号在当前版本上加一
+//
+// title为空时保留原标题。其他人同时修改页面导致版本冲突时，重新获取最新版本后重试，
+// 重试次数用尽后返回冲突错误(IsConflict为true)。
+func (c *Client) UpdatePage(pageID, title, value, representation string) (*Page, error) {
+	if representation == "" {
+		representation = RepresentationStorage
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		current, err := c.GetPage(pageID, "version", "space")
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := c.putPage(current, title, value, representation)
+		if err == nil {
+			return updated, nil
+		}
+		if !IsConflict(err) {
+			return nil, err
+		}
+
+		lastErr = err
+		log.Printf("[ConfluenceClient] Version conflict updating page %s (version %d), retrying", pageID, current.Version.Number)
+	}
+
+	return nil, lastErr
+}
+
+// putPage 基于当前版本提交页面更新
+func (c *Client) putPage(current *Page, title, value, representation string) (*Page, error) {
+	if title == "" {
+		title = current.Title
+	}
+	pageType := current.Type
+	if pageType == "" {
+		pageType = "page"
+	}
+
+	update := map[string]interface{}{
+		"id":      current.ID,
+		"type":    pageType,
+		"title":   title,
+		"version": Version{Number: current.Version.Number + 1},
+		"body": map[string]interface{}{
+			representation: Content{Value: value, Representation: representation},
+		},
+	}
+	if current.Space != nil {
+		update["space"] = Space{Key: current.Space.Key}
+	}
+
+	var page Page
+	if err := c.doJSON(http.MethodPut, "/content/"+url.PathEscape(current.ID), update, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// doJSON 发送JSON请求并解码JSON响应
+func (c *Client) doJSON(method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+"/rest/api"+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 非2xx响应转换为APIError
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		if apiErr.Reason == "" {
+			apiErr.Reason = http.StatusText(resp.StatusCode)
+		}
+		apiErr.StatusCode = resp.StatusCode
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
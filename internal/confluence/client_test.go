@@ -0,0 +1,156 @@
+package confluence_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"my-scheduler-go/internal/confluence"
+	"my-scheduler-go/internal/confluence/confluencetest"
+)
+
+// editingTransport 在客户端提交更新(PUT)之前模拟其他用户修改页面，并记录请求的Authorization头
+type editingTransport struct {
+	server *confluencetest.Server
+	pageID string
+	edits  int // 需要模拟的修改次数，-1表示每次PUT前都修改
+
+	mu      sync.Mutex
+	auth    []string
+	methods []string
+}
+
+func (t *editingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.auth = append(t.auth, r.Header.Get("Authorization"))
+	t.methods = append(t.methods, r.Method)
+	edit := r.Method == http.MethodPut && t.edits != 0
+	if edit && t.edits > 0 {
+		t.edits--
+	}
+	t.mu.Unlock()
+
+	if edit {
+		if err := t.server.SimulateEdit(t.pageID, "<p>edited by someone else</p>"); err != nil {
+			return nil, err
+		}
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newFakeConfluence 启动使用Basic认证的模拟服务器，并创建经过editingTransport的客户端
+func newFakeConfluence(t *testing.T, edits int) (*confluencetest.Server, *confluence.Client, *editingTransport) {
+	t.Helper()
+	server, err := confluencetest.NewServer("conf_user", "secret", "")
+	if err != nil {
+		t.Fatalf("start fake confluence: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	server.AddPage("100", "Results", "<p>v1</p>")
+
+	transport := &editingTransport{server: server, pageID: "100", edits: edits}
+	client := confluence.NewClient(server.URL, "conf_user", "secret", "")
+	client.HTTPClient = &http.Client{Transport: transport}
+	return server, client, transport
+}
+
+// putVersions 返回服务器收到的PUT请求中提交的版本号
+func putVersions(t *testing.T, server *confluencetest.Server) []string {
+	t.Helper()
+	var versions []string
+	for _, request := range server.Requests() {
+		if request.Method != http.MethodPut {
+			continue
+		}
+		body := string(request.Body)
+		start := strings.Index(body, `"version":{"number":`)
+		if start < 0 {
+			t.Fatalf("PUT without version: %s", body)
+		}
+		rest := body[start+len(`"version":{"number":`):]
+		versions = append(versions, rest[:strings.IndexAny(rest, ",}")])
+	}
+	return versions
+}
+
+// 提交前页面被其他人修改时返回409，客户端重新获取页面后以新版本加一重试
+func TestUpdatePageRetriesAfterConcurrentEdit(t *testing.T) {
+	server, client, transport := newFakeConfluence(t, 1)
+
+	page, err := client.UpdatePage("100", "", "<p>report</p>", "")
+	if err != nil {
+		t.Fatalf("UpdatePage: %v", err)
+	}
+	if page.Version.Number != 3 || page.Title != "Results" {
+		t.Errorf("updated page = version %d %q, want version 3 keeping the title", page.Version.Number, page.Title)
+	}
+	if current, _ := server.Page("100"); current.StorageValue() != "<p>report</p>" {
+		t.Errorf("page content = %q", current.StorageValue())
+	}
+
+	// 第一次以版本2提交(冲突，此时页面已是版本2)，重新获取后以版本3提交
+	if got := strings.Join(putVersions(t, server), ","); got != "2,3" {
+		t.Errorf("PUT versions = %s, want 2,3", got)
+	}
+	if got := strings.Join(transport.methods, ","); got != "GET,PUT,GET,PUT" {
+		t.Errorf("requests = %s, want a re-fetch between the two updates", got)
+	}
+}
+
+// 每次提交都冲突时，重试MaxRetries次后返回冲突错误
+func TestUpdatePageGivesUpAfterMaxRetries(t *testing.T) {
+	server, client, _ := newFakeConfluence(t, -1)
+	client.MaxRetries = 2
+
+	_, err := client.UpdatePage("100", "", "<p>report</p>", "")
+	if !confluence.IsConflict(err) {
+		t.Fatalf("err = %v, want a version conflict", err)
+	}
+	if got := len(putVersions(t, server)); got != 3 {
+		t.Errorf("sent %d updates, want 1 + 2 retries", got)
+	}
+	if current, _ := server.Page("100"); current.StorageValue() == "<p>report</p>" {
+		t.Error("conflicting update was applied")
+	}
+}
+
+// 设置Token时使用Bearer认证，否则使用Basic认证
+func TestClientAuthentication(t *testing.T) {
+	server, err := confluencetest.NewServer("conf_user", "secret", "pat-123")
+	if err != nil {
+		t.Fatalf("start fake confluence: %v", err)
+	}
+	defer server.Close()
+	server.AddPage("100", "Results", "<p>v1</p>")
+
+	tests := []struct {
+		name       string
+		client     *confluence.Client
+		wantPrefix string
+		wantErr    bool
+	}{
+		{"token", confluence.NewClient(server.URL, "conf_user", "wrong", "pat-123"), "Bearer pat-123", false},
+		{"basic", confluence.NewClient(server.URL, "conf_user", "secret", ""), "Basic ", false},
+		{"wrong token", confluence.NewClient(server.URL, "conf_user", "secret", "other"), "Bearer other", true},
+		{"wrong password", confluence.NewClient(server.URL, "conf_user", "wrong", ""), "Basic ", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &editingTransport{server: server, pageID: "100"}
+			tt.client.HTTPClient = &http.Client{Transport: transport}
+
+			page, err := tt.client.GetPage("100")
+			if tt.wantErr {
+				if !confluence.IsUnauthorized(err) {
+					t.Errorf("err = %v, want unauthorized", err)
+				}
+			} else if err != nil || page.StorageValue() != "<p>v1</p>" {
+				t.Errorf("GetPage = %v, %v", page, err)
+			}
+			if len(transport.auth) != 1 || !strings.HasPrefix(transport.auth[0], tt.wantPrefix) {
+				t.Errorf("Authorization = %q, want prefix %q", transport.auth, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Package confluencetest 提供进程内的模拟Confluence服务器，
+// 用于开发模式和测试Confluence客户端(页面读取、版本递增和冲突)
+package confluencetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"my-scheduler-go/internal/confluence"
+)
+
+// RecordedRequest 记录服务器收到的一个REST请求
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+	Time   time.Time
+}
+
+// Server 是模拟的Confluence服务器，页面保存在内存中
+//
+// 设置了用户名或令牌时校验认证信息，否则接受任何请求。
+// 以wiki格式提交的内容不做转换，原样作为存储格式返回。
+type Server struct {
+	URL string
+
+	username string
+	password string
+	token    string
+
+	mu       sync.Mutex
+	pages    map[string]*confluence.Page
+	requests []RecordedRequest
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer 在本地随机端口上启动模拟服务器
+func NewServer(username, password, token string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:      "http://" + listener.Addr().String(),
+		username: username,
+		password: password,
+		token:    token,
+		pages:    make(map[string]*confluence.Page),
+		listener: listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rest/api/content/{id}", s.handleGetPage)
+	mux.HandleFunc("PUT /rest/api/content/{id}", s.handleUpdatePage)
+	s.server = &http.Server{Handler: s.authenticate(mux)}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("[FakeConfluence] Server error: %v", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// Close 关闭服务器
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// AddPage 添加或替换页面，版本号从1开始
+func (s *Server) AddPage(id, title, storage string) *confluence.Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page := &confluence.Page{
+		ID:      id,
+		Type:    "page",
+		Status:  "current",
+		Title:   title,
+		Space:   &confluence.Space{Key: "SCHED", Name: "Scheduler"},
+		Version: confluence.Version{Number: 1, When: time.Now().Format(time.RFC3339)},
+		Body: confluence.Body{Storage: &confluence.Content{
+			Value:          storage,
+			Representation: confluence.RepresentationStorage,
+		}},
+		Links: confluence.Links{Base: s.URL, WebUI: "/pages/viewpage.action?pageId=" + id},
+	}
+	s.pages[id] = page
+	return copyPage(page)
+}
+
+// Page 返回页面的当前内容
+func (s *Server) Page(id string) (*confluence.Page, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page, ok := s.pages[id]
+	if !ok {
+		return nil, false
+	}
+	return copyPage(page), true
+}
+
+// SimulateEdit 模拟其他用户修改页面(版本号加一)，用于触发版本冲突
+func (s *Server) SimulateEdit(id, storage string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page, ok := s.pages[id]
+	if !ok {
+		return fmt.Errorf("page %s not found", id)
+	}
+	page.Version.Number++
+	page.Version.When = time.Now().Format(time.RFC3339)
+	page.Body.Storage = &confluence.Content{Value: storage, Representation: confluence.RepresentationStorage}
+	return nil
+}
+
+// Requests 返回收到的所有REST请求
+func (s *Server) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+// authenticate 校验Basic认证或Bearer令牌，并记录请求
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.username != "" || s.token != "" {
+			username, password, basic := r.BasicAuth()
+			bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			validBasic := basic && s.username != "" && username == s.username && password == s.password
+			validToken := s.token != "" && bearer == s.token
+			if !validBasic && !validToken {
+				writeError(w, http.StatusUnauthorized, "invalid credentials")
+				return
+			}
+		}
+
+		body, _ := readAll(r)
+		s.mu.Lock()
+		s.requests = append(s.requests, RecordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Body:   body,
+			Time:   time.Now(),
+		})
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleGetPage 处理GET /rest/api/content/{id}，只有expand包含body.storage时返回内容
+func (s *Server) handleGetPage(w http.ResponseWriter, r *http.Request) {
+	page, ok := s.Page(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "No content found with id: "+r.PathValue("id"))
+		return
+	}
+
+	expand := r.URL.Query().Get("expand")
+	if !strings.Contains(expand, "body.storage") {
+		page.Body = confluence.Body{}
+	}
+	if !strings.Contains(expand, "space") {
+		page.Space = nil
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleUpdatePage 处理PUT /rest/api/content/{id}，版本号必须是当前版本加一
+func (s *Server) handleUpdatePage(w http.ResponseWriter, r *http.Request) {
+	var update struct {
+		Title   string                         `json:"title"`
+		Version confluence.Version             `json:"version"`
+		Body    map[string]*confluence.Content `json:"body"`
+	}
+	body, err := readAll(r)
+	if err == nil {
+		err = json.Unmarshal(body, &update)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	page, ok := s.pages[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "No content found with id: "+r.PathValue("id"))
+		return
+	}
+	if update.Version.Number != page.Version.Number+1 {
+		current := page.Version.Number
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("Version must be incremented on update. Current version is: %d", current))
+		return
+	}
+
+	var content *confluence.Content
+	for _, c := range update.Body {
+		content = c
+	}
+	if content == nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "body is required")
+		return
+	}
+
+	if update.Title != "" {
+		page.Title = update.Title
+	}
+	page.Version = confluence.Version{Number: update.Version.Number, When: time.Now().Format(time.RFC3339)}
+	page.Body.Storage = &confluence.Content{Value: content.Value, Representation: confluence.RepresentationStorage}
+	result := copyPage(page)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readAll 读取请求体，并允许后续处理器再次读取
+func readAll(r *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// copyPage 复制页面，避免调用方修改服务器中的数据
+func copyPage(page *confluence.Page) *confluence.Page {
+	result := *page
+	if page.Space != nil {
+		space := *page.Space
+		result.Space = &space
+	}
+	if page.Body.Storage != nil {
+		storage := *page.Body.Storage
+		result.Body.Storage = &storage
+	}
+	return &result
+}
+
+// writeJSON 写入JSON响应
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError 以Confluence的错误格式写入响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, confluence.APIError{
+		StatusCode: status,
+		Reason:     http.StatusText(status),
+		Message:    message,
+	})
+}
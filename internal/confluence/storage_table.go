@@ -0,0 +1,457 @@
+package confluence
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Table 是从存储格式页面中解析出的表格，第一行作为表头
+type Table struct {
+	Index   int      // 表格在页面中的序号(从1开始)
+	Section string   // 表格前最近的标题文本
+	Headers []string // 规范化后的列名，见NormalizeHeader
+	Rows    []Row
+}
+
+// Row 表格中的一行数据，按列名访问单元格
+type Row struct {
+	Number int // 数据行的行号(从1开始，不含表头)
+	Cells  map[string]Cell
+}
+
+// Cell 单元格内容
+//
+// Text是单元格的纯文本: 段落、列表项和换行之间以换行符分隔，链接取链接文字，
+// 用户提及取用户名(没有用户名时取用户键或账号ID)，宏取其参数或正文中的文本。
+type Cell struct {
+	Text     string
+	Links    []string // 超链接地址和页面链接的标题
+	Mentions []string // 提及的用户
+}
+
+// Value 返回指定列的文本，列不存在时返回空字符串
+func (r Row) Value(header string) string {
+	return r.Cells[header].Text
+}
+
+// Values 返回以列名为键的文本值
+func (r Row) Values() map[string]string {
+	values := make(map[string]string, len(r.Cells))
+	for header, cell := range r.Cells {
+		values[header] = cell.Text
+	}
+	return values
+}
+
+// IsEmpty 判断行中是否所有单元格都为空
+func (r Row) IsEmpty() bool {
+	for _, cell := range r.Cells {
+		if cell.Text != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HasHeaders 判断表格是否包含所有指定的列
+func (t *Table) HasHeaders(headers ...string) bool {
+	for _, header := range headers {
+		found := false
+		for _, h := range t.Headers {
+			if h == header {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeHeader 将表头文本规范化为列名: 小写，空格和连字符替换为下划线，
+// 如"Channel ID"规范化为"channel_id"
+func NormalizeHeader(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '\n' || r == '\t'
+	})
+	return strings.Join(fields, "_")
+}
+
+// ParseStorageTables 解析存储格式(XHTML)页面中的所有表格，嵌套在单元格中的表格作为单元格内容
+func ParseStorageTables(storage string) ([]*Table, error) {
+	root, err := parseStorage(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*Table
+	section := ""
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, child := range n.children {
+			switch {
+			case isHeading(child.tag):
+				section = strings.TrimSpace(cellText(child).Text)
+			case child.tag == "table":
+				table := buildTable(child)
+				table.Index = len(tables) + 1
+				table.Section = section
+				tables = append(tables, table)
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+
+	return tables, nil
+}
+
+// node 是存储格式文档中的一个元素或文本节点
+type node struct {
+	tag      string // 元素名(如"td"、"ac:link")，文本节点为空
+	attrs    map[string]string
+	text     string
+	children []*node
+	parent   *node
+}
+
+// voidElements 没有结束标签的HTML元素
+var voidElements = map[string]bool{
+	"br": true, "hr": true, "img": true, "col": true, "input": true, "meta": true, "link": true,
+}
+
+// parseStorage 将存储格式解析为节点树
+//
+// 存储格式中的ac:/ri:元素常以自闭合形式出现，HTML5解析器会忽略自闭合标记，
+// 因此这里基于分词器构建节点树，并容忍未闭合或多余的结束标签。
+func parseStorage(storage string) (*node, error) {
+	tokenizer := html.NewTokenizer(strings.NewReader(storage))
+	tokenizer.AllowCDATA(true)
+
+	root := &node{}
+	current := root
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("invalid storage format: %v", err)
+			}
+			return root, nil
+		case html.TextToken:
+			text := string(tokenizer.Text())
+			current.children = append(current.children, &node{text: text, parent: current})
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			child := &node{tag: token.Data, attrs: make(map[string]string, len(token.Attr)), parent: current}
+			for _, attr := range token.Attr {
+				name := attr.Key
+				if attr.Namespace != "" {
+					name = attr.Namespace + ":" + attr.Key
+				}
+				child.attrs[name] = attr.Val
+			}
+			current.children = append(current.children, child)
+			if tokenType == html.StartTagToken && !voidElements[child.tag] {
+				current = child
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			// 回到最近的同名元素的父节点，没有同名元素时忽略该结束标签
+			for n := current; n != root; n = n.parent {
+				if n.tag == token.Data {
+					current = n.parent
+					break
+				}
+			}
+		}
+	}
+}
+
+// isHeading 判断元素是否是标题
+func isHeading(tag string) bool {
+	return len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6'
+}
+
+// tableRows 返回表格中的所有行(包括thead/tbody/tfoot中的行)，不进入嵌套的表格
+func tableRows(table *node) []*node {
+	var rows []*node
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, child := range n.children {
+			switch child.tag {
+			case "tr":
+				rows = append(rows, child)
+			case "table":
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// spanCell 跨多行的单元格，在后续行中占据相同的列
+type spanCell struct {
+	cell      Cell
+	remaining int
+	colspan   int
+}
+
+// buildTable 将表格元素展开为规则的网格，第一行作为表头
+//
+// 跨行合并的单元格在其跨越的每一行中重复出现；跨列合并的单元格只填入第一列，
+// 其余列为空(表头跨列时其余列没有列名，会被忽略)。
+func buildTable(element *node) *Table {
+	table := &Table{}
+	spans := make(map[int]*spanCell)
+
+	for rowIndex, tr := range tableRows(element) {
+		var grid []Cell
+		col := 0
+		fill := func() {
+			// 填入上方行中跨行到当前位置的单元格
+			for {
+				span, ok := spans[col]
+				if !ok || span.remaining == 0 {
+					return
+				}
+				for i := 0; i < span.colspan; i++ {
+					if i == 0 {
+						grid = append(grid, span.cell)
+					} else {
+						grid = append(grid, Cell{})
+					}
+				}
+				span.remaining--
+				if span.remaining == 0 {
+					delete(spans, col)
+				}
+				col += span.colspan
+			}
+		}
+
+		for _, td := range tr.children {
+			if td.tag != "td" && td.tag != "th" {
+				continue
+			}
+			fill()
+
+			cell := cellText(td)
+			colspan := spanAttr(td, "colspan")
+			rowspan := spanAttr(td, "rowspan")
+			if rowspan > 1 {
+				spans[col] = &spanCell{cell: cell, remaining: rowspan - 1, colspan: colspan}
+			}
+			for i := 0; i < colspan; i++ {
+				if i == 0 {
+					grid = append(grid, cell)
+				} else {
+					grid = append(grid, Cell{})
+				}
+			}
+			col += colspan
+		}
+		fill()
+
+		if rowIndex == 0 {
+			for _, cell := range grid {
+				table.Headers = append(table.Headers, NormalizeHeader(cell.Text))
+			}
+			continue
+		}
+
+		row := Row{Number: rowIndex, Cells: make(map[string]Cell, len(table.Headers))}
+		for i, header := range table.Headers {
+			if header == "" || i >= len(grid) {
+				continue
+			}
+			if _, exists := row.Cells[header]; !exists {
+				row.Cells[header] = grid[i]
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// spanAttr 读取colspan/rowspan属性，无效或缺省时为1
+func spanAttr(n *node, name string) int {
+	value, err := strconv.Atoi(strings.TrimSpace(n.attrs[name]))
+	if err != nil || value < 1 {
+		return 1
+	}
+	return value
+}
+
+// blockElements 在文本中以换行分隔的元素
+var blockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "br": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// textMacros 以参数作为文本的宏及其参数名
+var textMacros = map[string]string{
+	"status": "title",
+	"jira":   "key",
+	"anchor": "",
+}
+
+// cellText 提取单元格的文本、链接和用户提及
+func cellText(n *node) Cell {
+	var cell Cell
+	var b strings.Builder
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, child := range n.children {
+			switch {
+			case child.tag == "":
+				b.WriteString(child.text)
+			case child.tag == "ac:link":
+				b.WriteString(linkText(child, &cell))
+			case child.tag == "a":
+				if href := child.attrs["href"]; href != "" {
+					cell.Links = append(cell.Links, href)
+				}
+				walk(child)
+			case child.tag == "ri:user":
+				user := userName(child)
+				cell.Mentions = append(cell.Mentions, user)
+				b.WriteString(user)
+			case child.tag == "ac:structured-macro" || child.tag == "ac:macro":
+				b.WriteString(macroText(child, &cell))
+			case child.tag == "time":
+				b.WriteString(child.attrs["datetime"])
+			case child.tag == "ac:emoticon" || child.tag == "ac:placeholder" || child.tag == "ac:parameter":
+			case blockElements[child.tag]:
+				newline()
+				walk(child)
+				newline()
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+
+	// 规整空白: 每行内的连续空白(包括&nbsp;)合并为一个空格，去掉空行
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	cell.Text = strings.Join(lines, "\n")
+	return cell
+}
+
+// linkText 返回ac:link的文字: 用户提及取用户名，页面链接取链接正文或页面标题
+func linkText(link *node, cell *Cell) string {
+	var target, body *node
+	for _, child := range link.children {
+		switch child.tag {
+		case "ri:user", "ri:page", "ri:attachment", "ri:space", "ri:blog-post":
+			target = child
+		case "ac:plain-text-link-body", "ac:link-body":
+			body = child
+		}
+	}
+
+	if target != nil && target.tag == "ri:user" {
+		user := userName(target)
+		cell.Mentions = append(cell.Mentions, user)
+		return user
+	}
+
+	title := ""
+	if target != nil {
+		title = target.attrs["ri:content-title"]
+		if title == "" {
+			title = target.attrs["ri:filename"]
+		}
+		if title == "" {
+			title = target.attrs["ri:space-key"]
+		}
+		if title != "" {
+			cell.Links = append(cell.Links, title)
+		}
+	}
+	if body != nil {
+		if text := cellText(body).Text; text != "" {
+			return text
+		}
+	}
+	return title
+}
+
+// userName 返回用户提及的用户名，没有用户名时返回用户键或账号ID
+func userName(user *node) string {
+	for _, attr := range []string{"ri:username", "ri:userkey", "ri:account-id"} {
+		if value := user.attrs[attr]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// macroText 返回宏的文本: 代码类宏取纯文本正文，status/jira等宏取参数，其他宏取富文本正文
+func macroText(macro *node, cell *Cell) string {
+	name := macro.attrs["ac:name"]
+	if param, ok := textMacros[name]; ok {
+		if param == "" {
+			return ""
+		}
+		for _, child := range macro.children {
+			if child.tag == "ac:parameter" && child.attrs["ac:name"] == param {
+				return cellText(child).Text
+			}
+		}
+		return ""
+	}
+
+	var parts []string
+	for _, child := range macro.children {
+		switch child.tag {
+		case "ac:plain-text-body":
+			parts = append(parts, strings.TrimSpace(rawText(child)))
+		case "ac:rich-text-body":
+			body := cellText(child)
+			cell.Links = append(cell.Links, body.Links...)
+			cell.Mentions = append(cell.Mentions, body.Mentions...)
+			parts = append(parts, body.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// rawText 返回节点下所有文本(保留原有空白，用于代码块)
+func rawText(n *node) string {
+	var b strings.Builder
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, child := range n.children {
+			if child.tag == "" {
+				b.WriteString(child.text)
+			} else {
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	return b.String()
+}
@@ -0,0 +1,305 @@
+package confluence
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// findElement 返回节点树中第一个指定名称的元素
+func findElement(n *node, tag string) *node {
+	for _, child := range n.children {
+		if child.tag == tag {
+			return child
+		}
+		if found := findElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// rowValues 返回表格所有数据行的文本值
+func rowValues(table *Table) []map[string]string {
+	var rows []map[string]string
+	for _, row := range table.Rows {
+		rows = append(rows, row.Values())
+	}
+	return rows
+}
+
+func TestNormalizeHeader(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Channel ID", "channel_id"},
+		{"  Forward-Type ", "forward_type"},
+		{"on_match", "on_match"},
+		{"Report\n Type", "report_type"},
+		{"Rule  --  ID", "rule_id"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeHeader(tt.text); got != tt.want {
+			t.Errorf("NormalizeHeader(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		headers []string
+		rows    []map[string]string
+	}{
+		{
+			name: "header normalization and thead/tbody",
+			storage: `<table><thead><tr><th>Channel ID</th><th><strong>Forward-Type</strong></th><th>On
+				Match</th></tr></thead><tbody><tr><td>ch-1</td><td>dm</td><td>stop</td></tr></tbody></table>`,
+			headers: []string{"channel_id", "forward_type", "on_match"},
+			rows:    []map[string]string{{"channel_id": "ch-1", "forward_type": "dm", "on_match": "stop"}},
+		},
+		{
+			name: "rowspan repeats the cell in the spanned rows",
+			storage: `<table><tr><th>ID</th><th>Channel</th></tr>
+				<tr><td rowspan="3">ops</td><td>ch-1</td></tr>
+				<tr><td>ch-2</td></tr>
+				<tr><td>ch-3</td></tr>
+				<tr><td>dev</td><td>ch-4</td></tr></table>`,
+			headers: []string{"id", "channel"},
+			rows: []map[string]string{
+				{"id": "ops", "channel": "ch-1"},
+				{"id": "ops", "channel": "ch-2"},
+				{"id": "ops", "channel": "ch-3"},
+				{"id": "dev", "channel": "ch-4"},
+			},
+		},
+		{
+			name: "colspan fills only the first column",
+			storage: `<table><tr><th>A</th><th>B</th><th>C</th></tr>
+				<tr><td colspan="2">wide</td><td>c1</td></tr>
+				<tr><td>a2</td><td colspan="0">b2</td><td>c2</td></tr></table>`,
+			headers: []string{"a", "b", "c"},
+			rows: []map[string]string{
+				{"a": "wide", "b": "", "c": "c1"},
+				{"a": "a2", "b": "b2", "c": "c2"},
+			},
+		},
+		{
+			name: "rowspan combined with colspan",
+			storage: `<table><tr><th>A</th><th>B</th><th>C</th></tr>
+				<tr><td rowspan="2" colspan="2">X</td><td>1</td></tr>
+				<tr><td>2</td></tr></table>`,
+			headers: []string{"a", "b", "c"},
+			rows: []map[string]string{
+				{"a": "X", "b": "", "c": "1"},
+				{"a": "X", "b": "", "c": "2"},
+			},
+		},
+		{
+			name: "header colspan leaves unnamed columns out",
+			storage: `<table><tr><th colspan="2">Both</th><th>C</th></tr>
+				<tr><td>1</td><td>2</td><td>3</td></tr></table>`,
+			headers: []string{"both", "", "c"},
+			rows:    []map[string]string{{"both": "1", "c": "3"}},
+		},
+		{
+			name: "duplicate header keeps the first column",
+			storage: `<table><tr><th>ID</th><th>id</th></tr>
+				<tr><td>first</td><td>second</td></tr></table>`,
+			headers: []string{"id", "id"},
+			rows:    []map[string]string{{"id": "first"}},
+		},
+		{
+			name: "malformed rows",
+			storage: `<table><tr><th>ID</th><th>Channel</th><th>Type</th></tr>
+				<tr><td>short</td></tr>
+				<tr><td>stray</span></p></td><td>ch-1</td><td>dm</tr>
+				<tr><td></td><td> &nbsp; </td><td></td></tr></table>`,
+			headers: []string{"id", "channel", "type"},
+			rows: []map[string]string{
+				{"id": "short"},
+				{"id": "stray", "channel": "ch-1", "type": "dm"},
+				{"id": "", "channel": "", "type": ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseStorage(tt.storage)
+			if err != nil {
+				t.Fatalf("parseStorage: %v", err)
+			}
+			table := buildTable(findElement(root, "table"))
+			if !reflect.DeepEqual(table.Headers, tt.headers) {
+				t.Errorf("headers = %q, want %q", table.Headers, tt.headers)
+			}
+			if got := rowValues(table); !reflect.DeepEqual(got, tt.rows) {
+				t.Errorf("rows = %v, want %v", got, tt.rows)
+			}
+			for i, row := range table.Rows {
+				if row.Number != i+1 {
+					t.Errorf("row %d has number %d", i, row.Number)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTableEmptyRow(t *testing.T) {
+	root, _ := parseStorage(`<table><tr><th>ID</th></tr><tr><td><p> </p></td></tr><tr><td>x</td></tr></table>`)
+	table := buildTable(findElement(root, "table"))
+	if len(table.Rows) != 2 || !table.Rows[0].IsEmpty() || table.Rows[1].IsEmpty() {
+		t.Errorf("rows = %v, want an empty row followed by x", rowValues(table))
+	}
+}
+
+func TestCellText(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		text     string
+		links    []string
+		mentions []string
+	}{
+		{
+			name:    "paragraphs, lists and line breaks",
+			content: `<p>one</p><p>two&nbsp;  three</p><ul><li>a</li><li><p>b</p></li></ul>c<br/>d`,
+			text:    "one\ntwo three\na\nb\nc\nd",
+		},
+		{
+			name:     "user mentions",
+			content:  `<ac:link><ri:user ri:username="alice" /></ac:link>, <ri:user ri:userkey="k42"/> and <ac:link><ri:user ri:account-id="557058:abc"/></ac:link>`,
+			text:     "alice, k42 and 557058:abc",
+			mentions: []string{"alice", "k42", "557058:abc"},
+		},
+		{
+			name:    "page link with CDATA body",
+			content: `see <ac:link><ri:page ri:content-title="Runbook" /><ac:plain-text-link-body><![CDATA[the <runbook>]]></ac:plain-text-link-body></ac:link>`,
+			text:    "see the <runbook>",
+			links:   []string{"Runbook"},
+		},
+		{
+			name:    "page link without body and attachment link",
+			content: `<ac:link><ri:page ri:content-title="Home"/></ac:link> <ac:link><ri:attachment ri:filename="report.xlsx"/></ac:link>`,
+			text:    "Home report.xlsx",
+			links:   []string{"Home", "report.xlsx"},
+		},
+		{
+			name:    "hyperlink",
+			content: `<a href="https://example.com/docs">the <em>docs</em></a>`,
+			text:    "the docs",
+			links:   []string{"https://example.com/docs"},
+		},
+		{
+			name:    "status macro",
+			content: `<ac:structured-macro ac:name="status" ac:schema-version="1"><ac:parameter ac:name="colour">Green</ac:parameter><ac:parameter ac:name="title">Done</ac:parameter></ac:structured-macro>`,
+			text:    "Done",
+		},
+		{
+			name:    "jira macro",
+			content: `<ac:structured-macro ac:name="jira"><ac:parameter ac:name="server">Jira</ac:parameter><ac:parameter ac:name="key">SCHED-1</ac:parameter></ac:structured-macro>`,
+			text:    "SCHED-1",
+		},
+		{
+			name: "code macro with CDATA plain-text body",
+			content: `<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">yaml</ac:parameter><ac:plain-text-body><![CDATA[  pattern: "<ERROR>"
+  labels: [a, b]  ]]></ac:plain-text-body></ac:structured-macro>`,
+			text: "pattern: \"<ERROR>\"\nlabels: [a, b]",
+		},
+		{
+			name:     "rich text body macro",
+			content:  `<ac:structured-macro ac:name="info"><ac:parameter ac:name="title">Note</ac:parameter><ac:rich-text-body><p>ask <ac:link><ri:user ri:username="bob"/></ac:link></p></ac:rich-text-body></ac:structured-macro>`,
+			text:     "ask bob",
+			mentions: []string{"bob"},
+		},
+		{
+			name:    "ignored elements",
+			content: `<ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">top</ac:parameter></ac:structured-macro>ok <ac:emoticon ac:name="tick"/><ac:placeholder>type here</ac:placeholder>`,
+			text:    "ok",
+		},
+		{
+			name:    "date",
+			content: `due <time datetime="2024-05-01" />`,
+			text:    "due 2024-05-01",
+		},
+		{
+			name:    "nested table",
+			content: `<p>values:</p><table><tr><th>k</th></tr><tr><td>v1</td></tr><tr><td>v2</td></tr></table>`,
+			text:    "values:\nk\nv1\nv2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseStorage("<td>" + tt.content + "</td>")
+			if err != nil {
+				t.Fatalf("parseStorage: %v", err)
+			}
+			cell := cellText(findElement(root, "td"))
+			if cell.Text != tt.text {
+				t.Errorf("text = %q, want %q", cell.Text, tt.text)
+			}
+			if !reflect.DeepEqual(cell.Links, tt.links) {
+				t.Errorf("links = %q, want %q", cell.Links, tt.links)
+			}
+			if !reflect.DeepEqual(cell.Mentions, tt.mentions) {
+				t.Errorf("mentions = %q, want %q", cell.Mentions, tt.mentions)
+			}
+		})
+	}
+}
+
+func TestParseStorageTables(t *testing.T) {
+	storage := `<p>intro</p>
+<table><tr><th>Name</th></tr><tr><td>untitled</td></tr></table>
+<h2>Forwarding <em>rules</em></h2>
+<ac:layout><ac:layout-section ac:type="single"><ac:layout-cell>
+<table><tbody><tr><th>ID</th><th>Values</th></tr>
+<tr><td>ops</td><td><table><tr><th>Key</th></tr><tr><td>nested</td></tr></table></td></tr>
+<tr><td>dev</td><td>plain</td></tr></tbody></table>
+</ac:layout-cell></ac:layout-section></ac:layout>
+<h3>Reports</h3>
+<p>text between tables</p>
+<table><tr><th>Report Type</th></tr><tr><td>weekly</td></tr></table>
+<table><tr><th>Owner</th></tr></table>`
+
+	tables, err := ParseStorageTables(storage)
+	if err != nil {
+		t.Fatalf("ParseStorageTables: %v", err)
+	}
+
+	type summary struct {
+		Index   int
+		Section string
+		Headers string
+		Rows    int
+	}
+	var got []summary
+	for _, table := range tables {
+		got = append(got, summary{table.Index, table.Section, strings.Join(table.Headers, ","), len(table.Rows)})
+	}
+	want := []summary{
+		{1, "", "name", 1},
+		{2, "Forwarding rules", "id,values", 2},
+		{3, "Reports", "report_type", 1},
+		{4, "Reports", "owner", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tables = %+v, want %+v", got, want)
+	}
+
+	// 嵌套表格作为单元格内容，其中的行不属于外层表格
+	if value := tables[1].Rows[0].Value("values"); value != "Key\nnested" {
+		t.Errorf("nested table cell = %q", value)
+	}
+	if value := tables[1].Rows[1].Value("id"); value != "dev" {
+		t.Errorf("second row id = %q, want dev", value)
+	}
+	if !tables[1].HasHeaders("id", "values") || tables[1].HasHeaders("id", "key") {
+		t.Error("HasHeaders does not match the outer table columns")
+	}
+}
@@ -0,0 +1,430 @@
+// Package jira 实现Jira REST API v2(/rest/api/2)的客户端
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPageSize 搜索时每页请求的问题数量(Jira服务器可能返回更少)
+const DefaultPageSize = 100
+
+// treeBatchSize 获取问题树时每次搜索的问题数量
+const treeBatchSize = 50
+
+// APIError 表示Jira REST API返回的错误
+type APIError struct {
+	StatusCode    int               `json:"-"`
+	ErrorMessages []string          `json:"errorMessages"`
+	Errors        map[string]string `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	messages := append([]string(nil), e.ErrorMessages...)
+	fields := make([]string, 0, len(e.Errors))
+	for field := range e.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, field+": "+e.Errors[field])
+	}
+	if len(messages) == 0 {
+		messages = append(messages, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("jira api error %d: %s", e.StatusCode, strings.Join(messages, "; "))
+}
+
+// IsNotFound 判断错误是否表示问题不存在(或没有查看权限)
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized 判断错误是否表示认证失败或没有权限
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// hasStatus 判断错误是否是指定状态码的APIError
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// SearchResult 一页搜索结果
+type SearchResult struct {
+	StartAt    int     `json:"startAt"`
+	MaxResults int     `json:"maxResults"`
+	Total      int     `json:"total"`
+	Issues     []Issue `json:"issues"`
+	// WarningMessages 使用validateQuery=warn时，JQL中有问题的部分(如不存在的key)以警告返回而不是400
+	WarningMessages []string `json:"warningMessages,omitempty"`
+}
+
+// Client 是Jira REST API v2的客户端
+//
+// 设置了Token时使用个人访问令牌(Bearer)认证，否则使用用户名和密码的Basic认证。
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	Token      string
+	PageSize   int // 搜索时每页的问题数量
+	HTTPClient *http.Client
+
+	limiter *rateLimiter
+}
+
+// NewClient 创建REST客户端，baseURL为Jira的根地址(如https://jira.example.com)
+func NewClient(baseURL, username, password, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Username:   username,
+		Password:   password,
+		Token:      token,
+		PageSize:   DefaultPageSize,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetRateLimit 限制每秒最多发送perSecond个请求，<=0时不限制；
+// 服务器返回429时无论是否设置都会按Retry-After等待后重试
+func (c *Client) SetRateLimit(perSecond float64) {
+	c.limiter = newRateLimiter(perSecond)
+}
+
+// ServerInfo Jira服务器信息
+type ServerInfo struct {
+	BaseURL     string `json:"baseUrl"`
+	Version     string `json:"version"`
+	ServerTitle string `json:"serverTitle"`
+}
+
+// ServerInfo 获取服务器信息(不要求认证)
+func (c *Client) ServerInfo() (*ServerInfo, error) {
+	var info ServerInfo
+	if err := c.doJSON(http.MethodGet, "/serverInfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Myself 返回当前认证的用户，可用于校验认证信息
+func (c *Client) Myself() (*User, error) {
+	var user User
+	if err := c.doJSON(http.MethodGet, "/myself", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// IssueURL 返回问题的浏览地址
+func (c *Client) IssueURL(key string) string {
+	return c.BaseURL + "/browse/" + url.PathEscape(key)
+}
+
+// GetIssue 获取问题，fields为空时获取所有可导航字段
+func (c *Client) GetIssue(key string, fields ...string) (*Issue, error) {
+	path := "/issue/" + url.PathEscape(key)
+	if len(fields) > 0 {
+		path += "?fields=" + url.QueryEscape(strings.Join(fields, ","))
+	}
+
+	var issue Issue
+	if err := c.doJSON(http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// SearchPage 执行JQL搜索，返回从startAt开始的一页结果
+func (c *Client) SearchPage(jql string, startAt, maxResults int, fields []string) (*SearchResult, error) {
+	return c.searchPage(jql, "", startAt, maxResults, fields)
+}
+
+// searchPage 执行一页搜索，validateQuery为空时使用服务器默认的严格校验
+func (c *Client) searchPage(jql, validateQuery string, startAt, maxResults int, fields []string) (*SearchResult, error) {
+	query := url.Values{}
+	query.Set("jql", jql)
+	if validateQuery != "" {
+		query.Set("validateQuery", validateQuery)
+	}
+	query.Set("startAt", strconv.Itoa(startAt))
+	query.Set("maxResults", strconv.Itoa(maxResults))
+	if len(fields) > 0 {
+		query.Set("fields", strings.Join(fields, ","))
+	}
+
+	var result SearchResult
+	if err := c.doJSON(http.MethodGet, "/search?"+query.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Search 执行JQL搜索并逐页获取所有结果
+func (c *Client) Search(jql string, fields ...string) ([]Issue, error) {
+	return c.search(jql, "", fields)
+}
+
+// search 逐页获取所有结果，validateQuery为warn时服务器返回的警告记录到日志
+func (c *Client) search(jql, validateQuery string, fields []string) ([]Issue, error) {
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	var issues []Issue
+	for {
+		page, err := c.searchPage(jql, validateQuery, len(issues), pageSize, fields)
+		if err != nil {
+			return nil, err
+		}
+		if len(issues) == 0 {
+			for _, warning := range page.WarningMessages {
+				log.Printf("[JiraClient] Search warning: %s", warning)
+			}
+		}
+		issues = append(issues, page.Issues...)
+
+		// 服务器可能限制每页数量，以实际返回的数量和total判断是否还有下一页
+		if len(page.Issues) == 0 || len(issues) >= page.Total {
+			return issues, nil
+		}
+	}
+}
+
+// GetIssueTree 获取问题及其子任务和链接的问题(递归)，每个问题在树中只出现一次
+//
+// 按层获取: 每层的问题通过key in (...)搜索批量获取，避免逐个请求。
+func (c *Client) GetIssueTree(key string, opts TreeOptions) (*IssueNode, error) {
+	fields := opts.Fields
+	if len(fields) > 0 {
+		fields = appendMissing(fields, "subtasks", "issuelinks")
+	}
+
+	root, err := c.GetIssue(key, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	rootNode := &IssueNode{Issue: root}
+	visited := map[string]bool{root.Key: true}
+	level := []*IssueNode{rootNode}
+
+	for len(level) > 0 && (opts.MaxDepth <= 0 || level[0].Depth < opts.MaxDepth) {
+		// 收集下一层的问题及其与父节点的关系
+		type pending struct {
+			parent   *IssueNode
+			key      string
+			relation string
+		}
+		var next []pending
+		for _, node := range level {
+			for _, subtask := range node.Issue.Fields.Subtasks {
+				if !visited[subtask.Key] {
+					visited[subtask.Key] = true
+					next = append(next, pending{node, subtask.Key, RelationSubtask})
+				}
+			}
+			for _, link := range node.Issue.Fields.IssueLinks {
+				linked, relation := link.Linked()
+				if linked == nil || visited[linked.Key] || !opts.followsLink(link.Type) {
+					continue
+				}
+				visited[linked.Key] = true
+				next = append(next, pending{node, linked.Key, relation})
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+
+		keys := make([]string, len(next))
+		for i, p := range next {
+			keys[i] = p.key
+		}
+		issues, err := c.getIssues(keys, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		level = level[:0:0]
+		for _, p := range next {
+			issue, ok := issues[p.key]
+			if !ok {
+				// 没有查看权限或已删除的问题不出现在搜索结果中
+				log.Printf("[JiraClient] Issue %s linked from %s not found, skipped", p.key, p.parent.Issue.Key)
+				continue
+			}
+			child := &IssueNode{Issue: issue, Relation: p.relation, Depth: p.parent.Depth + 1}
+			p.parent.Children = append(p.parent.Children, child)
+			level = append(level, child)
+		}
+	}
+
+	return rootNode, nil
+}
+
+// getIssues 按key批量获取问题
+func (c *Client) getIssues(keys []string, fields []string) (map[string]*Issue, error) {
+	issues := make(map[string]*Issue, len(keys))
+	for start := 0; start < len(keys); start += treeBatchSize {
+		end := min(start+treeBatchSize, len(keys))
+		// 严格校验下只要有一个key不存在(已删除或没有查看权限)整个搜索就返回400，
+		// 使用warn校验使这些key只产生警告，其余问题照常返回
+		found, err := c.search("key in ("+strings.Join(keys[start:end], ",")+")", "warn", fields)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			issues[found[i].Key] = &found[i]
+		}
+	}
+	return issues, nil
+}
+
+// UpdateIssue 更新问题的字段，fields的键为字段ID(如summary、customfield_10010)
+func (c *Client) UpdateIssue(key string, fields map[string]interface{}) error {
+	payload := map[string]interface{}{"fields": fields}
+	return c.doJSON(http.MethodPut, "/issue/"+url.PathEscape(key), payload, nil)
+}
+
+// GetTransitions 返回问题当前可执行的工作流转换
+func (c *Client) GetTransitions(key string) ([]Transition, error) {
+	var result struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	if err := c.doJSON(http.MethodGet, "/issue/"+url.PathEscape(key)+"/transitions", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Transitions, nil
+}
+
+// TransitionIssue 执行工作流转换，transition为转换的ID、名称或目标状态名称(不区分大小写)，
+// fields为转换界面上需要填写的字段(可为nil)
+func (c *Client) TransitionIssue(key, transition string, fields map[string]interface{}) error {
+	transitions, err := c.GetTransitions(key)
+	if err != nil {
+		return err
+	}
+
+	var selected *Transition
+	for i, t := range transitions {
+		if t.ID == transition || strings.EqualFold(t.Name, transition) ||
+			(t.To != nil && strings.EqualFold(t.To.Name, transition)) {
+			selected = &transitions[i]
+			break
+		}
+	}
+	if selected == nil {
+		names := make([]string, len(transitions))
+		for i, t := range transitions {
+			names[i] = t.Name
+		}
+		return fmt.Errorf("issue %s has no transition %q (available: %s)", key, transition, strings.Join(names, ", "))
+	}
+
+	payload := map[string]interface{}{"transition": map[string]string{"id": selected.ID}}
+	if len(fields) > 0 {
+		payload["fields"] = fields
+	}
+	return c.doJSON(http.MethodPost, "/issue/"+url.PathEscape(key)+"/transitions", payload, nil)
+}
+
+// doJSON 发送JSON请求并解码JSON响应，服务器返回429时等待后重试
+func (c *Client) doJSON(method, path string, payload interface{}, out interface{}) error {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.limiter.wait()
+		resp, err := c.send(method, path, data)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			delay := retryAfter(resp)
+			resp.Body.Close()
+			log.Printf("[JiraClient] Rate limited by %s, retrying in %s", c.BaseURL, delay)
+			time.Sleep(delay)
+			continue
+		}
+		defer resp.Body.Close()
+		return decodeResponse(resp, out)
+	}
+}
+
+// send 发送请求，data为nil时没有请求体
+func (c *Client) send(method, path string, data []byte) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+"/rest/api/2"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	return c.HTTPClient.Do(req)
+}
+
+// decodeResponse 解码JSON响应，非2xx响应转换为APIError
+func decodeResponse(resp *http.Response, out interface{}) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		apiErr.StatusCode = resp.StatusCode
+		return apiErr
+	}
+
+	// 204 No Content(更新和转换成功)
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// appendMissing 将fields中没有的字段追加到末尾
+func appendMissing(fields []string, required ...string) []string {
+	result := append([]string(nil), fields...)
+	for _, name := range required {
+		found := false
+		for _, field := range fields {
+			if field == name || field == "*all" || field == "*navigable" {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, name)
+		}
+	}
+	return result
+}
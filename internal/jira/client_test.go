@@ -0,0 +1,320 @@
+package jira_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/jira"
+	"my-scheduler-go/internal/jira/jiratest"
+)
+
+// newFakeJira 启动需要Basic认证的模拟服务器，返回服务器和使用正确凭据的客户端
+func newFakeJira(t *testing.T) (*jiratest.Server, *jira.Client) {
+	t.Helper()
+	server, err := jiratest.NewServer("jira_user", "secret", "")
+	if err != nil {
+		t.Fatalf("start fake jira: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, jira.NewClient(server.URL, "jira_user", "secret", "")
+}
+
+// searchRequests 返回服务器收到的搜索请求
+func searchRequests(server *jiratest.Server) []jiratest.RecordedRequest {
+	var result []jiratest.RecordedRequest
+	for _, request := range server.Requests() {
+		if request.Path == "/rest/api/2/search" {
+			result = append(result, request)
+		}
+	}
+	return result
+}
+
+// 服务器把maxResults限制在PageSize以下时，按实际返回的数量翻页直到total
+func TestSearchFollowsServerCappedPages(t *testing.T) {
+	server, client := newFakeJira(t)
+	server.PageLimit = 7
+	client.PageSize = 20
+	for i := 1; i <= 23; i++ {
+		server.AddIssue(jira.Issue{Key: fmt.Sprintf("PAGE-%d", i), Fields: jira.IssueFields{Summary: fmt.Sprintf("issue %d", i)}})
+	}
+	server.AddIssue(jira.Issue{Key: "OTHER-1"})
+
+	issues, err := client.Search(`project = "PAGE" ORDER BY key ASC`, "summary")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(issues) != 23 {
+		t.Fatalf("got %d issues, want 23", len(issues))
+	}
+	for i, issue := range issues {
+		if want := fmt.Sprintf("PAGE-%d", i+1); issue.Key != want {
+			t.Fatalf("issue %d = %s, want %s (duplicated or skipped page?)", i, issue.Key, want)
+		}
+	}
+
+	requests := searchRequests(server)
+	if len(requests) != 4 {
+		t.Fatalf("sent %d search requests, want 4 pages of at most 7", len(requests))
+	}
+	for i, request := range requests {
+		query, _ := url.ParseQuery(request.Query)
+		if got, want := query.Get("startAt"), fmt.Sprint(i*7); got != want {
+			t.Errorf("page %d startAt = %s, want %s", i, got, want)
+		}
+		if got := query.Get("maxResults"); got != "20" {
+			t.Errorf("page %d maxResults = %s, want the client page size 20", i, got)
+		}
+	}
+}
+
+// treeKeys 按深度优先顺序返回树中的问题，格式为 key(relation@depth)
+func treeKeys(root *jira.IssueNode) []string {
+	var keys []string
+	root.Walk(func(node *jira.IssueNode) {
+		keys = append(keys, fmt.Sprintf("%s(%s@%d)", node.Issue.Key, node.Relation, node.Depth))
+	})
+	return keys
+}
+
+// 链接形成环时每个问题只出现一次，MaxDepth限制获取的层数
+func TestGetIssueTreeHandlesCyclesAndMaxDepth(t *testing.T) {
+	server, client := newFakeJira(t)
+	server.AddIssue(jira.Issue{Key: "CYC-1"})
+	server.AddIssue(jira.Issue{Key: "CYC-2", Fields: jira.IssueFields{Parent: &jira.Issue{Key: "CYC-1"}}})
+	server.AddIssue(jira.Issue{Key: "CYC-3"})
+	server.AddIssue(jira.Issue{Key: "CYC-4"})
+	for _, l := range [][3]string{
+		{"CYC-1", "CYC-3", "Blocks"},
+		{"CYC-3", "CYC-4", "Blocks"},
+		{"CYC-4", "CYC-1", "Blocks"}, // 环: 1 -> 3 -> 4 -> 1
+		{"CYC-2", "CYC-3", "Relates"},
+	} {
+		if err := server.LinkIssues(l[0], l[1], l[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := client.GetIssueTree("CYC-1", jira.TreeOptions{})
+	if err != nil {
+		t.Fatalf("GetIssueTree: %v", err)
+	}
+	if got := tree.Count(); got != 4 {
+		t.Fatalf("tree has %d issues, want 4: %v", got, treeKeys(tree))
+	}
+	seen := map[string]int{}
+	tree.Walk(func(node *jira.IssueNode) { seen[node.Issue.Key]++ })
+	for key, count := range seen {
+		if count != 1 {
+			t.Errorf("%s appears %d times", key, count)
+		}
+	}
+	got := treeKeys(tree)
+	sort.Strings(got[1:])
+	want := []string{"CYC-1(@0)", "CYC-2(subtask@1)", "CYC-3(blocks@1)", "CYC-4(is blocked by@1)"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("tree = %v, want %v", got, want)
+	}
+
+	shallow, err := client.GetIssueTree("CYC-3", jira.TreeOptions{MaxDepth: 1, LinkTypes: []string{"Blocks"}})
+	if err != nil {
+		t.Fatalf("GetIssueTree with MaxDepth: %v", err)
+	}
+	for _, key := range treeKeys(shallow) {
+		if strings.HasSuffix(key, "@2)") {
+			t.Errorf("MaxDepth 1 returned %s", key)
+		}
+	}
+	if got := shallow.Count(); got != 3 {
+		t.Errorf("MaxDepth 1 tree = %v, want CYC-3 with CYC-1 and CYC-4", treeKeys(shallow))
+	}
+}
+
+// 链接的问题已删除或没有查看权限时，严格校验的key in (...)搜索会返回400；
+// 获取问题树时这些问题只被跳过，其余问题照常导出
+func TestGetIssueTreeSkipsInvisibleIssues(t *testing.T) {
+	server, client := newFakeJira(t)
+	server.AddIssue(jira.Issue{Key: "VIS-1"})
+	server.AddIssue(jira.Issue{Key: "VIS-2"})
+	server.AddIssue(jira.Issue{Key: "VIS-3"})
+	server.AddIssue(jira.Issue{Key: "VIS-4", Fields: jira.IssueFields{Parent: &jira.Issue{Key: "VIS-1"}}})
+	for _, inward := range []string{"VIS-2", "VIS-3"} {
+		if err := server.LinkIssues("VIS-1", inward, "Relates"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := server.HideIssue("VIS-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	var apiErr *jira.APIError
+	if _, err := client.Search("key in (VIS-2,VIS-3)"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("strict search for a hidden key error = %v, want 400", err)
+	}
+
+	tree, err := client.GetIssueTree("VIS-1", jira.TreeOptions{})
+	if err != nil {
+		t.Fatalf("GetIssueTree: %v", err)
+	}
+	got := treeKeys(tree)
+	sort.Strings(got[1:])
+	want := []string{"VIS-1(@0)", "VIS-2(relates to@1)", "VIS-4(subtask@1)"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("tree = %v, want %v", got, want)
+	}
+
+	// 第一个是上面的严格搜索
+	requests := searchRequests(server)
+	if len(requests) != 2 {
+		t.Fatalf("tree fetch sent %d searches, want 1", len(requests)-1)
+	}
+	query, _ := url.ParseQuery(requests[1].Query)
+	if query.Get("validateQuery") != "warn" || !strings.Contains(query.Get("jql"), "VIS-3") {
+		t.Errorf("tree search query = %v, want the hidden key searched with validateQuery=warn", query)
+	}
+}
+
+func TestTransitionIssueSelection(t *testing.T) {
+	server, client := newFakeJira(t)
+	server.AddIssue(jira.Issue{Key: "WF-1"})
+
+	steps := []struct {
+		transition string
+		wantStatus string
+	}{
+		{"21", "In Progress"}, // ID
+		{"done", "Done"},      // 名称(不区分大小写)
+		{"To Do", "To Do"},    // 名称与目标状态相同
+		{"in progress", "In Progress"},
+	}
+	for _, step := range steps {
+		if err := client.TransitionIssue("WF-1", step.transition, nil); err != nil {
+			t.Fatalf("TransitionIssue(%q): %v", step.transition, err)
+		}
+		issue, _ := server.Issue("WF-1")
+		if issue.Fields.Status.Name != step.wantStatus {
+			t.Errorf("after %q status = %s, want %s", step.transition, issue.Fields.Status.Name, step.wantStatus)
+		}
+	}
+
+	err := client.TransitionIssue("WF-1", "Reopen", nil)
+	if err == nil || !strings.Contains(err.Error(), `no transition "Reopen"`) || !strings.Contains(err.Error(), "available: To Do, Done") {
+		t.Errorf("unknown transition error = %v", err)
+	}
+	// 当前状态不在可用转换中
+	if err := client.TransitionIssue("WF-1", "In Progress", nil); err == nil {
+		t.Error("transition to the current status should fail")
+	}
+}
+
+// 目标状态与转换名称不同时按目标状态匹配
+func TestTransitionIssueByTargetStatus(t *testing.T) {
+	var posted atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"transitions":[{"id":"5","name":"Start work","to":{"name":"In Progress"}},{"id":"7","name":"Resolve","to":{"name":"Resolved"}}]}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		posted.Store(string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := jira.NewClient(server.URL, "", "", "")
+	if err := client.TransitionIssue("X-1", "resolved", nil); err != nil {
+		t.Fatalf("TransitionIssue: %v", err)
+	}
+	if body, _ := posted.Load().(string); !strings.Contains(body, `"id":"7"`) {
+		t.Errorf("posted %s, want transition 7", body)
+	}
+}
+
+// 429时按Retry-After等待后重试
+func TestDoJSONRetriesAfterRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	var first, second time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			second = time.Now()
+			fmt.Fprint(w, `{"key":"RL-1","fields":{"summary":"after retry"}}`)
+		}
+	}))
+	defer server.Close()
+
+	issue, err := jira.NewClient(server.URL, "", "", "").GetIssue("RL-1")
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if issue.Fields.Summary != "after retry" || calls.Load() != 2 {
+		t.Errorf("got %q after %d calls, want the retried response after 2 calls", issue.Fields.Summary, calls.Load())
+	}
+	if waited := second.Sub(first); waited < 900*time.Millisecond {
+		t.Errorf("retried after %s, want Retry-After of 1s", waited)
+	}
+}
+
+// 429超过重试次数后返回APIError
+func TestDoJSONGivesUpAfterRetries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for Retry-After")
+	}
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"errorMessages":["Rate limit exceeded"]}`)
+	}))
+	defer server.Close()
+
+	_, err := jira.NewClient(server.URL, "", "", "").GetIssue("RL-1")
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("err = %v, want a 429 APIError", err)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("sent %d requests, want 1 + 3 retries", calls.Load())
+	}
+}
+
+func TestAPIErrorDecoding(t *testing.T) {
+	server, client := newFakeJira(t)
+	server.AddIssue(jira.Issue{Key: "ERR-1"})
+
+	_, err := client.GetIssue("ERR-404")
+	if !jira.IsNotFound(err) || jira.IsUnauthorized(err) {
+		t.Errorf("missing issue: IsNotFound=%v IsUnauthorized=%v (%v)", jira.IsNotFound(err), jira.IsUnauthorized(err), err)
+	}
+	if err == nil || err.Error() != "jira api error 404: Issue Does Not Exist" {
+		t.Errorf("404 error = %v", err)
+	}
+
+	badAuth := jira.NewClient(server.URL, "jira_user", "wrong", "")
+	_, err = badAuth.GetIssue("ERR-1")
+	if !jira.IsUnauthorized(err) || jira.IsNotFound(err) {
+		t.Errorf("bad credentials: IsUnauthorized=%v IsNotFound=%v (%v)", jira.IsUnauthorized(err), jira.IsNotFound(err), err)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "jira api error 401: You are not authenticated") {
+		t.Errorf("401 error = %v", err)
+	}
+
+	// 字段错误按字段名排序
+	err = client.UpdateIssue("ERR-1", map[string]interface{}{"created": "2024-01-01", "nonexistent": 1})
+	if err == nil || !strings.HasPrefix(err.Error(), "jira api error 400:") {
+		t.Errorf("invalid update error = %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package jira
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// TimeLayout Jira REST API v2使用的时间格式
+const TimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// Time 以Jira格式(如2024-01-02T15:04:05.000+0800)编解码的时间
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON 解析Jira格式的时间，也接受RFC3339
+func (t *Time) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	parsed, err := time.Parse(TimeLayout, value)
+	if err != nil {
+		parsed, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// MarshalJSON 以Jira格式输出时间，零值输出null
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Format(TimeLayout))
+}
+
+// Issue 表示Jira问题
+type Issue struct {
+	ID     string      `json:"id,omitempty"`
+	Key    string      `json:"key"`
+	Self   string      `json:"self,omitempty"`
+	Fields IssueFields `json:"fields"`
+}
+
+// IssueFields 问题的字段
+//
+// 常用字段解析到对应的成员中，所有字段(包括自定义字段customfield_*)的原始值保存在Raw中。
+type IssueFields struct {
+	Summary     string      `json:"summary,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Status      *Status     `json:"status,omitempty"`
+	IssueType   *IssueType  `json:"issuetype,omitempty"`
+	Project     *Project    `json:"project,omitempty"`
+	Priority    *Priority   `json:"priority,omitempty"`
+	Assignee    *User       `json:"assignee,omitempty"`
+	Reporter    *User       `json:"reporter,omitempty"`
+	Labels      []string    `json:"labels,omitempty"`
+	Created     *Time       `json:"created,omitempty"`
+	Updated     *Time       `json:"updated,omitempty"`
+	DueDate     string      `json:"duedate,omitempty"` // YYYY-MM-DD
+	Parent      *Issue      `json:"parent,omitempty"`  // 子任务的父问题(只含key和少量字段)
+	Subtasks    []Issue     `json:"subtasks,omitempty"`
+	IssueLinks  []IssueLink `json:"issuelinks,omitempty"`
+
+	Raw map[string]interface{} `json:"-"`
+}
+
+// issueFieldsAlias 用于编解码IssueFields的已知字段，避免递归调用自定义的编解码方法
+type issueFieldsAlias IssueFields
+
+// UnmarshalJSON 解析已知字段，并保留所有字段的原始值
+func (f *IssueFields) UnmarshalJSON(data []byte) error {
+	var alias issueFieldsAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = IssueFields(alias)
+	f.Raw = raw
+	return nil
+}
+
+// MarshalJSON 输出Raw中的所有字段，已知字段以成员的值为准
+func (f IssueFields) MarshalJSON() ([]byte, error) {
+	known, err := json.Marshal(issueFieldsAlias(f))
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Raw) == 0 {
+		return known, nil
+	}
+
+	merged := make(map[string]interface{}, len(f.Raw))
+	for name, value := range f.Raw {
+		merged[name] = value
+	}
+	var knownFields map[string]interface{}
+	if err := json.Unmarshal(known, &knownFields); err != nil {
+		return nil, err
+	}
+	for name, value := range knownFields {
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
+
+// Field 返回字段的原始值，字段不存在时返回nil
+func (f *IssueFields) Field(name string) interface{} {
+	return f.Raw[name]
+}
+
+// Status 问题的状态
+type Status struct {
+	ID             string          `json:"id,omitempty"`
+	Name           string          `json:"name"`
+	StatusCategory *StatusCategory `json:"statusCategory,omitempty"`
+}
+
+// StatusCategory 状态分类(new、indeterminate、done)
+type StatusCategory struct {
+	Key  string `json:"key"`
+	Name string `json:"name,omitempty"`
+}
+
+// IssueType 问题类型
+type IssueType struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Subtask bool   `json:"subtask,omitempty"`
+}
+
+// Project 问题所属的项目
+type Project struct {
+	ID   string `json:"id,omitempty"`
+	Key  string `json:"key"`
+	Name string `json:"name,omitempty"`
+}
+
+// Priority 问题的优先级
+type Priority struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// User Jira用户
+type User struct {
+	Name         string `json:"name,omitempty"`
+	Key          string `json:"key,omitempty"`
+	DisplayName  string `json:"displayName,omitempty"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+}
+
+// IssueLink 问题之间的链接，InwardIssue和OutwardIssue中只有一个不为空(另一端是当前问题)
+type IssueLink struct {
+	ID           string        `json:"id,omitempty"`
+	Type         IssueLinkType `json:"type"`
+	InwardIssue  *Issue        `json:"inwardIssue,omitempty"`
+	OutwardIssue *Issue        `json:"outwardIssue,omitempty"`
+}
+
+// IssueLinkType 链接类型，如Blocks(outward: blocks, inward: is blocked by)
+type IssueLinkType struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Inward  string `json:"inward,omitempty"`
+	Outward string `json:"outward,omitempty"`
+}
+
+// Linked 返回链接另一端的问题和从当前问题看的关系描述(如blocks)
+func (l *IssueLink) Linked() (*Issue, string) {
+	if l.OutwardIssue != nil {
+		return l.OutwardIssue, l.Type.Outward
+	}
+	return l.InwardIssue, l.Type.Inward
+}
+
+// Transition 问题可执行的工作流转换
+type Transition struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	To   *Status `json:"to,omitempty"`
+}
+
+// 问题树中子节点与父节点的关系
+const (
+	RelationSubtask = "subtask"
+)
+
+// IssueNode 问题树的节点
+type IssueNode struct {
+	Issue    *Issue       `json:"issue"`
+	Relation string       `json:"relation,omitempty"` // subtask或链接关系(如blocks)，根节点为空
+	Depth    int          `json:"depth"`
+	Children []*IssueNode `json:"children,omitempty"`
+}
+
+// Walk 按深度优先顺序访问树中的所有节点(包括自身)
+func (n *IssueNode) Walk(visit func(node *IssueNode)) {
+	visit(n)
+	for _, child := range n.Children {
+		child.Walk(visit)
+	}
+}
+
+// Count 返回树中的问题数量
+func (n *IssueNode) Count() int {
+	count := 0
+	n.Walk(func(*IssueNode) { count++ })
+	return count
+}
+
+// TreeOptions 获取问题树的选项
+type TreeOptions struct {
+	// Fields 获取的字段，为空时获取所有可导航字段；subtasks和issuelinks总会获取
+	Fields []string
+	// MaxDepth 最大深度，根节点为0，<=0时不限制
+	MaxDepth int
+	// LinkTypes 跟随的链接类型名称(如Blocks)，为空时跟随所有链接；NoLinks为true时不跟随链接
+	LinkTypes []string
+	NoLinks   bool
+}
+
+// followsLink 判断是否跟随某种类型的链接
+func (o TreeOptions) followsLink(linkType IssueLinkType) bool {
+	if o.NoLinks {
+		return false
+	}
+	if len(o.LinkTypes) == 0 {
+		return true
+	}
+	for _, name := range o.LinkTypes {
+		if strings.EqualFold(name, linkType.Name) {
+			return true
+		}
+	}
+	return false
+}
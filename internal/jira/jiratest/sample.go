@@ -0,0 +1,64 @@
+package jiratest
+
+import (
+	"fmt"
+
+	"my-scheduler-go/internal/jira"
+)
+
+// SeedSampleProject 添加一个示例项目并返回根问题的key:
+// 一个Epic，包含3个Story(Epic-Story链接)，Story各有子任务，其中一个Story阻塞另一个，
+// 另外有tasks个独立的任务(多于一页时可用于验证搜索分页)
+func (s *Server) SeedSampleProject(project string, tasks int) string {
+	key := func(n int) string { return fmt.Sprintf("%s-%d", project, n) }
+	issueType := func(name string, subtask bool) *jira.IssueType {
+		return &jira.IssueType{Name: name, Subtask: subtask}
+	}
+	high := &jira.Priority{Name: "High"}
+	medium := &jira.Priority{Name: "Medium"}
+	alice := &jira.User{Name: "alice", DisplayName: "Alice"}
+	bob := &jira.User{Name: "bob", DisplayName: "Bob"}
+
+	root := key(1)
+	s.AddIssue(jira.Issue{Key: root, Fields: jira.IssueFields{
+		Summary: "Scheduler release", IssueType: issueType("Epic", false), Priority: high, Assignee: alice,
+	}})
+
+	n := 2
+	var stories []string
+	for i, assignee := range []*jira.User{alice, bob, alice} {
+		story := key(n)
+		n++
+		stories = append(stories, story)
+		s.AddIssue(jira.Issue{Key: story, Fields: jira.IssueFields{
+			Summary:   fmt.Sprintf("Story %d", i+1),
+			IssueType: issueType("Story", false),
+			Priority:  medium,
+			Assignee:  assignee,
+			Labels:    []string{"release"},
+			DueDate:   fmt.Sprintf("2026-12-%02d", 10*(i+1)),
+		}})
+		_ = s.LinkIssues(root, story, "Epic-Story")
+
+		for j := 1; j <= 2; j++ {
+			s.AddIssue(jira.Issue{Key: key(n), Fields: jira.IssueFields{
+				Summary:  fmt.Sprintf("Story %d sub-task %d", i+1, j),
+				Parent:   &jira.Issue{Key: story},
+				Assignee: assignee,
+			}})
+			n++
+		}
+	}
+	_ = s.LinkIssues(stories[0], stories[1], "Blocks")
+	_ = s.LinkIssues(stories[2], stories[0], "Relates")
+
+	for i := 1; i <= tasks; i++ {
+		s.AddIssue(jira.Issue{Key: key(n), Fields: jira.IssueFields{
+			Summary:  fmt.Sprintf("Maintenance task %d", i),
+			Priority: medium,
+		}})
+		n++
+	}
+
+	return root
+}
@@ -0,0 +1,636 @@
+// Package jiratest 提供进程内的模拟Jira服务器，
+// 用于开发模式和测试Jira客户端(JQL搜索分页、问题树、字段更新和工作流转换)
+package jiratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"my-scheduler-go/internal/jira"
+)
+
+// DefaultPageLimit 搜索时每页最多返回的问题数量，与Jira服务器的默认限制一样小于客户端请求的数量
+const DefaultPageLimit = 50
+
+// RecordedRequest 记录服务器收到的一个REST请求
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+	Time   time.Time
+}
+
+// 链接类型
+var linkTypes = map[string]jira.IssueLinkType{
+	"Blocks":     {ID: "10000", Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+	"Relates":    {ID: "10001", Name: "Relates", Inward: "relates to", Outward: "relates to"},
+	"Duplicate":  {ID: "10002", Name: "Duplicate", Inward: "is duplicated by", Outward: "duplicates"},
+	"Epic-Story": {ID: "10003", Name: "Epic-Story", Inward: "belongs to epic", Outward: "contains"},
+}
+
+// 工作流: 任何状态都可以转换到其他状态
+var workflow = []jira.Transition{
+	{ID: "11", Name: "To Do", To: &jira.Status{ID: "1", Name: "To Do", StatusCategory: &jira.StatusCategory{Key: "new", Name: "To Do"}}},
+	{ID: "21", Name: "In Progress", To: &jira.Status{ID: "3", Name: "In Progress", StatusCategory: &jira.StatusCategory{Key: "indeterminate", Name: "In Progress"}}},
+	{ID: "31", Name: "Done", To: &jira.Status{ID: "10001", Name: "Done", StatusCategory: &jira.StatusCategory{Key: "done", Name: "Done"}}},
+}
+
+// link 两个问题之间的链接
+type link struct {
+	id       string
+	linkType jira.IssueLinkType
+	outward  string // 发起方，如A blocks B中的A
+	inward   string
+}
+
+// Server 是模拟的Jira服务器，问题保存在内存中
+//
+// 设置了用户名或令牌时校验认证信息，否则接受任何请求。
+// 搜索只支持用AND连接的field = value和field in (...)条件(project、key、parent、issuetype、status)，
+// ORDER BY被忽略，结果按key排序。与Jira一样，key条件引用不存在的问题时返回400，
+// 除非请求带有validateQuery=warn(此时以warningMessages返回)。
+type Server struct {
+	URL string
+	// PageLimit 搜索时每页最多返回的问题数量
+	PageLimit int
+
+	username string
+	password string
+	token    string
+
+	mu       sync.Mutex
+	issues   map[string]*jira.Issue
+	hidden   map[string]bool
+	links    []link
+	nextID   int
+	requests []RecordedRequest
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer 在本地随机端口上启动模拟服务器
+func NewServer(username, password, token string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:       "http://" + listener.Addr().String(),
+		PageLimit: DefaultPageLimit,
+		username:  username,
+		password:  password,
+		token:     token,
+		issues:    make(map[string]*jira.Issue),
+		hidden:    make(map[string]bool),
+		nextID:    10000,
+		listener:  listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rest/api/2/issue/{key}", s.handleGetIssue)
+	mux.HandleFunc("PUT /rest/api/2/issue/{key}", s.handleUpdateIssue)
+	mux.HandleFunc("GET /rest/api/2/issue/{key}/transitions", s.handleGetTransitions)
+	mux.HandleFunc("POST /rest/api/2/issue/{key}/transitions", s.handleTransition)
+	mux.HandleFunc("GET /rest/api/2/search", s.handleSearch)
+	mux.HandleFunc("GET /rest/api/2/myself", s.handleMyself)
+	// serverInfo不要求认证
+	root := http.NewServeMux()
+	root.HandleFunc("GET /rest/api/2/serverInfo", s.handleServerInfo)
+	root.Handle("/", s.authenticate(mux))
+	s.server = &http.Server{Handler: root}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("[FakeJira] Server error: %v", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// Close 关闭服务器
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// AddIssue 添加或替换问题
+//
+// issue.Key必须形如PROJ-1；未设置的项目、问题类型、状态和时间使用默认值，
+// Fields.Parent只需设置Key，子任务列表和链接由服务器生成。
+func (s *Server) AddIssue(issue jira.Issue) *jira.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := issue
+	s.nextID++
+	stored.ID = strconv.Itoa(s.nextID)
+	stored.Self = s.URL + "/rest/api/2/issue/" + stored.ID
+
+	fields := &stored.Fields
+	if fields.Project == nil {
+		project, _, _ := strings.Cut(stored.Key, "-")
+		fields.Project = &jira.Project{Key: project, Name: project}
+	}
+	if fields.IssueType == nil {
+		fields.IssueType = &jira.IssueType{Name: "Task"}
+		if fields.Parent != nil {
+			fields.IssueType = &jira.IssueType{Name: "Sub-task", Subtask: true}
+		}
+	}
+	if fields.Status == nil {
+		fields.Status = workflow[0].To
+	}
+	now := &jira.Time{Time: time.Now().Truncate(time.Millisecond)}
+	if fields.Created == nil {
+		fields.Created = now
+	}
+	if fields.Updated == nil {
+		fields.Updated = now
+	}
+	fields.Subtasks = nil
+	fields.IssueLinks = nil
+
+	s.issues[stored.Key] = &stored
+	return s.render(&stored)
+}
+
+// LinkIssues 创建链接，linkType为Blocks、Relates、Duplicate或Epic-Story，
+// outward为发起方(如A blocks B中的A)
+func (s *Server) LinkIssues(outward, inward, linkType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lt, ok := linkTypes[linkType]
+	if !ok {
+		return fmt.Errorf("unknown link type %s", linkType)
+	}
+	for _, key := range []string{outward, inward} {
+		if _, ok := s.issues[key]; !ok {
+			return fmt.Errorf("issue %s not found", key)
+		}
+	}
+	s.nextID++
+	s.links = append(s.links, link{id: strconv.Itoa(s.nextID), linkType: lt, outward: outward, inward: inward})
+	return nil
+}
+
+// HideIssue 模拟当前用户没有查看权限的问题: 它仍出现在其他问题的子任务和链接中，
+// 但获取时返回404，搜索时视为不存在的key
+func (s *Server) HideIssue(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.issues[key]; !ok {
+		return fmt.Errorf("issue %s not found", key)
+	}
+	s.hidden[key] = true
+	return nil
+}
+
+// Issue 返回问题的当前内容(包括子任务和链接)
+func (s *Server) Issue(key string) (*jira.Issue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[key]
+	if !ok {
+		return nil, false
+	}
+	return s.render(issue), true
+}
+
+// Requests 返回收到的所有REST请求
+func (s *Server) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+// authenticate 校验Basic认证或Bearer令牌，并记录请求
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.username != "" || s.token != "" {
+			username, password, basic := r.BasicAuth()
+			bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			validBasic := basic && s.username != "" && username == s.username && password == s.password
+			validToken := s.token != "" && bearer == s.token
+			if !validBasic && !validToken {
+				writeError(w, http.StatusUnauthorized, "You are not authenticated. Authentication required to perform this operation.")
+				return
+			}
+		}
+
+		body, _ := readAll(r)
+		s.mu.Lock()
+		s.requests = append(s.requests, RecordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Body:   body,
+			Time:   time.Now(),
+		})
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleServerInfo 处理GET /rest/api/2/serverInfo
+func (s *Server) handleServerInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jira.ServerInfo{BaseURL: s.URL, Version: "9.12.0", ServerTitle: "Fake Jira"})
+}
+
+// handleMyself 处理GET /rest/api/2/myself，返回认证的用户
+func (s *Server) handleMyself(w http.ResponseWriter, r *http.Request) {
+	username, _, ok := r.BasicAuth()
+	if !ok {
+		username = "token-user"
+		if s.username == "" && s.token == "" {
+			username = "anonymous"
+		}
+	}
+	writeJSON(w, http.StatusOK, jira.User{Name: username, Key: username, DisplayName: username})
+}
+
+// handleGetIssue 处理GET /rest/api/2/issue/{key}
+func (s *Server) handleGetIssue(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	hidden := s.hidden[r.PathValue("key")]
+	s.mu.Unlock()
+	issue, ok := s.Issue(r.PathValue("key"))
+	if !ok || hidden {
+		writeError(w, http.StatusNotFound, "Issue Does Not Exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, selectFields(issue, r.URL.Query().Get("fields")))
+}
+
+// handleUpdateIssue 处理PUT /rest/api/2/issue/{key}，更新fields中的字段
+func (s *Server) handleUpdateIssue(w http.ResponseWriter, r *http.Request) {
+	var update struct {
+		Fields map[string]interface{} `json:"fields"`
+	}
+	body, err := readAll(r)
+	if err == nil {
+		err = json.Unmarshal(body, &update)
+	}
+	if err != nil || len(update.Fields) == 0 {
+		writeError(w, http.StatusBadRequest, "fields are required")
+		return
+	}
+
+	for _, readOnly := range []string{"status", "project", "created", "updated", "subtasks", "issuelinks"} {
+		if _, ok := update.Fields[readOnly]; ok {
+			writeFieldError(w, readOnly, fmt.Sprintf("Field '%s' cannot be set. It is not on the appropriate screen, or unknown.", readOnly))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[r.PathValue("key")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue Does Not Exist")
+		return
+	}
+
+	// 在当前字段上覆盖更新的字段后重新解析
+	data, _ := json.Marshal(issue.Fields)
+	var merged map[string]interface{}
+	_ = json.Unmarshal(data, &merged)
+	for name, value := range update.Fields {
+		merged[name] = value
+	}
+	data, _ = json.Marshal(merged)
+	var fields jira.IssueFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fields.Updated = &jira.Time{Time: time.Now().Truncate(time.Millisecond)}
+	issue.Fields = fields
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetTransitions 处理GET /rest/api/2/issue/{key}/transitions
+func (s *Server) handleGetTransitions(w http.ResponseWriter, r *http.Request) {
+	issue, ok := s.Issue(r.PathValue("key"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue Does Not Exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transitions": availableTransitions(issue)})
+}
+
+// handleTransition 处理POST /rest/api/2/issue/{key}/transitions
+func (s *Server) handleTransition(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Transition struct {
+			ID string `json:"id"`
+		} `json:"transition"`
+	}
+	body, err := readAll(r)
+	if err == nil {
+		err = json.Unmarshal(body, &request)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[r.PathValue("key")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue Does Not Exist")
+		return
+	}
+	for _, t := range availableTransitions(issue) {
+		if t.ID == request.Transition.ID {
+			issue.Fields.Status = t.To
+			issue.Fields.Updated = &jira.Time{Time: time.Now().Truncate(time.Millisecond)}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusBadRequest, "It seems that you have tried to perform a workflow operation ("+request.Transition.ID+") that is not valid for the current state of this issue")
+}
+
+// handleSearch 处理GET /rest/api/2/search
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	conditions, err := parseJQL(query.Get("jql"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	startAt, _ := strconv.Atoi(query.Get("startAt"))
+	maxResults, err := strconv.Atoi(query.Get("maxResults"))
+	if err != nil || maxResults <= 0 || maxResults > s.PageLimit {
+		maxResults = s.PageLimit
+	}
+
+	s.mu.Lock()
+	// 与Jira一样，默认的严格校验下引用不存在的key返回400，validateQuery=warn时只返回警告
+	var warnings []string
+	for _, key := range conditions["key"] {
+		if _, ok := s.issues[strings.ToUpper(key)]; ok && !s.hidden[strings.ToUpper(key)] {
+			continue
+		}
+		message := fmt.Sprintf("An issue with key '%s' does not exist for field 'key'.", strings.ToUpper(key))
+		switch query.Get("validateQuery") {
+		case "warn", "none", "false":
+			warnings = append(warnings, message)
+		default:
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, message)
+			return
+		}
+	}
+
+	var matched []*jira.Issue
+	for _, issue := range s.issues {
+		if !s.hidden[issue.Key] && conditions.match(issue) {
+			matched = append(matched, issue)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return keyLess(matched[i].Key, matched[j].Key) })
+
+	page := make([]interface{}, 0, maxResults)
+	for i := startAt; i < len(matched) && len(page) < maxResults; i++ {
+		page = append(page, selectFields(s.render(matched[i]), query.Get("fields")))
+	}
+	s.mu.Unlock()
+
+	result := map[string]interface{}{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(matched),
+		"issues":     page,
+	}
+	if len(warnings) > 0 {
+		result["warningMessages"] = warnings
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// render 复制问题并生成子任务、父问题和链接，调用方需持有锁
+func (s *Server) render(issue *jira.Issue) *jira.Issue {
+	result := *issue
+	fields := &result.Fields
+
+	if fields.Parent != nil {
+		if parent, ok := s.issues[fields.Parent.Key]; ok {
+			fields.Parent = brief(parent)
+		}
+	}
+
+	fields.Subtasks = nil
+	for _, other := range s.issues {
+		if other.Fields.Parent != nil && other.Fields.Parent.Key == issue.Key {
+			fields.Subtasks = append(fields.Subtasks, *brief(other))
+		}
+	}
+	sort.Slice(fields.Subtasks, func(i, j int) bool { return keyLess(fields.Subtasks[i].Key, fields.Subtasks[j].Key) })
+
+	fields.IssueLinks = nil
+	for _, l := range s.links {
+		switch issue.Key {
+		case l.outward:
+			if other, ok := s.issues[l.inward]; ok {
+				fields.IssueLinks = append(fields.IssueLinks, jira.IssueLink{ID: l.id, Type: l.linkType, OutwardIssue: brief(other)})
+			}
+		case l.inward:
+			if other, ok := s.issues[l.outward]; ok {
+				fields.IssueLinks = append(fields.IssueLinks, jira.IssueLink{ID: l.id, Type: l.linkType, InwardIssue: brief(other)})
+			}
+		}
+	}
+
+	// 通过JSON复制，使返回值与服务器中的数据互不影响，并生成包含所有字段的Raw
+	data, _ := json.Marshal(result)
+	var copied jira.Issue
+	_ = json.Unmarshal(data, &copied)
+	return &copied
+}
+
+// brief 返回只含摘要、状态和类型的问题，用于子任务、父问题和链接
+func brief(issue *jira.Issue) *jira.Issue {
+	return &jira.Issue{
+		ID:   issue.ID,
+		Key:  issue.Key,
+		Self: issue.Self,
+		Fields: jira.IssueFields{
+			Summary:   issue.Fields.Summary,
+			Status:    issue.Fields.Status,
+			IssueType: issue.Fields.IssueType,
+			Priority:  issue.Fields.Priority,
+		},
+	}
+}
+
+// availableTransitions 返回问题可执行的转换(转换到当前状态以外的状态)
+func availableTransitions(issue *jira.Issue) []jira.Transition {
+	var transitions []jira.Transition
+	for _, t := range workflow {
+		if issue.Fields.Status == nil || !strings.EqualFold(issue.Fields.Status.Name, t.To.Name) {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions
+}
+
+// selectFields 按fields参数(逗号分隔)筛选问题的字段，为空或*all、*navigable时返回所有字段
+func selectFields(issue *jira.Issue, fields string) interface{} {
+	if fields == "" || strings.Contains(fields, "*all") || strings.Contains(fields, "*navigable") {
+		return issue
+	}
+
+	selected := make(map[string]interface{})
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if value, ok := issue.Fields.Raw[name]; ok {
+			selected[name] = value
+		}
+	}
+	return map[string]interface{}{
+		"id":     issue.ID,
+		"key":    issue.Key,
+		"self":   issue.Self,
+		"fields": selected,
+	}
+}
+
+// jqlClause 匹配: field = value 或 field in (v1, v2)
+var jqlClause = regexp.MustCompile(`(?i)^\s*(\w+)\s*(=|in)\s*(.+?)\s*$`)
+
+// jqlConditions 用AND连接的条件，字段名到允许的值
+type jqlConditions map[string][]string
+
+// parseJQL 解析支持的JQL子集
+func parseJQL(jql string) (jqlConditions, error) {
+	if idx := strings.Index(strings.ToLower(jql), "order by"); idx >= 0 {
+		jql = jql[:idx]
+	}
+	conditions := make(jqlConditions)
+	if strings.TrimSpace(jql) == "" {
+		return conditions, nil
+	}
+
+	for _, clause := range regexp.MustCompile(`(?i)\s+and\s+`).Split(jql, -1) {
+		m := jqlClause.FindStringSubmatch(clause)
+		if m == nil {
+			return nil, fmt.Errorf("Error in the JQL Query: unsupported clause %q", strings.TrimSpace(clause))
+		}
+		field := strings.ToLower(m[1])
+		if field == "issuekey" {
+			field = "key"
+		}
+		switch field {
+		case "project", "key", "parent", "issuetype", "status":
+		default:
+			return nil, fmt.Errorf("Field '%s' is not supported", m[1])
+		}
+
+		value := m[3]
+		if strings.EqualFold(m[2], "in") {
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "("), ")")
+		}
+		for _, v := range strings.Split(value, ",") {
+			v = strings.Trim(strings.TrimSpace(v), `"'`)
+			conditions[field] = append(conditions[field], strings.ToLower(v))
+		}
+	}
+	return conditions, nil
+}
+
+// match 判断问题是否满足所有条件
+func (c jqlConditions) match(issue *jira.Issue) bool {
+	for field, values := range c {
+		var actual string
+		switch field {
+		case "project":
+			if issue.Fields.Project != nil {
+				actual = issue.Fields.Project.Key
+			}
+		case "key":
+			actual = issue.Key
+		case "parent":
+			if issue.Fields.Parent != nil {
+				actual = issue.Fields.Parent.Key
+			}
+		case "issuetype":
+			if issue.Fields.IssueType != nil {
+				actual = issue.Fields.IssueType.Name
+			}
+		case "status":
+			if issue.Fields.Status != nil {
+				actual = issue.Fields.Status.Name
+			}
+		}
+
+		found := false
+		for _, value := range values {
+			if strings.EqualFold(actual, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// keyLess 按项目和编号比较问题key(PROJ-2排在PROJ-10之前)
+func keyLess(a, b string) bool {
+	projectA, numberA, _ := strings.Cut(a, "-")
+	projectB, numberB, _ := strings.Cut(b, "-")
+	if projectA != projectB {
+		return projectA < projectB
+	}
+	na, _ := strconv.Atoi(numberA)
+	nb, _ := strconv.Atoi(numberB)
+	return na < nb
+}
+
+// readAll 读取请求体，并允许后续处理器再次读取
+func readAll(r *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// writeJSON 写入JSON响应
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError 以Jira的错误格式写入响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, jira.APIError{ErrorMessages: []string{message}, Errors: map[string]string{}})
+}
+
+// writeFieldError 写入字段校验错误
+func writeFieldError(w http.ResponseWriter, field, message string) {
+	writeJSON(w, http.StatusBadRequest, jira.APIError{ErrorMessages: []string{}, Errors: map[string]string{field: message}})
+}
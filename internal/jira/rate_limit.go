@@ -0,0 +1,58 @@
+package jira
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitRetries 服务器返回429时的最大重试次数
+const maxRateLimitRetries = 3
+
+// maxRetryAfter 429响应的Retry-After超过该时长时按该时长等待
+const maxRetryAfter = 60 * time.Second
+
+// rateLimiter 限制发往服务器的请求速率: 相邻两个请求之间至少间隔interval
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter 创建每秒最多perSecond个请求的限流器，perSecond<=0时返回nil(不限流)
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait 阻塞到允许发送下一个请求
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// retryAfter 返回429响应要求等待的时长，Retry-After缺失或无效时为1秒
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return time.Second
+	}
+	return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+}
@@ -0,0 +1,263 @@
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APIError 表示Mattermost REST API返回的错误
+type APIError struct {
+	StatusCode int    `json:"status_code"`
+	ID         string `json:"id"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mattermost api error %d (%s): %s", e.StatusCode, e.ID, e.Message)
+}
+
+// FileInfo 表示Mattermost文件元数据
+type FileInfo struct {
+	ID        string `json:"id"`
+	PostID    string `json:"post_id"`
+	ChannelID string `json:"channel_id"`
+	Name      string `json:"name"`
+	Extension string `json:"extension"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type"`
+}
+
+// Client 是Mattermost REST API v4的客户端
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient 创建REST客户端，serverURL可以是ws(s)://或http(s)://地址
+func NewClient(serverURL, token string) *Client {
+	return &Client{
+		BaseURL:    RESTBaseURL(serverURL),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// RESTBaseURL 将服务器地址转换为REST API的基础地址
+func RESTBaseURL(serverURL string) string {
+	base := strings.TrimRight(serverURL, "/")
+	switch {
+	case strings.HasPrefix(base, "wss://"):
+		base = "https://" + strings.TrimPrefix(base, "wss://")
+	case strings.HasPrefix(base, "ws://"):
+		base = "http://" + strings.TrimPrefix(base, "ws://")
+	}
+	return strings.TrimSuffix(base, "/api/v4/websocket")
+}
+
+// GetMe 获取当前令牌对应的用户
+func (c *Client) GetMe() (*User, error) {
+	var user User
+	if err := c.doJSON(http.MethodGet, "/users/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreatePost 创建消息
+func (c *Client) CreatePost(post *Post) (*Post, error) {
+	var created Post
+	if err := c.doJSON(http.MethodPost, "/posts", post, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// PatchPost 修改消息内容
+func (c *Client) PatchPost(postID, message string) (*Post, error) {
+	var patched Post
+	patch := map[string]string{"message": message}
+	if err := c.doJSON(http.MethodPut, "/posts/"+url.PathEscape(postID)+"/patch", patch, &patched); err != nil {
+		return nil, err
+	}
+	return &patched, nil
+}
+
+// DeletePost 删除消息
+func (c *Client) DeletePost(postID string) error {
+	return c.doJSON(http.MethodDelete, "/posts/"+url.PathEscape(postID), nil, nil)
+}
+
+// GetChannel 获取频道信息
+func (c *Client) GetChannel(channelID string) (*Channel, error) {
+	var channel Channel
+	if err := c.doJSON(http.MethodGet, "/channels/"+url.PathEscape(channelID), nil, &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// GetPostsSince 获取频道中在since(毫秒时间戳)之后创建或修改的消息，按创建时间升序返回
+func (c *Client) GetPostsSince(channelID string, since int64) ([]*Post, error) {
+	var list struct {
+		Order []string         `json:"order"`
+		Posts map[string]*Post `json:"posts"`
+	}
+	path := fmt.Sprintf("/channels/%s/posts?since=%d", url.PathEscape(channelID), since)
+	if err := c.doJSON(http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+
+	posts := make([]*Post, 0, len(list.Posts))
+	for _, post := range list.Posts {
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+
+	return posts, nil
+}
+
+// CreateDirectChannel 创建或获取两个用户之间的私聊频道
+func (c *Client) CreateDirectChannel(userID, otherUserID string) (*Channel, error) {
+	var channel Channel
+	if err := c.doJSON(http.MethodPost, "/channels/direct", []string{userID, otherUserID}, &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// GetFileInfo 获取文件元数据
+func (c *Client) GetFileInfo(fileID string) (*FileInfo, error) {
+	var info FileInfo
+	if err := c.doJSON(http.MethodGet, "/files/"+url.PathEscape(fileID)+"/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetFile 下载文件内容，超过maxBytes时返回错误(maxBytes<=0表示不限制)
+func (c *Client) GetFile(fileID string, maxBytes int64) ([]byte, error) {
+	resp, err := c.do(http.MethodGet, "/files/"+url.PathEscape(fileID), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reader := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, maxBytes+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("file %s exceeds size limit of %d bytes", fileID, maxBytes)
+	}
+
+	return data, nil
+}
+
+// UploadFile 上传文件到指定频道，返回新文件的元数据
+func (c *Client) UploadFile(channelID, filename string, data []byte) (*FileInfo, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("channel_id", channelID); err != nil {
+		return nil, err
+	}
+	part, err := writer.CreateFormFile("files", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(http.MethodPost, "/files", &body, writer.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		FileInfos []*FileInfo `json:"file_infos"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.FileInfos) == 0 {
+		return nil, fmt.Errorf("upload of %s returned no file info", filename)
+	}
+
+	return result.FileInfos[0], nil
+}
+
+// doJSON 发送JSON请求并解码JSON响应
+func (c *Client) doJSON(method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	contentType := ""
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.do(method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do 发送请求，非2xx响应会被转换为APIError
+func (c *Client) do(method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.BaseURL+"/api/v4"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		apiErr.StatusCode = resp.StatusCode
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
@@ -1,9 +1,15 @@
 package mattermost
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // Connection 管理与Mattermost的连接
@@ -15,6 +21,10 @@ type Connection struct {
 	stopChan          chan struct{}
 	mu                sync.Mutex
 	eventHandlers     []EventHandler
+	connectHandlers   []func()
+	ws                *websocket.Conn
+	seq               int64
+	maintaining       bool
 }
 
 // EventHandler 事件处理器接口
@@ -22,6 +32,25 @@ type EventHandler interface {
 	HandleEvent(event *Event)
 }
 
+// EventHandlerFunc 将函数适配为EventHandler
+type EventHandlerFunc func(event *Event)
+
+// HandleEvent 实现EventHandler接口
+func (f EventHandlerFunc) HandleEvent(event *Event) {
+	f(event)
+}
+
+// WebSocketMessage 表示Mattermost WebSocket推送的消息
+type WebSocketMessage struct {
+	Event     string                 `json:"event,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Broadcast map[string]interface{} `json:"broadcast,omitempty"`
+	Seq       int64                  `json:"seq,omitempty"`
+	Action    string                 `json:"action,omitempty"`
+	Status    string                 `json:"status,omitempty"`
+	SeqReply  int64                  `json:"seq_reply,omitempty"`
+}
+
 // NewConnection 创建一个新的连接实例
 func NewConnection(serverURL, token string, reconnectInterval time.Duration) *Connection {
 	return &Connection{
@@ -44,13 +73,40 @@ func (c *Connection) Connect() error {
 		return nil
 	}
 
-	// 模拟连接过程
-	log.Printf("[MattermostConnection] Connecting to %s", c.ServerURL)
-	time.Sleep(500 * time.Millisecond) // 模拟连接延迟
+	wsURL := WebSocketURL(c.ServerURL)
+	log.Printf("[MattermostConnection] Connecting to %s", wsURL)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.Token)
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		c.startMaintainingLocked()
+		return fmt.Errorf("failed to connect to %s: %v", wsURL, err)
+	}
+
+	// 发送认证请求
+	c.seq++
+	challenge := WebSocketMessage{
+		Seq:    c.seq,
+		Action: "authentication_challenge",
+		Data:   map[string]interface{}{"token": c.Token},
+	}
+	if err := ws.WriteJSON(challenge); err != nil {
+		ws.Close()
+		c.startMaintainingLocked()
+		return fmt.Errorf("failed to authenticate: %v", err)
+	}
+
+	c.ws = ws
 	c.Connected = true
 
+	go c.readLoop(ws)
+
 	// 启动心跳和重连机制
-	go c.maintainConnection()
+	c.startMaintainingLocked()
+
+	// 通知连接建立(包括重连)，例如补齐断线期间错过的消息
+	go c.notifyConnected()
 
 	return nil
 }
@@ -62,24 +118,90 @@ func (c *Connection) AddEventHandler(handler EventHandler) {
 	c.eventHandlers = append(c.eventHandlers, handler)
 }
 
+// AddConnectHandler 添加连接建立(包括重连)后的回调
+func (c *Connection) AddConnectHandler(handler func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connectHandlers = append(c.connectHandlers, handler)
+}
+
+// notifyConnected 调用所有连接建立回调
+func (c *Connection) notifyConnected() {
+	c.mu.Lock()
+	handlers := make([]func(), len(c.connectHandlers))
+	copy(handlers, c.connectHandlers)
+	c.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+// startMaintainingLocked 启动重连协程(只启动一次)，调用方需持有锁
+func (c *Connection) startMaintainingLocked() {
+	if c.maintaining {
+		return
+	}
+	c.maintaining = true
+	go c.maintainConnection(c.stopChan)
+}
+
 // 维持连接的后台协程
-func (c *Connection) maintainConnection() {
-	ticker := time.NewTicker(c.ReconnectInterval * time.Second)
+func (c *Connection) maintainConnection(stopChan chan struct{}) {
+	interval := c.ReconnectInterval * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			if !c.Connected {
+			c.mu.Lock()
+			connected := c.Connected
+			c.mu.Unlock()
+
+			if !connected {
 				log.Println("[MattermostConnection] Connection lost, reconnecting...")
-				c.Connect()
+				if err := c.Connect(); err != nil {
+					log.Printf("[MattermostConnection] Reconnect failed: %v", err)
+				}
 			}
-		case <-c.stopChan:
+		case <-stopChan:
 			return
 		}
 	}
 }
 
+// readLoop 读取WebSocket消息并分发事件，连接断开时标记为未连接等待重连
+func (c *Connection) readLoop(ws *websocket.Conn) {
+	for {
+		var msg WebSocketMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			c.mu.Lock()
+			if c.ws == ws {
+				c.ws = nil
+				c.Connected = false
+				log.Printf("[MattermostConnection] Connection closed: %v", err)
+			}
+			c.mu.Unlock()
+			ws.Close()
+			return
+		}
+
+		// 对请求的响应(如认证结果)没有事件名
+		if msg.Event == "" {
+			if msg.Status != "" && msg.Status != "OK" {
+				log.Printf("[MattermostConnection] Request %d failed: %s", msg.SeqReply, msg.Status)
+			}
+			continue
+		}
+
+		c.DispatchEvent(EventFromWebSocket(&msg))
+	}
+}
+
 // DispatchEvent 分发事件到所有处理器
 func (c *Connection) DispatchEvent(event *Event) {
 	c.mu.Lock()
@@ -97,12 +219,100 @@ func (c *Connection) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.maintaining {
+		close(c.stopChan)
+		c.stopChan = make(chan struct{})
+		c.maintaining = false
+	}
+
 	if !c.Connected {
 		return nil
 	}
 
-	close(c.stopChan)
 	c.Connected = false
+	if c.ws != nil {
+		c.ws.Close()
+		c.ws = nil
+	}
 	log.Println("[MattermostConnection] Connection closed")
 	return nil
 }
+
+// WebSocketURL 将服务器地址转换为WebSocket API地址
+func WebSocketURL(serverURL string) string {
+	base := strings.TrimRight(serverURL, "/")
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		base = "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	if strings.HasSuffix(base, "/api/v4/websocket") {
+		return base
+	}
+	return base + "/api/v4/websocket"
+}
+
+// EventFromWebSocket 将WebSocket消息转换为事件
+//
+// Mattermost把频道和用户信息分散在data和broadcast中，这里统一整理为
+// NewEvent可以解析的channel/user结构。
+func EventFromWebSocket(msg *WebSocketMessage) *Event {
+	data := make(map[string]interface{}, len(msg.Data)+2)
+	for key, value := range msg.Data {
+		data[key] = value
+	}
+
+	channelID := stringField(msg.Broadcast, "channel_id")
+	if channelID == "" {
+		channelID = stringField(msg.Data, "channel_id")
+	}
+	if _, ok := data["channel_id"]; !ok && channelID != "" {
+		data["channel_id"] = channelID
+	}
+	if _, ok := data["team_id"]; !ok {
+		if teamID := stringField(msg.Broadcast, "team_id"); teamID != "" {
+			data["team_id"] = teamID
+		}
+	}
+
+	// posted事件中post是JSON字符串，频道ID以消息中的为准
+	var post map[string]interface{}
+	switch p := msg.Data["post"].(type) {
+	case string:
+		_ = json.Unmarshal([]byte(p), &post)
+	case map[string]interface{}:
+		post = p
+	}
+	if post != nil {
+		data["post"] = post
+		if id := stringField(post, "channel_id"); id != "" {
+			channelID = id
+		}
+	}
+
+	if _, ok := data["channel"]; !ok && channelID != "" {
+		data["channel"] = map[string]interface{}{
+			"id":           channelID,
+			"name":         stringField(msg.Data, "channel_name"),
+			"display_name": stringField(msg.Data, "channel_display_name"),
+			"type":         stringField(msg.Data, "channel_type"),
+			"team_id":      stringField(data, "team_id"),
+		}
+	}
+
+	if _, ok := data["user"]; !ok {
+		userID := stringField(post, "user_id")
+		if userID == "" {
+			userID = stringField(msg.Data, "user_id")
+		}
+		if userID != "" {
+			data["user"] = map[string]interface{}{
+				"id":       userID,
+				"username": strings.TrimPrefix(stringField(msg.Data, "sender_name"), "@"),
+			}
+		}
+	}
+
+	return NewEvent(EventType(msg.Event), data)
+}
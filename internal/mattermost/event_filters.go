@@ -0,0 +1,255 @@
+package mattermost
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AllFilter 所有子过滤器都通过时才处理事件(AND)，没有子过滤器时处理所有事件
+type AllFilter struct {
+	Filters []EventFilter
+}
+
+// ShouldProcess 判断事件是否通过所有子过滤器
+func (f *AllFilter) ShouldProcess(event *Event) bool {
+	for _, filter := range f.Filters {
+		if !filter.ShouldProcess(event) {
+			return false
+		}
+	}
+	return true
+}
+
+// AnyFilter 任一子过滤器通过即处理事件(OR)，没有子过滤器时不处理任何事件
+type AnyFilter struct {
+	Filters []EventFilter
+}
+
+// ShouldProcess 判断事件是否通过任一子过滤器
+func (f *AnyFilter) ShouldProcess(event *Event) bool {
+	for _, filter := range f.Filters {
+		if filter.ShouldProcess(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotFilter 对子过滤器的结果取反(NOT)
+type NotFilter struct {
+	Filter EventFilter
+}
+
+// ShouldProcess 判断事件是否未通过子过滤器
+func (f *NotFilter) ShouldProcess(event *Event) bool {
+	return !f.Filter.ShouldProcess(event)
+}
+
+// UserFilter 基于发送者的过滤器，用户可以是ID或用户名
+type UserFilter struct {
+	Users []string
+}
+
+// ShouldProcess 判断事件是否由关注的用户触发
+func (f *UserFilter) ShouldProcess(event *Event) bool {
+	var userID, username string
+	if event.User != nil {
+		userID, username = event.User.ID, event.User.Username
+	}
+	if userID == "" && event.Post != nil {
+		userID = event.Post.UserID
+	}
+
+	for _, user := range f.Users {
+		if user == "" {
+			continue
+		}
+		if user == userID || strings.EqualFold(strings.TrimPrefix(user, "@"), username) {
+			return true
+		}
+	}
+	return false
+}
+
+// TeamFilter 基于团队ID的过滤器
+type TeamFilter struct {
+	TeamIDs []string
+}
+
+// ShouldProcess 判断事件是否来自关注的团队
+func (f *TeamFilter) ShouldProcess(event *Event) bool {
+	teamID := stringField(event.Data, "team_id")
+	if event.Channel != nil && event.Channel.TeamID != "" {
+		teamID = event.Channel.TeamID
+	}
+	if teamID == "" {
+		return false
+	}
+
+	for _, id := range f.TeamIDs {
+		if id == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelTypeFilter 基于频道类型的过滤器: O公开频道，P私有频道，D私聊，G群聊
+type ChannelTypeFilter struct {
+	Types []string
+}
+
+// ShouldProcess 判断事件所在频道的类型是否需要处理
+func (f *ChannelTypeFilter) ShouldProcess(event *Event) bool {
+	if event.Channel == nil || event.Channel.Type == "" {
+		return false
+	}
+
+	for _, channelType := range f.Types {
+		if strings.EqualFold(channelType, event.Channel.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegexFilter 基于消息内容正则表达式的过滤器，没有消息的事件不通过
+type RegexFilter struct {
+	Pattern *regexp.Regexp
+}
+
+// NewRegexFilter 编译正则表达式并创建过滤器
+func NewRegexFilter(pattern string) (*RegexFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexFilter{Pattern: re}, nil
+}
+
+// ShouldProcess 判断消息内容是否匹配正则表达式
+func (f *RegexFilter) ShouldProcess(event *Event) bool {
+	return event.Post != nil && f.Pattern.MatchString(event.Post.Message)
+}
+
+// BotPostFilter 排除机器人、Webhook和本系统发送的消息，其他事件都通过
+type BotPostFilter struct{}
+
+// ShouldProcess 判断事件是否不是机器人发送的消息
+func (f *BotPostFilter) ShouldProcess(event *Event) bool {
+	if event.Post == nil {
+		return true
+	}
+	return !event.Post.IsFromScheduler() && !propIsTrue(event.Post.Props, "from_bot") && !propIsTrue(event.Post.Props, "from_webhook")
+}
+
+// propIsTrue 判断消息属性是否为真(Mattermost中这类属性通常是字符串"true")
+func propIsTrue(props map[string]interface{}, key string) bool {
+	switch value := props[key].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
+
+// WatchFilter 监听的频道和团队，可以在运行时更新: 事件来自监听的频道或监听的团队中的任一频道时通过
+type WatchFilter struct {
+	mu       sync.RWMutex
+	channels map[string]bool
+	teams    map[string]bool
+}
+
+// NewWatchFilter 创建监听指定频道和团队的过滤器
+func NewWatchFilter(channelIDs, teamIDs []string) *WatchFilter {
+	f := &WatchFilter{}
+	f.Update(channelIDs, teamIDs)
+	return f
+}
+
+// Update 替换监听的频道和团队
+func (f *WatchFilter) Update(channelIDs, teamIDs []string) {
+	channels := make(map[string]bool, len(channelIDs))
+	for _, id := range channelIDs {
+		if id != "" {
+			channels[id] = true
+		}
+	}
+	teams := make(map[string]bool, len(teamIDs))
+	for _, id := range teamIDs {
+		if id != "" {
+			teams[id] = true
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels = channels
+	f.teams = teams
+}
+
+// Channels 返回监听的频道ID
+func (f *WatchFilter) Channels() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return sortedKeys(f.channels)
+}
+
+// Teams 返回监听的团队ID
+func (f *WatchFilter) Teams() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return sortedKeys(f.teams)
+}
+
+// ShouldProcess 判断事件是否来自监听的频道或团队
+func (f *WatchFilter) ShouldProcess(event *Event) bool {
+	channelID := ""
+	teamID := stringField(event.Data, "team_id")
+	if event.Channel != nil {
+		channelID = event.Channel.ID
+		if event.Channel.TeamID != "" {
+			teamID = event.Channel.TeamID
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return (channelID != "" && f.channels[channelID]) || (teamID != "" && f.teams[teamID])
+}
+
+// sortedKeys 按字典序返回集合中的元素
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// filterChannelIDs 返回过滤器(包括All/Any组合中)允许的频道ID，用于确定需要补齐消息的频道
+func filterChannelIDs(filter EventFilter) []string {
+	switch f := filter.(type) {
+	case *ChannelFilter:
+		return f.ChannelIDs
+	case *WatchFilter:
+		return f.Channels()
+	case *AllFilter:
+		var ids []string
+		for _, child := range f.Filters {
+			ids = append(ids, filterChannelIDs(child)...)
+		}
+		return ids
+	case *AnyFilter:
+		var ids []string
+		for _, child := range f.Filters {
+			ids = append(ids, filterChannelIDs(child)...)
+		}
+		return ids
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package mattermost
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEventFilterComposition(t *testing.T) {
+	ops := &ChannelFilter{ChannelIDs: []string{"ch-ops"}}
+	posts := &EventTypeFilter{EventTypes: []EventType{EventTypePosted, EventTypePostEdited}}
+	urgent, err := NewRegexFilter("(?i)urgent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posted := &Event{Type: EventTypePosted, Channel: &Channel{ID: "ch-ops"}, Post: &Post{ID: "p1", Message: "URGENT: disk full"}}
+	quiet := &Event{Type: EventTypePosted, Channel: &Channel{ID: "ch-ops"}, Post: &Post{ID: "p2", Message: "lunch?"}}
+	elsewhere := &Event{Type: EventTypePosted, Channel: &Channel{ID: "ch-random"}, Post: &Post{ID: "p3", Message: "urgent"}}
+	// 被监听频道中的输入状态事件: 以前main.go的频道过滤器和事件类型过滤器按OR组合，这类事件会被处理
+	typing := &Event{Type: EventTypeTyping, Channel: &Channel{ID: "ch-ops"}}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		event  *Event
+		want   bool
+	}{
+		{name: "empty all passes", filter: &AllFilter{}, event: typing, want: true},
+		{name: "empty any rejects", filter: &AnyFilter{}, event: posted, want: false},
+		{name: "all channel and type accepts a post", filter: &AllFilter{Filters: []EventFilter{ops, posts}}, event: posted, want: true},
+		{name: "all channel and type rejects typing", filter: &AllFilter{Filters: []EventFilter{ops, posts}}, event: typing, want: false},
+		{name: "all channel and type rejects another channel", filter: &AllFilter{Filters: []EventFilter{ops, posts}}, event: elsewhere, want: false},
+		{name: "any channel or type accepts typing", filter: &AnyFilter{Filters: []EventFilter{ops, posts}}, event: typing, want: true},
+		{name: "any accepts another channel", filter: &AnyFilter{Filters: []EventFilter{ops, posts}}, event: elsewhere, want: true},
+		{name: "not inverts", filter: &NotFilter{Filter: ops}, event: elsewhere, want: true},
+		{name: "not of empty all rejects", filter: &NotFilter{Filter: &AllFilter{}}, event: posted, want: false},
+		{name: "not of any rejects either match", filter: &NotFilter{Filter: &AnyFilter{Filters: []EventFilter{ops, urgent}}}, event: elsewhere, want: false},
+		{name: "not of any accepts neither match", filter: &NotFilter{Filter: &AnyFilter{Filters: []EventFilter{urgent, posts}}}, event: typing, want: true},
+		{
+			name:   "nested all any not accepts",
+			filter: &AllFilter{Filters: []EventFilter{ops, &AnyFilter{Filters: []EventFilter{urgent, &NotFilter{Filter: posts}}}}},
+			event:  posted,
+			want:   true,
+		},
+		{
+			name:   "nested all any not rejects a quiet post",
+			filter: &AllFilter{Filters: []EventFilter{ops, &AnyFilter{Filters: []EventFilter{urgent, &NotFilter{Filter: posts}}}}},
+			event:  quiet,
+			want:   false,
+		},
+		{
+			name:   "nested all any not accepts typing through not",
+			filter: &AllFilter{Filters: []EventFilter{ops, &AnyFilter{Filters: []EventFilter{urgent, &NotFilter{Filter: posts}}}}},
+			event:  typing,
+			want:   true,
+		},
+		{name: "regex rejects events without a post", filter: urgent, event: typing, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.ShouldProcess(tt.event); got != tt.want {
+				t.Errorf("ShouldProcess(%s in %s) = %v, want %v", tt.event.Type, tt.event.Channel.ID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventFilterLeaves(t *testing.T) {
+	bot := &Event{Type: EventTypePosted, Post: &Post{ID: "p1", Props: map[string]interface{}{"from_bot": "true"}}}
+	webhook := &Event{Type: EventTypePosted, Post: &Post{ID: "p2", Props: map[string]interface{}{"from_webhook": true}}}
+	human := &Event{
+		Type:    EventTypePosted,
+		Post:    &Post{ID: "p3", UserID: "u1"},
+		Channel: &Channel{ID: "ch", Type: "P", TeamID: "team-a"},
+		User:    &User{ID: "u1", Username: "Alice"},
+	}
+	joined := &Event{Type: EventTypeUserAdded, Data: map[string]interface{}{"team_id": "team-b"}}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		event  *Event
+		want   bool
+	}{
+		{name: "bot post excluded", filter: &BotPostFilter{}, event: bot, want: false},
+		{name: "webhook post excluded", filter: &BotPostFilter{}, event: webhook, want: false},
+		{name: "human post kept", filter: &BotPostFilter{}, event: human, want: true},
+		{name: "non-post event kept", filter: &BotPostFilter{}, event: joined, want: true},
+		{name: "user by id", filter: &UserFilter{Users: []string{"u1"}}, event: human, want: true},
+		{name: "user by username", filter: &UserFilter{Users: []string{"@alice"}}, event: human, want: true},
+		{name: "other user", filter: &UserFilter{Users: []string{"bob", ""}}, event: human, want: false},
+		{name: "team from channel", filter: &TeamFilter{TeamIDs: []string{"team-a"}}, event: human, want: true},
+		{name: "team from event data", filter: &TeamFilter{TeamIDs: []string{"team-b"}}, event: joined, want: true},
+		{name: "channel type", filter: &ChannelTypeFilter{Types: []string{"o", "p"}}, event: human, want: true},
+		{name: "channel type unknown", filter: &ChannelTypeFilter{Types: []string{"O"}}, event: joined, want: false},
+		{name: "watched team", filter: NewWatchFilter(nil, []string{"team-b"}), event: joined, want: true},
+		{name: "unwatched channel", filter: NewWatchFilter([]string{"ch-ops", ""}, nil), event: human, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.ShouldProcess(tt.event); got != tt.want {
+				t.Errorf("ShouldProcess = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 需要补齐消息的频道包括组合过滤器中所有频道过滤器和监听过滤器的频道
+func TestFilterChannelIDs(t *testing.T) {
+	watch := NewWatchFilter([]string{"ch-b", "ch-a"}, []string{"team"})
+	filter := &AllFilter{Filters: []EventFilter{
+		watch,
+		&AnyFilter{Filters: []EventFilter{&ChannelFilter{ChannelIDs: []string{"ch-c"}}, &EventTypeFilter{}}},
+		&NotFilter{Filter: &ChannelFilter{ChannelIDs: []string{"ch-muted"}}},
+	}}
+
+	got := filterChannelIDs(filter)
+	sort.Strings(got)
+	if want := []string{"ch-a", "ch-b", "ch-c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filterChannelIDs = %v, want %v", got, want)
+	}
+
+	watch.Update([]string{"ch-d"}, nil)
+	if got := filterChannelIDs(watch); !reflect.DeepEqual(got, []string{"ch-d"}) {
+		t.Errorf("filterChannelIDs after Update = %v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package mattermost
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -48,6 +49,9 @@ func (f *EventTypeFilter) ShouldProcess(event *Event) bool {
 	return false
 }
 
+// checkpointSaveInterval 检查点的保存间隔
+const checkpointSaveInterval = 10 * time.Second
+
 // EventListener 负责监听Mattermost WebSocket事件
 type EventListener struct {
 	conn       *Connection
@@ -56,18 +60,20 @@ type EventListener struct {
 	mu         sync.Mutex
 	isRunning  bool
 	stopChan   chan struct{}
-	mockEvents bool // 是否使用模拟事件
+	checkpoint *Checkpoint // 消息检查点，用于去重和断线补齐
+	fetcher    PostFetcher // 获取错过的消息，为nil时只去重不补齐
+	queue      *EventQueue // 异步事件队列，为nil时在读取协程中同步分发
+	store      *EventStore // 事件存储，为nil时不记录事件
 }
 
 // NewEventListener 创建一个新的事件监听器
-func NewEventListener(conn *Connection, mockEvents bool) *EventListener {
+func NewEventListener(conn *Connection) *EventListener {
 	return &EventListener{
-		conn:       conn,
-		handlers:   make([]EventHandler, 0),
-		filters:    make([]EventFilter, 0),
-		isRunning:  false,
-		stopChan:   make(chan struct{}),
-		mockEvents: mockEvents,
+		conn:      conn,
+		handlers:  make([]EventHandler, 0),
+		filters:   make([]EventFilter, 0),
+		isRunning: false,
+		stopChan:  make(chan struct{}),
 	}
 }
 
@@ -78,13 +84,60 @@ func (l *EventListener) AddHandler(handler EventHandler) {
 	l.handlers = append(l.handlers, handler)
 }
 
-// AddFilter 添加事件过滤器
+// AddFilter 添加事件过滤器，事件需要通过所有已添加的过滤器才会被处理
 func (l *EventListener) AddFilter(filter EventFilter) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.filters = append(l.filters, filter)
 }
 
+// EnableRecovery 启用事件去重，以及连接建立后基于检查点补齐错过的消息
+func (l *EventListener) EnableRecovery(checkpoint *Checkpoint, fetcher PostFetcher) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.checkpoint = checkpoint
+	l.fetcher = fetcher
+}
+
+// EnableQueue 启用异步事件队列，事件经过过滤后入队，由队列的处理协程分发给处理器
+func (l *EventListener) EnableQueue(options EventQueueOptions) error {
+	queue, err := NewEventQueue(EventHandlerFunc(l.dispatch), options)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queue = queue
+	return nil
+}
+
+// EnableEventStore 启用事件存储，记录每个接收到的事件及其过滤结果
+func (l *EventListener) EnableEventStore(store *EventStore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store = store
+}
+
+// EventStore 返回事件存储，未启用时返回nil
+func (l *EventListener) EventStore() *EventStore {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.store
+}
+
+// QueueMetrics 返回事件队列的运行指标，未启用队列时返回false
+func (l *EventListener) QueueMetrics() (EventQueueMetrics, bool) {
+	l.mu.Lock()
+	queue := l.queue
+	l.mu.Unlock()
+
+	if queue == nil {
+		return EventQueueMetrics{}, false
+	}
+	return queue.Metrics(), true
+}
+
 // StartListening 开始监听事件
 func (l *EventListener) StartListening() {
 	l.mu.Lock()
@@ -97,29 +150,58 @@ func (l *EventListener) StartListening() {
 
 	log.Println("[MattermostEventListener] Start listening for events...")
 
-	// 连接到Mattermost
-	if err := l.conn.Connect(); err != nil {
-		log.Printf("[MattermostEventListener] Failed to connect: %v", err)
-		return
+	if l.queue != nil {
+		l.queue.Start()
+	}
+
+	// 每次连接建立后补齐错过的消息，并定期保存检查点和事件存储
+	if l.checkpoint != nil {
+		l.conn.AddConnectHandler(l.recoverMissedEvents)
+	}
+	if l.checkpoint != nil || l.store != nil {
+		go l.saveLoop()
 	}
 
 	// 注册为事件处理器
 	l.conn.AddEventHandler(l)
 
-	// 如果启用了模拟事件，则启动模拟事件生成
-	if l.mockEvents {
-		go l.generateMockEvents()
+	// 连接到Mattermost，失败时由连接的重连机制继续尝试
+	if err := l.conn.Connect(); err != nil {
+		log.Printf("[MattermostEventListener] Failed to connect: %v", err)
 	}
 }
 
 // HandleEvent 实现EventHandler接口
 func (l *EventListener) HandleEvent(event *Event) {
+	// 丢弃已处理过的事件(断线补齐和实时推送可能重复)
+	if l.checkpoint != nil && !l.checkpoint.Observe(event) {
+		return
+	}
+
+	if l.store != nil {
+		l.store.Record(event)
+	}
+
 	// 应用所有过滤器
 	if !l.shouldProcessEvent(event) {
+		if l.store != nil {
+			l.store.MarkFiltered(event.ID, "rejected by event filters")
+		}
+		if l.checkpoint != nil {
+			l.checkpoint.Done(event)
+		}
+		return
+	}
+
+	if l.queue != nil {
+		l.queue.HandleEvent(event)
 		return
 	}
+	l.dispatch(event)
+}
 
-	// 分发事件到所有已注册的处理器
+// dispatch 分发事件到所有已注册的处理器
+func (l *EventListener) dispatch(event *Event) {
 	l.mu.Lock()
 	handlers := make([]EventHandler, len(l.handlers))
 	copy(handlers, l.handlers)
@@ -128,24 +210,52 @@ func (l *EventListener) HandleEvent(event *Event) {
 	for _, handler := range handlers {
 		handler.HandleEvent(event)
 	}
+
+	// 处理完成后才推进检查点，队列中尚未处理的事件在崩溃重启后仍会被补齐
+	if l.checkpoint != nil {
+		l.checkpoint.Done(event)
+	}
+	if l.store != nil {
+		l.store.MarkProcessed(event.ID)
+	}
+}
+
+// Replay 将事件存储中的事件重新经过过滤器和当前的处理器处理，返回处理后的记录
+//
+// 重放跳过去重和事件队列，在调用方的协程中同步处理，便于修正配置后立即查看结果。
+func (l *EventListener) Replay(id string) (EventRecord, error) {
+	if l.store == nil {
+		return EventRecord{}, fmt.Errorf("event store not enabled")
+	}
+
+	event, err := l.store.BeginReplay(id)
+	if err != nil {
+		return EventRecord{}, err
+	}
+
+	log.Printf("[MattermostEventListener] Replaying event %s (%s)", id, event.Type)
+	if l.shouldProcessEvent(event) {
+		l.dispatch(event)
+	} else {
+		l.store.MarkFiltered(id, "rejected by event filters")
+	}
+
+	record, _ := l.store.Get(id)
+	return record, nil
 }
 
-// shouldProcessEvent 判断是否处理事件
+// shouldProcessEvent 判断是否处理事件: 无过滤器时处理所有事件，否则需要通过所有过滤器
 func (l *EventListener) shouldProcessEvent(event *Event) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if len(l.filters) == 0 {
-		return true // 无过滤器，处理所有事件
-	}
-
 	for _, filter := range l.filters {
-		if filter.ShouldProcess(event) {
-			return true
+		if !filter.ShouldProcess(event) {
+			return false
 		}
 	}
 
-	return false
+	return true
 }
 
 // StopListening 停止监听事件
@@ -161,56 +271,98 @@ func (l *EventListener) StopListening() {
 
 	log.Println("[MattermostEventListener] Stopping event listening.")
 	l.conn.Close()
+
+	// 处理完队列中剩余的事件
+	if l.queue != nil {
+		l.queue.Stop()
+	}
+
+	l.save()
+}
+
+// recoverMissedEvents 对每个关注的频道，获取自上次处理之后的消息并重新分发
+func (l *EventListener) recoverMissedEvents() {
+	if l.fetcher == nil {
+		return
+	}
+
+	for _, channelID := range l.recoveryChannels() {
+		since := l.checkpoint.LastSeen(channelID)
+		if since == 0 {
+			// 从未处理过该频道的消息，不回放历史
+			continue
+		}
+
+		events, err := recoverChannel(l.fetcher, channelID, since)
+		if err != nil {
+			log.Printf("[MattermostEventListener] Failed to recover posts of channel %s: %v", channelID, err)
+			continue
+		}
+
+		if len(events) > 0 {
+			log.Printf("[MattermostEventListener] Replaying %d missed event(s) from channel %s", len(events), channelID)
+		}
+		for _, event := range events {
+			l.HandleEvent(event)
+		}
+	}
+
+	if err := l.checkpoint.Save(); err != nil {
+		log.Printf("[MattermostEventListener] Failed to save checkpoint: %v", err)
+	}
+}
+
+// recoveryChannels 返回需要补齐消息的频道: 频道过滤器中的频道和检查点中记录过的频道
+func (l *EventListener) recoveryChannels() []string {
+	seen := make(map[string]bool)
+	var channels []string
+	add := func(channelID string) {
+		if channelID != "" && !seen[channelID] {
+			seen[channelID] = true
+			channels = append(channels, channelID)
+		}
+	}
+
+	l.mu.Lock()
+	for _, filter := range l.filters {
+		for _, channelID := range filterChannelIDs(filter) {
+			add(channelID)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, channelID := range l.checkpoint.Channels() {
+		add(channelID)
+	}
+
+	return channels
 }
 
-// generateMockEvents 生成模拟事件进行测试
-func (l *EventListener) generateMockEvents() {
-	ticker := time.NewTicker(5 * time.Second)
+// saveLoop 定期保存检查点和事件存储
+func (l *EventListener) saveLoop() {
+	ticker := time.NewTicker(checkpointSaveInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			// 生成一个模拟的消息发布事件
-			event := l.createMockPostEvent()
-			log.Printf("[MattermostEventListener] Generated mock event: %s", event.Type)
-
-			// 分发事件
-			l.conn.DispatchEvent(event)
-
+			l.save()
 		case <-l.stopChan:
 			return
 		}
 	}
 }
 
-// createMockPostEvent 创建一个模拟的消息发布事件
-func (l *EventListener) createMockPostEvent() *Event {
-	channelID := "channel1"
-	userID := "user1"
-
-	postData := map[string]interface{}{
-		"id":         "post1",
-		"create_at":  time.Now().Unix(),
-		"user_id":    userID,
-		"channel_id": channelID,
-		"message":    "This is a test message that should trigger a task",
-		"type":       "",
+// save 保存检查点和事件存储
+func (l *EventListener) save() {
+	if l.checkpoint != nil {
+		if err := l.checkpoint.Save(); err != nil {
+			log.Printf("[MattermostEventListener] Failed to save checkpoint: %v", err)
+		}
 	}
-
-	data := map[string]interface{}{
-		"post": postData,
-		"channel": map[string]interface{}{
-			"id":           channelID,
-			"name":         "test-channel",
-			"display_name": "Test Channel",
-		},
-		"user": map[string]interface{}{
-			"id":       userID,
-			"username": "testuser",
-			"email":    "test@example.com",
-		},
+	if l.store != nil {
+		if err := l.store.Save(); err != nil {
+			log.Printf("[MattermostEventListener] Failed to save event store: %v", err)
+		}
 	}
-
-	return NewEvent(EventTypePosted, data)
 }
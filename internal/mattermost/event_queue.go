@@ -0,0 +1,430 @@
+package mattermost
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy 定义事件队列已满时的处理方式
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"       // 阻塞读取协程，直到队列有空位(背压)
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // 丢弃最早的事件
+	OverflowSpill      OverflowPolicy = "spill"       // 写入磁盘，队列有空位后按顺序读回
+)
+
+// EventQueueOptions 事件队列的配置
+type EventQueueOptions struct {
+	Capacity int            // 内存中最多缓存的事件数
+	Workers  int            // 处理事件的协程数
+	Overflow OverflowPolicy // 队列已满时的处理方式
+	SpillDir string         // spill策略下溢出事件的存放目录
+}
+
+// EventQueueMetrics 事件队列的运行指标
+type EventQueueMetrics struct {
+	Depth       int            `json:"depth"`        // 内存中等待处理的事件数
+	Spilled     int            `json:"spilled"`      // 磁盘上等待处理的事件数
+	MaxDepth    int            `json:"max_depth"`    // 内存中曾经达到的最大事件数
+	Capacity    int            `json:"capacity"`     // 内存容量
+	Workers     int            `json:"workers"`      // 处理协程数
+	Overflow    OverflowPolicy `json:"overflow"`     // 溢出策略
+	Enqueued    int64          `json:"enqueued"`     // 累计入队事件数
+	Processed   int64          `json:"processed"`    // 累计处理完成的事件数
+	Dropped     int64          `json:"dropped"`      // 累计丢弃的事件数
+	SpillWrites int64          `json:"spill_writes"` // 累计写入磁盘的事件数
+	Blocked     int64          `json:"blocked"`      // 累计因队列已满而阻塞的次数
+}
+
+// EventQueue 是位于事件监听器和事件处理器之间的有界异步队列
+//
+// 事件按频道分配到固定的处理协程，保证同一频道内的事件按顺序处理。
+type EventQueue struct {
+	handler EventHandler
+	options EventQueueOptions
+	shards  []*queueShard
+	wg      sync.WaitGroup
+	started bool
+	mu      sync.Mutex
+
+	enqueued    int64
+	processed   int64
+	dropped     int64
+	spillWrites int64
+	blocked     int64
+}
+
+// queueShard 是单个处理协程负责的队列
+type queueShard struct {
+	queue    *EventQueue
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	items    []*Event
+	capacity int
+	maxDepth int
+	closed   bool
+	spill    *spillFile
+}
+
+// NewEventQueue 创建事件队列，事件出队后交给handler处理
+func NewEventQueue(handler EventHandler, options EventQueueOptions) (*EventQueue, error) {
+	if options.Capacity <= 0 {
+		options.Capacity = 1000
+	}
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.Workers > options.Capacity {
+		options.Workers = options.Capacity
+	}
+	switch options.Overflow {
+	case "":
+		options.Overflow = OverflowBlock
+	case OverflowBlock, OverflowDropOldest:
+	case OverflowSpill:
+		if options.SpillDir == "" {
+			return nil, fmt.Errorf("spill overflow policy requires a spill directory")
+		}
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %q", options.Overflow)
+	}
+
+	q := &EventQueue{
+		handler: handler,
+		options: options,
+	}
+
+	shardCapacity := (options.Capacity + options.Workers - 1) / options.Workers
+	for i := 0; i < options.Workers; i++ {
+		shard := &queueShard{queue: q, capacity: shardCapacity}
+		shard.notEmpty = sync.NewCond(&shard.mu)
+		shard.notFull = sync.NewCond(&shard.mu)
+
+		if options.Overflow == OverflowSpill {
+			spill, err := openSpillFile(filepath.Join(options.SpillDir, fmt.Sprintf("events-%d.jsonl", i)))
+			if err != nil {
+				return nil, err
+			}
+			shard.spill = spill
+		}
+		q.shards = append(q.shards, shard)
+	}
+
+	return q, nil
+}
+
+// Start 启动处理协程
+func (q *EventQueue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+
+	for _, shard := range q.shards {
+		q.wg.Add(1)
+		go shard.run()
+	}
+	log.Printf("[EventQueue] Started %d worker(s), capacity %d, overflow policy %s",
+		q.options.Workers, q.options.Capacity, q.options.Overflow)
+}
+
+// Stop 停止接收事件，等待已入队的事件(包括磁盘上的)处理完成
+func (q *EventQueue) Stop() {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = false
+	q.mu.Unlock()
+
+	for _, shard := range q.shards {
+		shard.mu.Lock()
+		shard.closed = true
+		shard.notEmpty.Broadcast()
+		shard.notFull.Broadcast()
+		shard.mu.Unlock()
+	}
+	q.wg.Wait()
+
+	for _, shard := range q.shards {
+		if shard.spill != nil {
+			shard.spill.close()
+		}
+	}
+	log.Println("[EventQueue] Stopped")
+}
+
+// HandleEvent 实现EventHandler接口，将事件放入队列
+func (q *EventQueue) HandleEvent(event *Event) {
+	q.shardFor(event).push(event)
+}
+
+// Metrics 返回队列的运行指标
+func (q *EventQueue) Metrics() EventQueueMetrics {
+	metrics := EventQueueMetrics{
+		Capacity:    q.options.Capacity,
+		Workers:     q.options.Workers,
+		Overflow:    q.options.Overflow,
+		Enqueued:    atomic.LoadInt64(&q.enqueued),
+		Processed:   atomic.LoadInt64(&q.processed),
+		Dropped:     atomic.LoadInt64(&q.dropped),
+		SpillWrites: atomic.LoadInt64(&q.spillWrites),
+		Blocked:     atomic.LoadInt64(&q.blocked),
+	}
+
+	for _, shard := range q.shards {
+		shard.mu.Lock()
+		metrics.Depth += len(shard.items)
+		metrics.MaxDepth += shard.maxDepth
+		if shard.spill != nil {
+			metrics.Spilled += shard.spill.pending
+		}
+		shard.mu.Unlock()
+	}
+
+	return metrics
+}
+
+// shardFor 按频道选择队列，保证同一频道的事件由同一协程按顺序处理
+func (q *EventQueue) shardFor(event *Event) *queueShard {
+	if len(q.shards) == 1 {
+		return q.shards[0]
+	}
+
+	key := ""
+	switch {
+	case event.Channel != nil:
+		key = event.Channel.ID
+	case event.Post != nil:
+		key = event.Post.ChannelID
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return q.shards[h.Sum32()%uint32(len(q.shards))]
+}
+
+// push 将事件加入队列，队列已满时按溢出策略处理
+func (s *queueShard) push(event *Event) {
+	q := s.queue
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		atomic.AddInt64(&q.dropped, 1)
+		log.Printf("[EventQueue] Queue stopped, dropping %s event", event.Type)
+		return
+	}
+	atomic.AddInt64(&q.enqueued, 1)
+
+	switch q.options.Overflow {
+	case OverflowSpill:
+		// 磁盘上有积压时新事件也写入磁盘，保证顺序
+		if len(s.items) >= s.capacity || s.spill.pending > 0 {
+			if err := s.spill.write(event); err != nil {
+				atomic.AddInt64(&q.dropped, 1)
+				log.Printf("[EventQueue] Failed to spill %s event, dropping: %v", event.Type, err)
+				return
+			}
+			atomic.AddInt64(&q.spillWrites, 1)
+			s.notEmpty.Signal()
+			return
+		}
+	case OverflowDropOldest:
+		if len(s.items) >= s.capacity {
+			dropped := s.items[0]
+			s.items = s.items[1:]
+			atomic.AddInt64(&q.dropped, 1)
+			log.Printf("[EventQueue] Queue full, dropping oldest %s event", dropped.Type)
+		}
+	default:
+		if len(s.items) >= s.capacity {
+			atomic.AddInt64(&q.blocked, 1)
+			for len(s.items) >= s.capacity && !s.closed {
+				s.notFull.Wait()
+			}
+			if s.closed {
+				atomic.AddInt64(&q.dropped, 1)
+				log.Printf("[EventQueue] Queue stopped, dropping %s event", event.Type)
+				return
+			}
+		}
+	}
+
+	s.items = append(s.items, event)
+	if len(s.items) > s.maxDepth {
+		s.maxDepth = len(s.items)
+	}
+	s.notEmpty.Signal()
+}
+
+// pop 取出下一个事件，队列已停止且没有剩余事件时返回nil
+func (s *queueShard) pop() *Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.items) == 0 && !s.hasSpilled() && !s.closed {
+		s.notEmpty.Wait()
+	}
+
+	var event *Event
+	if len(s.items) > 0 {
+		event = s.items[0]
+		s.items[0] = nil
+		s.items = s.items[1:]
+	}
+
+	// 从磁盘读回积压的事件补充到内存队列末尾
+	for s.hasSpilled() && len(s.items) < s.capacity {
+		spilled, err := s.spill.read()
+		if err != nil {
+			atomic.AddInt64(&s.queue.dropped, 1)
+			log.Printf("[EventQueue] Failed to read spilled event, dropping: %v", err)
+			continue
+		}
+		s.items = append(s.items, spilled)
+	}
+	if event == nil && len(s.items) > 0 {
+		event = s.items[0]
+		s.items[0] = nil
+		s.items = s.items[1:]
+	}
+
+	s.notFull.Signal()
+	return event
+}
+
+func (s *queueShard) hasSpilled() bool {
+	return s.spill != nil && s.spill.pending > 0
+}
+
+// run 循环处理事件，直到队列停止且所有事件处理完成
+func (s *queueShard) run() {
+	defer s.queue.wg.Done()
+
+	for {
+		event := s.pop()
+		if event == nil {
+			s.mu.Lock()
+			done := s.closed && len(s.items) == 0 && !s.hasSpilled()
+			s.mu.Unlock()
+			if done {
+				return
+			}
+			continue
+		}
+
+		s.handle(event)
+	}
+}
+
+// handle 调用处理器，处理器panic时只丢弃当前事件
+func (s *queueShard) handle(event *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[EventQueue] Handler panicked on %s event: %v", event.Type, r)
+		}
+		atomic.AddInt64(&s.queue.processed, 1)
+	}()
+	s.queue.handler.HandleEvent(event)
+}
+
+// spillFile 是按行存储事件的磁盘溢出文件，重启后会继续处理文件中剩余的事件
+type spillFile struct {
+	path    string
+	writer  *os.File
+	reader  *os.File
+	buf     *bufio.Reader
+	pending int
+}
+
+func openSpillFile(path string) (*spillFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	writer, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	spill := &spillFile{path: path, writer: writer, reader: reader, buf: bufio.NewReader(reader)}
+
+	// 统计上次运行遗留的事件
+	counter := bufio.NewScanner(io.NewSectionReader(reader, 0, 1<<62))
+	counter.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for counter.Scan() {
+		spill.pending++
+	}
+	if spill.pending > 0 {
+		log.Printf("[EventQueue] Resuming %d spilled event(s) from %s", spill.pending, path)
+	}
+
+	return spill, nil
+}
+
+func (f *spillFile) write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := f.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	f.pending++
+	return nil
+}
+
+func (f *spillFile) read() (*Event, error) {
+	line, err := f.buf.ReadBytes('\n')
+	f.pending--
+	if f.pending == 0 {
+		f.reset()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// reset 所有事件都已读回时清空文件
+func (f *spillFile) reset() {
+	if err := f.writer.Truncate(0); err != nil {
+		log.Printf("[EventQueue] Failed to truncate spill file %s: %v", f.path, err)
+		return
+	}
+	if _, err := f.reader.Seek(0, io.SeekStart); err != nil {
+		log.Printf("[EventQueue] Failed to rewind spill file %s: %v", f.path, err)
+		return
+	}
+	f.buf.Reset(f.reader)
+}
+
+func (f *spillFile) close() {
+	f.writer.Close()
+	f.reader.Close()
+}
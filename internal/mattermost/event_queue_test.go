@@ -0,0 +1,265 @@
+package mattermost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedHandler 记录处理过的事件；gate不为nil时，每个事件开始处理后等待gate放行
+type gatedHandler struct {
+	mu      sync.Mutex
+	handled []string
+	started chan string
+	gate    chan struct{}
+}
+
+func newGatedHandler() *gatedHandler {
+	return &gatedHandler{started: make(chan string, 100), gate: make(chan struct{})}
+}
+
+func (h *gatedHandler) HandleEvent(event *Event) {
+	h.started <- event.Post.ID
+	<-h.gate
+	h.mu.Lock()
+	h.handled = append(h.handled, event.Post.ID)
+	h.mu.Unlock()
+}
+
+func (h *gatedHandler) Handled() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.handled...)
+}
+
+// waitStarted 等待处理协程开始处理指定事件
+func (h *gatedHandler) waitStarted(t *testing.T, id string) {
+	t.Helper()
+	select {
+	case got := <-h.started:
+		if got != id {
+			t.Fatalf("started handling %s, want %s", got, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s to be handled", id)
+	}
+}
+
+func postEvent(channelID, postID string) *Event {
+	return &Event{Type: EventTypePosted, Post: &Post{ID: postID, ChannelID: channelID, Message: "message " + postID}}
+}
+
+// waitFor 轮询直到条件满足，超时时测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 同一频道的事件总是分配到同一个处理协程，并按入队顺序处理
+func TestEventQueueKeepsChannelOrderAcrossShards(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string][]string)
+	queue, err := NewEventQueue(EventHandlerFunc(func(event *Event) {
+		mu.Lock()
+		handled[event.Post.ChannelID] = append(handled[event.Post.ChannelID], event.Post.ID)
+		mu.Unlock()
+	}), EventQueueOptions{Capacity: 100, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.shards) != 4 {
+		t.Fatalf("queue has %d shards, want 4", len(queue.shards))
+	}
+	if queue.shardFor(postEvent("ch-a", "1")) != queue.shardFor(&Event{Channel: &Channel{ID: "ch-a"}}) {
+		t.Error("post and channel events of the same channel use different shards")
+	}
+
+	queue.Start()
+	want := make(map[string][]string)
+	for i := 0; i < 30; i++ {
+		channelID := fmt.Sprintf("ch-%d", i%5)
+		id := fmt.Sprintf("%s-%02d", channelID, i)
+		want[channelID] = append(want[channelID], id)
+		queue.HandleEvent(postEvent(channelID, id))
+	}
+	queue.Stop()
+
+	if !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled = %v\nwant %v", handled, want)
+	}
+	if metrics := queue.Metrics(); metrics.Enqueued != 30 || metrics.Processed != 30 || metrics.Depth != 0 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+func TestNewEventQueueOptions(t *testing.T) {
+	queue, err := NewEventQueue(EventHandlerFunc(func(*Event) {}), EventQueueOptions{Capacity: 2, Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics := queue.Metrics(); metrics.Workers != 2 || metrics.Overflow != OverflowBlock {
+		t.Errorf("metrics = %+v, want workers clamped to the capacity and the block policy", metrics)
+	}
+	if _, err := NewEventQueue(nil, EventQueueOptions{Overflow: OverflowSpill}); err == nil {
+		t.Error("spill policy without a directory was accepted")
+	}
+	if _, err := NewEventQueue(nil, EventQueueOptions{Overflow: "discard"}); err == nil {
+		t.Error("unknown overflow policy was accepted")
+	}
+}
+
+// block策略下队列已满时入队协程等待，不丢弃事件
+func TestEventQueueBlockPolicy(t *testing.T) {
+	handler := newGatedHandler()
+	queue, err := NewEventQueue(handler, EventQueueOptions{Capacity: 1, Overflow: OverflowBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.Start()
+
+	queue.HandleEvent(postEvent("ch", "e1"))
+	handler.waitStarted(t, "e1")
+	queue.HandleEvent(postEvent("ch", "e2"))
+
+	pushed := make(chan struct{})
+	go func() {
+		queue.HandleEvent(postEvent("ch", "e3"))
+		close(pushed)
+	}()
+	waitFor(t, "the third event to block", func() bool { return queue.Metrics().Blocked == 1 })
+	select {
+	case <-pushed:
+		t.Fatal("HandleEvent returned while the queue was full")
+	default:
+	}
+
+	close(handler.gate)
+	<-pushed
+	queue.Stop()
+
+	if got := handler.Handled(); !reflect.DeepEqual(got, []string{"e1", "e2", "e3"}) {
+		t.Errorf("handled = %v", got)
+	}
+	if metrics := queue.Metrics(); metrics.Dropped != 0 || metrics.MaxDepth != 1 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+// drop_oldest策略下队列已满时丢弃最早等待的事件，正在处理的事件不受影响
+func TestEventQueueDropOldestPolicy(t *testing.T) {
+	handler := newGatedHandler()
+	queue, err := NewEventQueue(handler, EventQueueOptions{Capacity: 1, Overflow: OverflowDropOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.Start()
+
+	queue.HandleEvent(postEvent("ch", "e1"))
+	handler.waitStarted(t, "e1")
+	queue.HandleEvent(postEvent("ch", "e2"))
+	queue.HandleEvent(postEvent("ch", "e3"))
+
+	close(handler.gate)
+	queue.Stop()
+
+	if got := handler.Handled(); !reflect.DeepEqual(got, []string{"e1", "e3"}) {
+		t.Errorf("handled = %v, want e2 dropped", got)
+	}
+	if metrics := queue.Metrics(); metrics.Dropped != 1 || metrics.Enqueued != 3 || metrics.Blocked != 0 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+// spill策略下溢出的事件写入磁盘，按顺序读回处理，全部读回后清空文件
+func TestEventQueueSpillPolicy(t *testing.T) {
+	dir := t.TempDir()
+	handler := newGatedHandler()
+	queue, err := NewEventQueue(handler, EventQueueOptions{Capacity: 1, Overflow: OverflowSpill, SpillDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.Start()
+
+	queue.HandleEvent(postEvent("ch", "e1"))
+	handler.waitStarted(t, "e1")
+	for _, id := range []string{"e2", "e3", "e4"} {
+		queue.HandleEvent(postEvent("ch", id))
+	}
+	if metrics := queue.Metrics(); metrics.Depth != 1 || metrics.Spilled != 2 || metrics.SpillWrites != 2 {
+		t.Errorf("metrics while full = %+v, want one event in memory and two on disk", metrics)
+	}
+	spillPath := filepath.Join(dir, "events-0.jsonl")
+	if info, err := os.Stat(spillPath); err != nil || info.Size() == 0 {
+		t.Fatalf("spill file = %v, %v; want the spilled events on disk", info, err)
+	}
+
+	close(handler.gate)
+	queue.Stop()
+
+	if got := handler.Handled(); !reflect.DeepEqual(got, []string{"e1", "e2", "e3", "e4"}) {
+		t.Errorf("handled = %v", got)
+	}
+	if info, err := os.Stat(spillPath); err != nil || info.Size() != 0 {
+		t.Errorf("spill file = %v, %v; want it truncated once drained", info, err)
+	}
+	if metrics := queue.Metrics(); metrics.Dropped != 0 || metrics.Spilled != 0 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+// 上次运行遗留在磁盘上的事件在新队列启动后继续处理
+func TestEventQueueResumesSpilledEvents(t *testing.T) {
+	dir := t.TempDir()
+	spill, err := openSpillFile(filepath.Join(dir, "events-0.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"s1", "s2", "s3"} {
+		if err := spill.write(postEvent("ch", id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spill.close()
+
+	var mu sync.Mutex
+	var handled []*Event
+	queue, err := NewEventQueue(EventHandlerFunc(func(event *Event) {
+		mu.Lock()
+		handled = append(handled, event)
+		mu.Unlock()
+	}), EventQueueOptions{Capacity: 2, Overflow: OverflowSpill, SpillDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spilled := queue.Metrics().Spilled; spilled != 3 {
+		t.Fatalf("resumed %d spilled events, want 3", spilled)
+	}
+	queue.Start()
+	queue.HandleEvent(postEvent("ch", "n1"))
+	queue.Stop()
+
+	var ids []string
+	for _, event := range handled {
+		ids = append(ids, event.Post.ID)
+	}
+	// 磁盘上有积压时新事件排在积压之后
+	if !reflect.DeepEqual(ids, []string{"s1", "s2", "s3", "n1"}) {
+		t.Errorf("handled = %v", ids)
+	}
+	if handled[0].Type != EventTypePosted || handled[0].Post.ChannelID != "ch" || handled[0].Post.Message != "message s1" {
+		t.Errorf("spilled event did not round-trip: %+v %+v", handled[0], handled[0].Post)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "events-0.jsonl")); err != nil || len(data) != 0 {
+		t.Errorf("spill file = %q, %v; want it truncated", data, err)
+	}
+}
@@ -0,0 +1,363 @@
+package mattermost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultMaxStoredEvents 未配置时保留的事件数量上限
+const defaultMaxStoredEvents = 10000
+
+// 事件处理阶段，用于标识决定记录的来源
+const (
+	EventStageFilter    = "filter"    // 监听器的事件过滤器
+	EventStageRule      = "rule"      // 路由规则
+	EventStageConfig    = "config"    // Mattermost转发配置
+	EventStageProcessor = "processor" // 事件处理器
+	EventStageReplay    = "replay"    // 通过API重新处理
+)
+
+// 事件的处理结果
+const (
+	EventOutcomePending     = "pending"      // 已接收，尚未处理完成
+	EventOutcomeFiltered    = "filtered"     // 被过滤器丢弃
+	EventOutcomeNoTask      = "no_task"      // 处理完成但没有生成任务
+	EventOutcomeTaskCreated = "task_created" // 至少生成了一个任务
+)
+
+// EventDecision 记录事件处理过程中的一个决定，例如匹配的规则或处理器的结果
+type EventDecision struct {
+	Time   time.Time `json:"time"`
+	Stage  string    `json:"stage"`
+	Detail string    `json:"detail"`
+}
+
+// EventRecord 是事件存储中的一条记录: 原始事件、处理决定和生成的任务
+type EventRecord struct {
+	ID         string          `json:"id"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Type       EventType       `json:"type"`
+	ChannelID  string          `json:"channel_id"`
+	UserID     string          `json:"user_id"`
+	PostID     string          `json:"post_id"`
+	Outcome    string          `json:"outcome"`
+	Decisions  []EventDecision `json:"decisions"`
+	TaskIDs    []string        `json:"task_ids"`
+	Replays    int             `json:"replays"`
+	Event      *Event          `json:"event"`
+}
+
+// EventStoreOptions 事件存储的配置
+type EventStoreOptions struct {
+	Path      string        // 持久化文件，为空时只保存在内存中
+	Retention time.Duration // 事件保留时长，<=0时只按数量淘汰
+	MaxEvents int           // 保留的事件数量上限，<=0时使用默认值
+}
+
+// EventQuery 事件查询条件，零值字段不参与过滤
+type EventQuery struct {
+	Type      EventType
+	ChannelID string
+	UserID    string
+	PostID    string
+	Outcome   string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// EventStore 保存接收到的每个事件及其处理过程，用于排查事件为何没有生成任务，
+// 并支持在修正配置后重新处理事件
+type EventStore struct {
+	path      string
+	retention time.Duration
+	maxEvents int
+	mu        sync.Mutex
+	records   map[string]*EventRecord
+	order     []string // 按接收时间排列的记录ID，用于淘汰和查询
+	dirty     bool
+}
+
+// NewEventStore 创建事件存储，并从持久化文件加载已有记录
+func NewEventStore(options EventStoreOptions) (*EventStore, error) {
+	store := &EventStore{
+		path:      options.Path,
+		retention: options.Retention,
+		maxEvents: options.MaxEvents,
+		records:   make(map[string]*EventRecord),
+	}
+	if store.maxEvents <= 0 {
+		store.maxEvents = defaultMaxStoredEvents
+	}
+
+	if store.path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*EventRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid event store file %s: %v", store.path, err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ReceivedAt.Before(records[j].ReceivedAt)
+	})
+	for _, record := range records {
+		if record.ID == "" || record.Event == nil {
+			continue
+		}
+		store.records[record.ID] = record
+		store.order = append(store.order, record.ID)
+	}
+	store.pruneLocked(time.Now())
+
+	return store, nil
+}
+
+// Record 保存新接收的事件，为事件分配ID并返回
+func (s *EventStore) Record(event *Event) string {
+	now := time.Now()
+	event.ID = uuid.New().String()
+
+	record := &EventRecord{
+		ID:         event.ID,
+		ReceivedAt: now,
+		Type:       event.Type,
+		Outcome:    EventOutcomePending,
+		Event:      event,
+	}
+	if event.Channel != nil {
+		record.ChannelID = event.Channel.ID
+	}
+	if event.User != nil {
+		record.UserID = event.User.ID
+	}
+	if event.Post != nil {
+		record.PostID = event.Post.ID
+		if record.ChannelID == "" {
+			record.ChannelID = event.Post.ChannelID
+		}
+		if record.UserID == "" {
+			record.UserID = event.Post.UserID
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.ID] = record
+	s.order = append(s.order, record.ID)
+	s.pruneLocked(now)
+	s.dirty = true
+
+	return record.ID
+}
+
+// AddDecision 为事件追加一条处理决定，事件不存在(已被淘汰)时忽略
+func (s *EventStore) AddDecision(id, stage, detail string) {
+	s.update(id, func(record *EventRecord) {
+		record.Decisions = append(record.Decisions, EventDecision{
+			Time:   time.Now(),
+			Stage:  stage,
+			Detail: detail,
+		})
+	})
+}
+
+// AddTask 记录事件生成的任务
+func (s *EventStore) AddTask(id, taskID string) {
+	s.update(id, func(record *EventRecord) {
+		record.TaskIDs = append(record.TaskIDs, taskID)
+		record.Outcome = EventOutcomeTaskCreated
+	})
+}
+
+// MarkFiltered 标记事件被过滤器丢弃
+func (s *EventStore) MarkFiltered(id, detail string) {
+	s.AddDecision(id, EventStageFilter, detail)
+	s.update(id, func(record *EventRecord) {
+		record.Outcome = EventOutcomeFiltered
+	})
+}
+
+// MarkProcessed 标记事件已处理完成，没有生成任务时结果为no_task
+func (s *EventStore) MarkProcessed(id string) {
+	s.update(id, func(record *EventRecord) {
+		if record.Outcome == EventOutcomePending {
+			record.Outcome = EventOutcomeNoTask
+		}
+	})
+}
+
+// BeginReplay 准备重新处理事件: 记录重放并返回原始事件的副本
+func (s *EventStore) BeginReplay(id string) (*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return nil, fmt.Errorf("event %s not found", id)
+	}
+
+	// 通过JSON复制事件，避免重新处理时修改已保存的原始事件
+	data, err := json.Marshal(record.Event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy event %s: %v", id, err)
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to copy event %s: %v", id, err)
+	}
+
+	record.Replays++
+	record.Outcome = EventOutcomePending
+	record.Decisions = append(record.Decisions, EventDecision{
+		Time:   time.Now(),
+		Stage:  EventStageReplay,
+		Detail: fmt.Sprintf("replay #%d", record.Replays),
+	})
+	s.dirty = true
+
+	return &event, nil
+}
+
+// Get 返回指定ID的事件记录
+func (s *EventStore) Get(id string) (EventRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return EventRecord{}, false
+	}
+	return copyRecord(record), true
+}
+
+// Query 按接收时间从新到旧返回满足条件的事件记录
+func (s *EventStore) Query(query EventQuery) []EventRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []EventRecord
+	for i := len(s.order) - 1; i >= 0; i-- {
+		record := s.records[s.order[i]]
+		if !query.matches(record) {
+			continue
+		}
+		result = append(result, copyRecord(record))
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Save 淘汰过期事件并写入持久化文件(没有变化时跳过)
+func (s *EventStore) Save() error {
+	s.mu.Lock()
+	s.pruneLocked(time.Now())
+	if !s.dirty || s.path == "" {
+		s.mu.Unlock()
+		return nil
+	}
+	records := make([]*EventRecord, 0, len(s.order))
+	for _, id := range s.order {
+		records = append(records, s.records[id])
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// update 在持有锁的情况下修改事件记录
+func (s *EventStore) update(id string, fn func(record *EventRecord)) {
+	if id == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[id]; ok {
+		fn(record)
+		s.dirty = true
+	}
+}
+
+// pruneLocked 淘汰超过数量上限或保留时长的最早事件，调用方需持有锁
+func (s *EventStore) pruneLocked(now time.Time) {
+	removed := 0
+	for removed < len(s.order) {
+		record := s.records[s.order[removed]]
+		expired := s.retention > 0 && now.Sub(record.ReceivedAt) > s.retention
+		if !expired && len(s.order)-removed <= s.maxEvents {
+			break
+		}
+		delete(s.records, record.ID)
+		removed++
+	}
+
+	if removed > 0 {
+		s.order = s.order[removed:]
+		s.dirty = true
+	}
+}
+
+// matches 判断事件记录是否满足查询条件
+func (q EventQuery) matches(record *EventRecord) bool {
+	switch {
+	case q.Type != "" && record.Type != q.Type:
+		return false
+	case q.ChannelID != "" && record.ChannelID != q.ChannelID:
+		return false
+	case q.UserID != "" && record.UserID != q.UserID:
+		return false
+	case q.PostID != "" && record.PostID != q.PostID:
+		return false
+	case q.Outcome != "" && record.Outcome != q.Outcome:
+		return false
+	case !q.Since.IsZero() && record.ReceivedAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && record.ReceivedAt.After(q.Until):
+		return false
+	}
+	return true
+}
+
+// copyRecord 复制记录的切片，避免调用方读取时与后续的修改并发
+func copyRecord(record *EventRecord) EventRecord {
+	result := *record
+	result.Decisions = append([]EventDecision(nil), record.Decisions...)
+	result.TaskIDs = append([]string(nil), record.TaskIDs...)
+	return result
+}
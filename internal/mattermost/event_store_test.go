@@ -0,0 +1,273 @@
+package mattermost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// storedIDs 按从新到旧返回满足查询的记录对应的消息ID
+func storedIDs(store *EventStore, query EventQuery) []string {
+	var ids []string
+	for _, record := range store.Query(query) {
+		ids = append(ids, record.PostID)
+	}
+	return ids
+}
+
+func TestEventStorePrunesByCount(t *testing.T) {
+	store, err := NewEventStore(EventStoreOptions{MaxEvents: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first string
+	for i := 1; i <= 5; i++ {
+		id := store.Record(postEvent("ch", fmt.Sprintf("p%d", i)))
+		if i == 1 {
+			first = id
+		}
+	}
+
+	if got := storedIDs(store, EventQuery{}); !reflect.DeepEqual(got, []string{"p5", "p4", "p3"}) {
+		t.Errorf("stored events = %v, want the newest three", got)
+	}
+	if _, ok := store.Get(first); ok {
+		t.Error("the oldest event was not pruned")
+	}
+	// 已淘汰的事件的更新被忽略
+	store.AddTask(first, "task")
+	if got := store.Query(EventQuery{Outcome: EventOutcomeTaskCreated}); len(got) != 0 {
+		t.Errorf("update of a pruned event created %d record(s)", len(got))
+	}
+}
+
+func TestEventStorePrunesByRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.json")
+	store, err := NewEventStore(EventStoreOptions{Path: path, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := store.Record(postEvent("ch", "old"))
+	store.Record(postEvent("ch", "new"))
+	store.records[old].ReceivedAt = time.Now().Add(-2 * time.Hour)
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := storedIDs(store, EventQuery{}); !reflect.DeepEqual(got, []string{"new"}) {
+		t.Errorf("stored events after Save = %v, want the expired event pruned", got)
+	}
+
+	// 加载时同样淘汰过期的记录
+	reloaded, err := NewEventStore(EventStoreOptions{Path: path, Retention: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := storedIDs(reloaded, EventQuery{}); !reflect.DeepEqual(got, []string{"new"}) {
+		t.Errorf("reloaded events = %v", got)
+	}
+	reloaded.records[reloaded.order[0]].ReceivedAt = time.Now().Add(-2 * time.Minute)
+	if err := reloaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := NewEventStore(EventStoreOptions{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := empty.Query(EventQuery{}); len(got) != 0 {
+		t.Errorf("events expired before the last save were reloaded: %+v", got)
+	}
+}
+
+func TestEventStoreOutcomes(t *testing.T) {
+	store, err := NewEventStore(EventStoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome := func(id string) string {
+		record, _ := store.Get(id)
+		return record.Outcome
+	}
+
+	noTask := store.Record(postEvent("ch", "p1"))
+	if outcome(noTask) != EventOutcomePending {
+		t.Errorf("new event outcome = %s, want pending", outcome(noTask))
+	}
+	store.MarkProcessed(noTask)
+	if outcome(noTask) != EventOutcomeNoTask {
+		t.Errorf("processed event without tasks = %s, want no_task", outcome(noTask))
+	}
+
+	created := store.Record(postEvent("ch", "p2"))
+	store.AddDecision(created, EventStageConfig, "matched ops-forward")
+	store.AddTask(created, "task-1")
+	store.MarkProcessed(created)
+	record, _ := store.Get(created)
+	if record.Outcome != EventOutcomeTaskCreated || !reflect.DeepEqual(record.TaskIDs, []string{"task-1"}) {
+		t.Errorf("event with a task = %s %v, want task_created", record.Outcome, record.TaskIDs)
+	}
+	if len(record.Decisions) != 1 || record.Decisions[0].Stage != EventStageConfig {
+		t.Errorf("decisions = %+v", record.Decisions)
+	}
+
+	filtered := store.Record(postEvent("ch", "p3"))
+	store.MarkFiltered(filtered, "rejected by event filters")
+	record, _ = store.Get(filtered)
+	if record.Outcome != EventOutcomeFiltered || len(record.Decisions) != 1 || record.Decisions[0].Stage != EventStageFilter {
+		t.Errorf("filtered event = %s %+v", record.Outcome, record.Decisions)
+	}
+
+	// 返回的记录是副本，修改不影响存储
+	record.TaskIDs = append(record.TaskIDs, "x")
+	record.Decisions[0].Detail = "changed"
+	if again, _ := store.Get(filtered); len(again.TaskIDs) != 0 || again.Decisions[0].Detail != "rejected by event filters" {
+		t.Errorf("changing a returned record changed the store: %+v", again)
+	}
+
+	if got := storedIDs(store, EventQuery{Outcome: EventOutcomeTaskCreated}); !reflect.DeepEqual(got, []string{"p2"}) {
+		t.Errorf("query by outcome = %v", got)
+	}
+	if got := storedIDs(store, EventQuery{Limit: 2}); !reflect.DeepEqual(got, []string{"p3", "p2"}) {
+		t.Errorf("query with limit = %v", got)
+	}
+}
+
+func TestEventStorePersistsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store", "events.json")
+	store, err := NewEventStore(EventStoreOptions{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := &Event{
+		Type:    EventTypePosted,
+		Channel: &Channel{ID: "ch-ops", Name: "ops"},
+		User:    &User{ID: "u1", Username: "alice"},
+		Post:    &Post{ID: "p1", ChannelID: "ch-ops", UserID: "u1", Message: "urgent: disk full"},
+	}
+	id := store.Record(event)
+	if event.ID != id {
+		t.Errorf("event ID = %q, want the record ID %q", event.ID, id)
+	}
+	store.AddDecision(id, EventStageRule, "matched urgent")
+	store.AddTask(id, "task-1")
+	filtered := store.Record(postEvent("ch-random", "p2"))
+	store.MarkFiltered(filtered, "rejected by event filters")
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	reloaded, err := NewEventStore(EventStoreOptions{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := store.Get(id)
+	got, ok := reloaded.Get(id)
+	if !ok {
+		t.Fatalf("event %s was not reloaded", id)
+	}
+	if got.ChannelID != "ch-ops" || got.UserID != "u1" || got.PostID != "p1" || got.Outcome != EventOutcomeTaskCreated ||
+		!reflect.DeepEqual(got.TaskIDs, want.TaskIDs) || len(got.Decisions) != 1 || got.Decisions[0].Detail != "matched urgent" {
+		t.Errorf("reloaded record = %+v\nwant %+v", got, want)
+	}
+	if got.Event == nil || got.Event.Post == nil || got.Event.Post.Message != "urgent: disk full" || got.Event.User.Username != "alice" {
+		t.Errorf("reloaded event = %+v", got.Event)
+	}
+	if ids := storedIDs(reloaded, EventQuery{}); !reflect.DeepEqual(ids, []string{"p2", "p1"}) {
+		t.Errorf("reloaded order = %v", ids)
+	}
+	if ids := storedIDs(reloaded, EventQuery{ChannelID: "ch-random", Outcome: EventOutcomeFiltered}); !reflect.DeepEqual(ids, []string{"p2"}) {
+		t.Errorf("query of reloaded events = %v", ids)
+	}
+
+	// 没有变化时不重写文件
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Save without changes wrote the file: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEventStore(EventStoreOptions{Path: path}); err == nil || !strings.Contains(err.Error(), "invalid event store file") {
+		t.Errorf("NewEventStore with a corrupt file error = %v", err)
+	}
+}
+
+// taskCreatingHandler 模拟事件处理器: 为每个消息事件生成一个任务并记录到事件存储
+type taskCreatingHandler struct {
+	store   *EventStore
+	created int
+	last    *Event
+}
+
+func (h *taskCreatingHandler) HandleEvent(event *Event) {
+	h.created++
+	h.store.AddTask(event.ID, fmt.Sprintf("task-%d", h.created))
+	h.last = event
+}
+
+func TestEventListenerReplay(t *testing.T) {
+	store, err := NewEventStore(EventStoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := NewEventListener(nil)
+	listener.EnableEventStore(store)
+	handler := &taskCreatingHandler{store: store}
+	listener.AddHandler(handler)
+	watch := NewWatchFilter([]string{"ch-ops"}, nil)
+	listener.AddFilter(watch)
+
+	event := &Event{Type: EventTypePosted, Channel: &Channel{ID: "ch-ops"}, Post: &Post{ID: "p1", ChannelID: "ch-ops", Message: "urgent"}}
+	listener.HandleEvent(event)
+	id := event.ID
+
+	record, err := listener.Replay(id)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	// 重放不检查之前生成的任务，处理器会再次生成任务
+	if record.Replays != 1 || record.Outcome != EventOutcomeTaskCreated || !reflect.DeepEqual(record.TaskIDs, []string{"task-1", "task-2"}) {
+		t.Errorf("replayed record = %+v", record)
+	}
+	if last := record.Decisions[len(record.Decisions)-1]; last.Stage != EventStageReplay || last.Detail != "replay #1" {
+		t.Errorf("last decision = %+v, want the replay", last)
+	}
+	// 重放的是存储事件的副本，处理器不会修改存储中的原始事件
+	if handler.last == record.Event || handler.last.Post == record.Event.Post || handler.last.Post.Message != "urgent" {
+		t.Errorf("replayed event %+v is not a copy of the stored event", handler.last)
+	}
+
+	// 修正配置后重放使用当前的过滤器
+	watch.Update(nil, nil)
+	record, err = listener.Replay(id)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if record.Replays != 2 || record.Outcome != EventOutcomeFiltered || handler.created != 2 {
+		t.Errorf("replay rejected by the filters = %+v, handler ran %d time(s)", record, handler.created)
+	}
+
+	if _, err := listener.Replay("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Replay of an unknown event error = %v", err)
+	}
+	if _, err := NewEventListener(nil).Replay(id); err == nil {
+		t.Error("Replay without an event store succeeded")
+	}
+}
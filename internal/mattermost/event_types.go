@@ -1,6 +1,9 @@
 package mattermost
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // EventType 定义Mattermost事件类型
 type EventType string
@@ -19,11 +22,15 @@ const (
 	EventTypeUpdateTeam     EventType = "update_team"     // 团队更新
 )
 
+// PropFromScheduler 标记由本系统发送的消息，用于避免处理自己发出的消息
+const PropFromScheduler = "from_scheduler"
+
 // Post 表示一个Mattermost消息结构
 type Post struct {
 	ID        string                 `json:"id"`
 	CreateAt  int64                  `json:"create_at"`
-	UpdateAt  int64                  `json:"update_at"`
+	UpdateAt  int64                  `json:"update_at"` // 反应、置顶和线程回复也会更新
+	EditAt    int64                  `json:"edit_at"`   // 最后一次修改内容的时间，未修改过时为0
 	DeleteAt  int64                  `json:"delete_at"`
 	UserID    string                 `json:"user_id"`
 	ChannelID string                 `json:"channel_id"`
@@ -55,6 +62,7 @@ type Channel struct {
 
 // Event 表示从Mattermost WebSocket接收到的事件
 type Event struct {
+	ID        string                 `json:"id,omitempty"` // 事件存储分配的ID，未启用事件存储时为空
 	Type      EventType              `json:"type"`
 	Timestamp time.Time              `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
@@ -73,23 +81,106 @@ func NewEvent(eventType EventType, data map[string]interface{}) *Event {
 		Raw:       data,
 	}
 
-	// 解析Post
-	if postData, ok := data["post"].(map[string]interface{}); ok {
-		event.Post = &Post{
-			ID:        postData["id"].(string),
-			Message:   postData["message"].(string),
-			ChannelID: postData["channel_id"].(string),
-			UserID:    postData["user_id"].(string),
+	// 解析Post (WebSocket事件中的post字段是JSON字符串)
+	switch postData := data["post"].(type) {
+	case map[string]interface{}:
+		event.Post = parsePost(postData)
+	case string:
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(postData), &decoded); err == nil {
+			event.Post = parsePost(decoded)
 		}
 	}
 
 	// 解析Channel
 	if channelData, ok := data["channel"].(map[string]interface{}); ok {
 		event.Channel = &Channel{
-			ID:   channelData["id"].(string),
-			Name: channelData["name"].(string),
+			ID:          stringField(channelData, "id"),
+			Name:        stringField(channelData, "name"),
+			DisplayName: stringField(channelData, "display_name"),
+			Type:        stringField(channelData, "type"),
+			TeamID:      stringField(channelData, "team_id"),
+		}
+	}
+
+	// 解析User
+	if userData, ok := data["user"].(map[string]interface{}); ok {
+		event.User = &User{
+			ID:       stringField(userData, "id"),
+			Username: stringField(userData, "username"),
+			Email:    stringField(userData, "email"),
+			Nickname: stringField(userData, "nickname"),
 		}
 	}
 
 	return event
 }
+
+// parsePost 从事件数据中解析消息
+func parsePost(postData map[string]interface{}) *Post {
+	post := &Post{
+		ID:        stringField(postData, "id"),
+		CreateAt:  int64Field(postData, "create_at"),
+		UpdateAt:  int64Field(postData, "update_at"),
+		EditAt:    int64Field(postData, "edit_at"),
+		DeleteAt:  int64Field(postData, "delete_at"),
+		UserID:    stringField(postData, "user_id"),
+		ChannelID: stringField(postData, "channel_id"),
+		RootID:    stringField(postData, "root_id"),
+		ParentID:  stringField(postData, "parent_id"),
+		Message:   stringField(postData, "message"),
+		Type:      stringField(postData, "type"),
+		Hashtags:  stringField(postData, "hashtags"),
+	}
+
+	if props, ok := postData["props"].(map[string]interface{}); ok {
+		post.Props = props
+	}
+
+	switch fileIDs := postData["file_ids"].(type) {
+	case []string:
+		post.FileIDs = fileIDs
+	case []interface{}:
+		for _, id := range fileIDs {
+			if s, ok := id.(string); ok {
+				post.FileIDs = append(post.FileIDs, s)
+			}
+		}
+	}
+
+	return post
+}
+
+// ThreadRootID 返回消息所在线程的根消息ID
+func (p *Post) ThreadRootID() string {
+	if p.RootID != "" {
+		return p.RootID
+	}
+	return p.ID
+}
+
+// IsFromScheduler 判断消息是否由本系统发送
+func (p *Post) IsFromScheduler() bool {
+	fromScheduler, _ := p.Props[PropFromScheduler].(bool)
+	return fromScheduler
+}
+
+// stringField 安全地读取字符串字段
+func stringField(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
+
+// int64Field 安全地读取数值字段(兼容JSON解码后的float64)
+func int64Field(data map[string]interface{}, key string) int64 {
+	switch value := data[key].(type) {
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	case float64:
+		return int64(value)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,103 @@
+package mattermost
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// 交互按钮支持的动作
+const (
+	ActionRetry   = "retry"
+	ActionCancel  = "cancel"
+	ActionApprove = "approve"
+)
+
+// Attachment 表示消息附件(Slack兼容格式)
+type Attachment struct {
+	Fallback string             `json:"fallback,omitempty"`
+	Color    string             `json:"color,omitempty"`
+	Title    string             `json:"title,omitempty"`
+	Text     string             `json:"text,omitempty"`
+	Fields   []*AttachmentField `json:"fields,omitempty"`
+	Actions  []*PostAction      `json:"actions,omitempty"`
+}
+
+// AttachmentField 表示附件中的字段
+type AttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// PostAction 表示附件中的交互按钮
+type PostAction struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Style       string                 `json:"style,omitempty"`
+	Integration *PostActionIntegration `json:"integration"`
+}
+
+// PostActionIntegration 定义按钮点击后的回调地址和上下文
+type PostActionIntegration struct {
+	URL     string                 `json:"url"`
+	Context map[string]interface{} `json:"context"`
+}
+
+// PostActionRequest 表示Mattermost在按钮点击时发送的回调请求
+type PostActionRequest struct {
+	UserID    string                 `json:"user_id"`
+	UserName  string                 `json:"user_name"`
+	ChannelID string                 `json:"channel_id"`
+	TeamID    string                 `json:"team_id"`
+	PostID    string                 `json:"post_id"`
+	TriggerID string                 `json:"trigger_id"`
+	Type      string                 `json:"type"`
+	Context   map[string]interface{} `json:"context"`
+}
+
+// PostActionResponse 表示对按钮回调的响应
+type PostActionResponse struct {
+	Update        *PostActionUpdate `json:"update,omitempty"`
+	EphemeralText string            `json:"ephemeral_text,omitempty"`
+}
+
+// PostActionUpdate 用于替换被点击的原始消息
+type PostActionUpdate struct {
+	Message string                 `json:"message"`
+	Props   map[string]interface{} `json:"props"`
+}
+
+// NewTaskAction 创建一个针对任务的交互按钮，上下文中附带签名用于回调校验
+func NewTaskAction(action, name, style, callbackURL, secret, taskID string) *PostAction {
+	return &PostAction{
+		ID:    action,
+		Name:  name,
+		Style: style,
+		Integration: &PostActionIntegration{
+			URL: callbackURL,
+			Context: map[string]interface{}{
+				"action":    action,
+				"task_id":   taskID,
+				"signature": SignAction(secret, action, taskID),
+			},
+		},
+	}
+}
+
+// SignAction 计算动作和任务ID的HMAC签名
+func SignAction(secret, action, taskID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(action + ":" + taskID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAction 校验回调上下文中的签名
+func VerifyAction(secret, action, taskID, signature string) bool {
+	if secret == "" {
+		return false
+	}
+
+	expected := SignAction(secret, action, taskID)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,47 @@
+package mattermost
+
+import "testing"
+
+func TestNewTaskActionSignsContext(t *testing.T) {
+	action := NewTaskAction(ActionApprove, "Approve", "primary", "http://scheduler/mattermost/actions", "secret", "task-1")
+	context := action.Integration.Context
+	if context["action"] != ActionApprove || context["task_id"] != "task-1" {
+		t.Fatalf("action context = %v", context)
+	}
+	signature, _ := context["signature"].(string)
+	if !VerifyAction("secret", ActionApprove, "task-1", signature) {
+		t.Error("signature of a new task action does not verify")
+	}
+}
+
+func TestVerifyAction(t *testing.T) {
+	signature := SignAction("secret", ActionCancel, "task-1")
+	if signature != SignAction("secret", ActionCancel, "task-1") {
+		t.Error("SignAction is not deterministic")
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		action    string
+		taskID    string
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", action: ActionCancel, taskID: "task-1", signature: signature, want: true},
+		// 把Cancel按钮的签名用于Approve
+		{name: "other action", secret: "secret", action: ActionApprove, taskID: "task-1", signature: signature},
+		{name: "other task", secret: "secret", action: ActionCancel, taskID: "task-2", signature: signature},
+		{name: "other secret", secret: "rotated", action: ActionCancel, taskID: "task-1", signature: signature},
+		// 未配置密钥时拒绝所有回调，即使签名是用空密钥计算的
+		{name: "empty secret", secret: "", action: ActionCancel, taskID: "task-1", signature: SignAction("", ActionCancel, "task-1")},
+		{name: "missing signature", secret: "secret", action: ActionCancel, taskID: "task-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyAction(tt.secret, tt.action, tt.taskID, tt.signature); got != tt.want {
+				t.Errorf("VerifyAction = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package mattermosttest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"my-scheduler-go/internal/mattermost"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 场景步骤支持的动作
+const (
+	ActionPost       = "post"
+	ActionEdit       = "edit"
+	ActionDelete     = "delete"
+	ActionUserAdded  = "user_added"
+	ActionDisconnect = "disconnect"
+)
+
+// Scenario 描述一组按顺序回放的用户操作，可从YAML或JSON文件加载
+type Scenario struct {
+	Name     string            `yaml:"name"`
+	Loop     bool              `yaml:"loop"` // 回放结束后从头开始，直到被取消
+	Users    []ScenarioUser    `yaml:"users"`
+	Channels []ScenarioChannel `yaml:"channels"`
+	Files    []ScenarioFile    `yaml:"files"`
+	Steps    []ScenarioStep    `yaml:"steps"`
+}
+
+// ScenarioUser 场景中预置的用户
+type ScenarioUser struct {
+	ID       string `yaml:"id"`
+	Username string `yaml:"username"`
+	Email    string `yaml:"email"`
+}
+
+// ScenarioChannel 场景中预置的频道
+type ScenarioChannel struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"display_name"`
+	Type        string `yaml:"type"`
+	TeamID      string `yaml:"team_id"`
+}
+
+// ScenarioFile 场景中预置的文件，可在步骤中通过Ref作为附件引用
+type ScenarioFile struct {
+	Ref     string `yaml:"ref"`
+	Name    string `yaml:"name"`
+	Content string `yaml:"content"`
+}
+
+// ScenarioStep 场景中的一个步骤
+//
+// Ref为post步骤创建的消息命名，edit/delete步骤和其他消息的Root通过它引用该消息。
+type ScenarioStep struct {
+	Delay   time.Duration `yaml:"delay"` // 执行前等待的时间，如"2s"
+	Action  string        `yaml:"action"`
+	Channel string        `yaml:"channel"`
+	User    string        `yaml:"user"`
+	Message string        `yaml:"message"`
+	Ref     string        `yaml:"ref"`
+	Root    string        `yaml:"root"`
+	Files   []string      `yaml:"files"`
+}
+
+// LoadScenario 从YAML或JSON文件加载场景
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %v", path, err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %v", path, err)
+	}
+
+	return &scenario, nil
+}
+
+// Validate 检查场景步骤是否完整，以及引用的消息是否在之前创建
+func (sc *Scenario) Validate() error {
+	files := make(map[string]bool, len(sc.Files))
+	for _, file := range sc.Files {
+		files[file.Ref] = true
+	}
+
+	refs := make(map[string]bool)
+	for i, step := range sc.Steps {
+		switch step.Action {
+		case ActionPost:
+			if step.Channel == "" || step.User == "" {
+				return fmt.Errorf("step %d: post requires channel and user", i+1)
+			}
+			if step.Root != "" && !refs[step.Root] {
+				return fmt.Errorf("step %d: unknown root %q", i+1, step.Root)
+			}
+			for _, file := range step.Files {
+				if !files[file] {
+					return fmt.Errorf("step %d: unknown file %q", i+1, file)
+				}
+			}
+			if step.Ref != "" {
+				refs[step.Ref] = true
+			}
+		case ActionEdit, ActionDelete:
+			if !refs[step.Ref] {
+				return fmt.Errorf("step %d: %s requires the ref of an earlier post", i+1, step.Action)
+			}
+		case ActionUserAdded:
+			if step.Channel == "" || step.User == "" {
+				return fmt.Errorf("step %d: user_added requires channel and user", i+1)
+			}
+		case ActionDisconnect:
+		default:
+			return fmt.Errorf("step %d: unknown action %q", i+1, step.Action)
+		}
+	}
+
+	return nil
+}
+
+// RunScenario 在服务器上回放场景，直到回放结束或ctx被取消
+func (s *Server) RunScenario(ctx context.Context, scenario *Scenario) error {
+	if err := scenario.Validate(); err != nil {
+		return err
+	}
+
+	for _, user := range scenario.Users {
+		s.AddUser(&mattermost.User{ID: user.ID, Username: user.Username, Email: user.Email})
+	}
+	for _, channel := range scenario.Channels {
+		s.AddChannel(&mattermost.Channel{
+			ID:          channel.ID,
+			Name:        channel.Name,
+			DisplayName: channel.DisplayName,
+			Type:        channel.Type,
+			TeamID:      channel.TeamID,
+		})
+	}
+
+	for {
+		log.Printf("[FakeMattermost] Running scenario %q", scenario.Name)
+		if err := s.runSteps(ctx, scenario); err != nil {
+			return err
+		}
+		if !scenario.Loop {
+			return nil
+		}
+	}
+}
+
+// runSteps 执行一轮场景步骤，每轮重新创建文件和消息
+func (s *Server) runSteps(ctx context.Context, scenario *Scenario) error {
+	files := make(map[string]string, len(scenario.Files))
+	for _, file := range scenario.Files {
+		files[file.Ref] = s.AddFile(file.Name, []byte(file.Content)).ID
+	}
+
+	posts := make(map[string]string)
+	for i, step := range scenario.Steps {
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var err error
+		switch step.Action {
+		case ActionPost:
+			post := &mattermost.Post{
+				ChannelID: step.Channel,
+				UserID:    step.User,
+				Message:   step.Message,
+				RootID:    posts[step.Root],
+			}
+			for _, ref := range step.Files {
+				post.FileIDs = append(post.FileIDs, files[ref])
+			}
+			created := s.SimulatePost(post)
+			if step.Ref != "" {
+				posts[step.Ref] = created.ID
+			}
+		case ActionEdit:
+			_, err = s.SimulateEdit(posts[step.Ref], step.Message)
+		case ActionDelete:
+			err = s.SimulateDelete(posts[step.Ref])
+		case ActionUserAdded:
+			s.SimulateUserAdded(step.Channel, step.User)
+		case ActionDisconnect:
+			s.DisconnectClients()
+		}
+		if err != nil {
+			return fmt.Errorf("scenario %q step %d: %v", scenario.Name, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// DemoScenario 返回开发模式默认回放的场景: 在指定频道中发送、回复、编辑和删除消息
+func DemoScenario(channelID string) *Scenario {
+	return &Scenario{
+		Name: "demo",
+		Loop: true,
+		Users: []ScenarioUser{
+			{ID: "user1", Username: "alice", Email: "alice@example.com"},
+			{ID: "user2", Username: "bob", Email: "bob@example.com"},
+		},
+		Channels: []ScenarioChannel{
+			{ID: channelID, Name: "scheduler", DisplayName: "Scheduler"},
+		},
+		Files: []ScenarioFile{
+			{Ref: "log", Name: "backup.log", Content: "backup failed: disk full\n"},
+		},
+		Steps: []ScenarioStep{
+			{Delay: 5 * time.Second, Action: ActionPost, Channel: channelID, User: "user1",
+				Message: "urgent: nightly backup failed #ops", Ref: "backup", Files: []string{"log"}},
+			{Delay: 5 * time.Second, Action: ActionPost, Channel: channelID, User: "user2",
+				Message: "task: looking into it", Root: "backup"},
+			{Delay: 5 * time.Second, Action: ActionEdit, Ref: "backup",
+				Message: "urgent: nightly backup failed again #ops"},
+			{Delay: 5 * time.Second, Action: ActionUserAdded, Channel: channelID, User: "user2"},
+			{Delay: 5 * time.Second, Action: ActionPost, Channel: channelID, User: "user1",
+				Message: "important: schedule a restore test", Ref: "restore"},
+			{Delay: 5 * time.Second, Action: ActionDelete, Ref: "restore"},
+			{Delay: 30 * time.Second, Action: ActionPost, Channel: channelID, User: "user2",
+				Message: "just chatting, nothing to do here"},
+		},
+	}
+}
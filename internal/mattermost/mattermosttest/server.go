@@ -0,0 +1,832 @@
+// Package mattermosttest 提供进程内的模拟Mattermost服务器，
+// 用于开发模式和端到端测试(事件 → 任务 → 转发)
+package mattermosttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"my-scheduler-go/internal/mattermost"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// 默认的团队和机器人用户
+const (
+	DefaultTeamID    = "team1"
+	DefaultBotUserID = "scheduler-bot"
+)
+
+// maxUploadSize 上传文件的大小上限
+const maxUploadSize = 64 << 20
+
+var hashtagPattern = regexp.MustCompile(`#[\p{L}\p{N}_-]+`)
+
+// RecordedRequest 记录服务器收到的一个REST请求
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+	Time   time.Time
+}
+
+// storedFile 保存上传或预置的文件
+type storedFile struct {
+	info *mattermost.FileInfo
+	data []byte
+}
+
+// wsClient 表示一个WebSocket连接
+type wsClient struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *wsClient) send(msg *mattermost.WebSocketMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+// Server 是进程内的模拟Mattermost服务器
+//
+// 它实现了本系统用到的REST API v4接口和WebSocket事件推送，记录收到的所有请求
+// 和通过API创建的消息。未知的频道和用户在首次使用时自动创建。
+type Server struct {
+	URL   string
+	Token string
+
+	listener   net.Listener
+	httpServer *http.Server
+	upgrader   websocket.Upgrader
+
+	mu       sync.Mutex
+	users    map[string]*mattermost.User
+	channels map[string]*mattermost.Channel
+	posts    map[string]*mattermost.Post
+	files    map[string]*storedFile
+	requests []RecordedRequest
+	created  []*mattermost.Post // 通过REST API创建的消息(即本系统发出的消息)
+	clients  map[*wsClient]bool
+	seq      int64
+	botUser  *mattermost.User
+}
+
+// NewServer 在127.0.0.1的随机端口上启动模拟服务器，token为空时不校验访问令牌
+func NewServer(token string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %v", err)
+	}
+
+	s := &Server{
+		URL:      "http://" + listener.Addr().String(),
+		Token:    token,
+		listener: listener,
+		users:    make(map[string]*mattermost.User),
+		channels: make(map[string]*mattermost.Channel),
+		posts:    make(map[string]*mattermost.Post),
+		files:    make(map[string]*storedFile),
+		clients:  make(map[*wsClient]bool),
+	}
+	s.botUser = s.AddUser(&mattermost.User{ID: DefaultBotUserID, Username: "scheduler"})
+
+	s.httpServer = &http.Server{Handler: s.routes()}
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("[FakeMattermost] Server error: %v", err)
+		}
+	}()
+
+	log.Printf("[FakeMattermost] Listening on %s", s.URL)
+	return s, nil
+}
+
+// Close 关闭服务器和所有WebSocket连接
+func (s *Server) Close() error {
+	s.DisconnectClients()
+	return s.httpServer.Close()
+}
+
+// AddUser 添加用户，ID为空时自动生成
+func (s *Server) AddUser(user *mattermost.User) *mattermost.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.ID == "" {
+		user.ID = newID()
+	}
+	if user.Username == "" {
+		user.Username = user.ID
+	}
+	s.users[user.ID] = user
+	return user
+}
+
+// AddChannel 添加频道，ID为空时自动生成
+func (s *Server) AddChannel(channel *mattermost.Channel) *mattermost.Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if channel.ID == "" {
+		channel.ID = newID()
+	}
+	s.channels[channel.ID] = fillChannel(channel)
+	return channel
+}
+
+// AddFile 预置一个文件，返回其元数据，可作为模拟消息的附件
+func (s *Server) AddFile(name string, data []byte) *mattermost.FileInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storeFileLocked("", name, data)
+}
+
+// SimulatePost 模拟用户发送消息并推送posted事件
+func (s *Server) SimulatePost(post *mattermost.Post) *mattermost.Post {
+	s.mu.Lock()
+	stored := s.storePostLocked(post)
+	s.mu.Unlock()
+
+	s.broadcastPost(mattermost.EventTypePosted, stored)
+	return stored
+}
+
+// SimulateEdit 模拟用户编辑消息并推送post_edited事件
+func (s *Server) SimulateEdit(postID, message string) (*mattermost.Post, error) {
+	s.mu.Lock()
+	post, err := s.editPostLocked(postID, message)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.broadcastPost(mattermost.EventTypePostEdited, post)
+	return post, nil
+}
+
+// SimulateDelete 模拟用户删除消息并推送post_deleted事件
+func (s *Server) SimulateDelete(postID string) error {
+	s.mu.Lock()
+	post, err := s.deletePostLocked(postID)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.broadcastPost(mattermost.EventTypePostDeleted, post)
+	return nil
+}
+
+// SimulateUserAdded 模拟用户加入频道并推送user_added事件
+func (s *Server) SimulateUserAdded(channelID, userID string) {
+	s.mu.Lock()
+	channel := s.channelLocked(channelID)
+	user := s.userLocked(userID)
+	s.mu.Unlock()
+
+	s.broadcast(&mattermost.WebSocketMessage{
+		Event: string(mattermost.EventTypeUserAdded),
+		Data: map[string]interface{}{
+			"team_id": channel.TeamID,
+			"user_id": user.ID,
+		},
+		Broadcast: map[string]interface{}{
+			"channel_id": channel.ID,
+		},
+	})
+}
+
+// DisconnectClients 断开所有WebSocket连接，用于模拟网络中断
+func (s *Server) DisconnectClients() {
+	s.mu.Lock()
+	clients := make([]*wsClient, 0, len(s.clients))
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.clients = make(map[*wsClient]bool)
+	s.mu.Unlock()
+
+	for _, client := range clients {
+		client.conn.Close()
+	}
+}
+
+// ConnectedClients 返回当前WebSocket连接数
+func (s *Server) ConnectedClients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Requests 返回收到的所有REST请求
+func (s *Server) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]RecordedRequest, len(s.requests))
+	copy(result, s.requests)
+	return result
+}
+
+// CreatedPosts 返回通过REST API创建的消息(包含之后的编辑和删除状态)
+func (s *Server) CreatedPosts() []*mattermost.Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*mattermost.Post, len(s.created))
+	for i, post := range s.created {
+		result[i] = copyPost(post)
+	}
+	return result
+}
+
+// ChannelPosts 返回频道中的所有消息，按创建时间升序
+func (s *Server) ChannelPosts(channelID string) []*mattermost.Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.channelPostsLocked(channelID, 0)
+}
+
+// WaitForPost 等待满足条件的消息通过REST API被创建，超时返回false
+func (s *Server) WaitForPost(match func(*mattermost.Post) bool, timeout time.Duration) (*mattermost.Post, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, post := range s.CreatedPosts() {
+			if match(post) {
+				return post, true
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Reset 清空消息、文件和请求记录，保留用户和频道
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.posts = make(map[string]*mattermost.Post)
+	s.files = make(map[string]*storedFile)
+	s.requests = nil
+	s.created = nil
+}
+
+// routes 注册REST和WebSocket路由
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/websocket", s.handleWebSocket)
+	mux.HandleFunc("GET /api/v4/users/me", s.handleGetMe)
+	mux.HandleFunc("GET /api/v4/users/{id}", s.handleGetUser)
+	mux.HandleFunc("GET /api/v4/channels/{id}", s.handleGetChannel)
+	mux.HandleFunc("POST /api/v4/channels/direct", s.handleCreateDirectChannel)
+	mux.HandleFunc("GET /api/v4/channels/{id}/posts", s.handleGetChannelPosts)
+	mux.HandleFunc("POST /api/v4/posts", s.handleCreatePost)
+	mux.HandleFunc("GET /api/v4/posts/{id}", s.handleGetPost)
+	mux.HandleFunc("PUT /api/v4/posts/{id}/patch", s.handlePatchPost)
+	mux.HandleFunc("DELETE /api/v4/posts/{id}", s.handleDeletePost)
+	mux.HandleFunc("POST /api/v4/files", s.handleUploadFile)
+	mux.HandleFunc("GET /api/v4/files/{id}", s.handleGetFile)
+	mux.HandleFunc("GET /api/v4/files/{id}/info", s.handleGetFileInfo)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "api.context.session_expired.app_error", "Invalid or expired session")
+			return
+		}
+		if r.URL.Path != "/api/v4/websocket" {
+			s.record(r)
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized 校验Bearer令牌(WebSocket也可以只通过认证消息携带令牌)
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" || r.URL.Path == "/api/v4/websocket" {
+		return true
+	}
+	return r.Header.Get("Authorization") == "Bearer "+s.Token
+}
+
+// record 记录请求，并恢复请求体供后续处理读取
+func (s *Server) record(r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(r.Body, maxUploadSize))
+		r.Body.Close()
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, RecordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Body:   body,
+		Time:   time.Now(),
+	})
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := &wsClient{conn: conn}
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+
+	_ = client.send(&mattermost.WebSocketMessage{
+		Event: "hello",
+		Data:  map[string]interface{}{"server_version": "fake"},
+		Seq:   s.nextSeq(),
+	})
+
+	go s.readClient(client)
+}
+
+// readClient 处理客户端发来的请求(认证等)，连接断开时移除客户端
+func (s *Server) readClient(client *wsClient) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+		client.conn.Close()
+	}()
+
+	for {
+		var msg mattermost.WebSocketMessage
+		if err := client.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		status := "OK"
+		if msg.Action == "authentication_challenge" && s.Token != "" {
+			if token, _ := msg.Data["token"].(string); token != s.Token {
+				status = "FAIL"
+			}
+		}
+		_ = client.send(&mattermost.WebSocketMessage{Status: status, SeqReply: msg.Seq})
+		if status != "OK" {
+			return
+		}
+	}
+}
+
+func (s *Server) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.botUser)
+}
+
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "app.user.missing_account.const", "Unable to find the user.")
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) handleGetChannel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, ok := s.channels[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "app.channel.get.existing.app_error", "Unable to find the existing channel.")
+		return
+	}
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func (s *Server) handleCreateDirectChannel(w http.ResponseWriter, r *http.Request) {
+	var userIDs []string
+	if err := json.NewDecoder(r.Body).Decode(&userIDs); err != nil || len(userIDs) != 2 {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "Expected two user ids")
+		return
+	}
+
+	sort.Strings(userIDs)
+	channelID := userIDs[0] + "__" + userIDs[1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userID := range userIDs {
+		s.userLocked(userID)
+	}
+	channel, ok := s.channels[channelID]
+	if !ok {
+		channel = &mattermost.Channel{ID: channelID, Name: channelID, Type: "D"}
+		s.channels[channelID] = channel
+	}
+	writeJSON(w, http.StatusCreated, channel)
+}
+
+func (s *Server) handleGetChannelPosts(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+
+	s.mu.Lock()
+	posts := s.channelPostsLocked(r.PathValue("id"), since)
+	s.mu.Unlock()
+
+	list := struct {
+		Order []string                    `json:"order"`
+		Posts map[string]*mattermost.Post `json:"posts"`
+	}{
+		Order: make([]string, 0, len(posts)),
+		Posts: make(map[string]*mattermost.Post, len(posts)),
+	}
+	// Mattermost按时间倒序返回order
+	for i := len(posts) - 1; i >= 0; i-- {
+		list.Order = append(list.Order, posts[i].ID)
+		list.Posts[posts[i].ID] = posts[i]
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleCreatePost(w http.ResponseWriter, r *http.Request) {
+	var post mattermost.Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", err.Error())
+		return
+	}
+	if post.ChannelID == "" {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "channel_id is required")
+		return
+	}
+
+	s.mu.Lock()
+	if post.RootID != "" {
+		if _, ok := s.posts[post.RootID]; !ok {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, "api.post.create_post.root_id.app_error", "Invalid RootId parameter")
+			return
+		}
+	}
+	post.ID = ""
+	post.UserID = s.botUser.ID
+	stored := s.storePostLocked(&post)
+	s.created = append(s.created, s.posts[stored.ID])
+	s.mu.Unlock()
+
+	s.broadcastPost(mattermost.EventTypePosted, stored)
+	writeJSON(w, http.StatusCreated, stored)
+}
+
+func (s *Server) handleGetPost(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[r.PathValue("id")]
+	if !ok || post.DeleteAt > 0 {
+		writeError(w, http.StatusNotFound, "app.post.get.app_error", "Unable to get the post.")
+		return
+	}
+	writeJSON(w, http.StatusOK, post)
+}
+
+func (s *Server) handlePatchPost(w http.ResponseWriter, r *http.Request) {
+	var patch struct {
+		Message *string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch.Message == nil {
+		writeError(w, http.StatusBadRequest, "api.context.invalid_body_param.app_error", "message is required")
+		return
+	}
+
+	s.mu.Lock()
+	post, err := s.editPostLocked(r.PathValue("id"), *patch.Message)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusNotFound, "app.post.get.app_error", err.Error())
+		return
+	}
+
+	s.broadcastPost(mattermost.EventTypePostEdited, post)
+	writeJSON(w, http.StatusOK, post)
+}
+
+func (s *Server) handleDeletePost(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	post, err := s.deletePostLocked(r.PathValue("id"))
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusNotFound, "app.post.get.app_error", err.Error())
+		return
+	}
+
+	s.broadcastPost(mattermost.EventTypePostDeleted, post)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "OK"})
+}
+
+func (s *Server) handleUploadFile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeError(w, http.StatusBadRequest, "api.file.upload_file.bad_parse.app_error", err.Error())
+		return
+	}
+
+	channelID := r.FormValue("channel_id")
+	infos := make([]*mattermost.FileInfo, 0)
+	for _, header := range r.MultipartForm.File["files"] {
+		file, err := header.Open()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "api.file.upload_file.bad_parse.app_error", err.Error())
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "api.file.upload_file.bad_parse.app_error", err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		infos = append(infos, s.storeFileLocked(channelID, header.Filename, data))
+		s.mu.Unlock()
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"file_infos": infos})
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	file, ok := s.files[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "app.file_info.get.app_error", "Unable to get the file info.")
+		return
+	}
+
+	w.Header().Set("Content-Type", file.info.MimeType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(file.data)
+}
+
+func (s *Server) handleGetFileInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, ok := s.files[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "app.file_info.get.app_error", "Unable to get the file info.")
+		return
+	}
+	writeJSON(w, http.StatusOK, file.info)
+}
+
+// storePostLocked 补全并保存消息，返回副本，调用方需持有锁
+func (s *Server) storePostLocked(post *mattermost.Post) *mattermost.Post {
+	now := time.Now().UnixMilli()
+	if post.ID == "" {
+		post.ID = newID()
+	}
+	if post.CreateAt == 0 {
+		post.CreateAt = now
+	}
+	post.UpdateAt = post.CreateAt
+	if post.Props == nil {
+		post.Props = make(map[string]interface{})
+	}
+	post.Hashtags = hashtags(post.Message)
+
+	s.channelLocked(post.ChannelID)
+	if post.UserID != "" {
+		s.userLocked(post.UserID)
+	}
+
+	stored := copyPost(post)
+	s.posts[stored.ID] = stored
+	for _, fileID := range stored.FileIDs {
+		if file, ok := s.files[fileID]; ok {
+			file.info.PostID = stored.ID
+		}
+	}
+
+	return copyPost(stored)
+}
+
+// editPostLocked 修改消息内容，返回副本，调用方需持有锁
+func (s *Server) editPostLocked(postID, message string) (*mattermost.Post, error) {
+	post, ok := s.posts[postID]
+	if !ok || post.DeleteAt > 0 {
+		return nil, fmt.Errorf("post %s not found", postID)
+	}
+
+	post.Message = message
+	post.Hashtags = hashtags(message)
+	post.UpdateAt = nextMillis(post.UpdateAt)
+	post.EditAt = post.UpdateAt
+	return copyPost(post), nil
+}
+
+// deletePostLocked 标记消息为已删除，返回副本，调用方需持有锁
+func (s *Server) deletePostLocked(postID string) (*mattermost.Post, error) {
+	post, ok := s.posts[postID]
+	if !ok || post.DeleteAt > 0 {
+		return nil, fmt.Errorf("post %s not found", postID)
+	}
+
+	post.DeleteAt = nextMillis(post.UpdateAt)
+	post.UpdateAt = post.DeleteAt
+	return copyPost(post), nil
+}
+
+// storeFileLocked 保存文件并返回元数据，调用方需持有锁
+func (s *Server) storeFileLocked(channelID, name string, data []byte) *mattermost.FileInfo {
+	extension := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		extension = strings.ToLower(name[i+1:])
+	}
+
+	info := &mattermost.FileInfo{
+		ID:        newID(),
+		ChannelID: channelID,
+		Name:      name,
+		Extension: extension,
+		Size:      int64(len(data)),
+		MimeType:  http.DetectContentType(data),
+	}
+	s.files[info.ID] = &storedFile{info: info, data: data}
+	return info
+}
+
+// channelPostsLocked 返回频道中在since之后创建、修改或删除的消息，调用方需持有锁
+func (s *Server) channelPostsLocked(channelID string, since int64) []*mattermost.Post {
+	posts := make([]*mattermost.Post, 0)
+	for _, post := range s.posts {
+		if post.ChannelID != channelID {
+			continue
+		}
+		if since > 0 && post.UpdateAt <= since && post.CreateAt <= since && post.DeleteAt <= since {
+			continue
+		}
+		if since == 0 && post.DeleteAt > 0 {
+			continue
+		}
+		posts = append(posts, copyPost(post))
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+	return posts
+}
+
+// channelLocked 获取频道，不存在时自动创建，调用方需持有锁
+func (s *Server) channelLocked(channelID string) *mattermost.Channel {
+	channel, ok := s.channels[channelID]
+	if !ok {
+		channel = fillChannel(&mattermost.Channel{ID: channelID})
+		s.channels[channelID] = channel
+	}
+	return channel
+}
+
+// userLocked 获取用户，不存在时自动创建，调用方需持有锁
+func (s *Server) userLocked(userID string) *mattermost.User {
+	user, ok := s.users[userID]
+	if !ok {
+		user = &mattermost.User{ID: userID, Username: userID}
+		s.users[userID] = user
+	}
+	return user
+}
+
+// broadcastPost 推送消息相关事件
+func (s *Server) broadcastPost(eventType mattermost.EventType, post *mattermost.Post) {
+	data, err := json.Marshal(post)
+	if err != nil {
+		log.Printf("[FakeMattermost] Failed to encode post %s: %v", post.ID, err)
+		return
+	}
+
+	s.mu.Lock()
+	channel := s.channelLocked(post.ChannelID)
+	sender := s.userLocked(post.UserID)
+	s.mu.Unlock()
+
+	msg := &mattermost.WebSocketMessage{
+		Event: string(eventType),
+		Data:  map[string]interface{}{"post": string(data)},
+		Broadcast: map[string]interface{}{
+			"channel_id": channel.ID,
+		},
+	}
+	if eventType == mattermost.EventTypePosted {
+		msg.Data["channel_name"] = channel.Name
+		msg.Data["channel_display_name"] = channel.DisplayName
+		msg.Data["channel_type"] = channel.Type
+		msg.Data["team_id"] = channel.TeamID
+		msg.Data["sender_name"] = "@" + sender.Username
+	}
+
+	s.broadcast(msg)
+}
+
+// broadcast 将事件推送给所有WebSocket客户端
+func (s *Server) broadcast(msg *mattermost.WebSocketMessage) {
+	msg.Seq = s.nextSeq()
+
+	s.mu.Lock()
+	clients := make([]*wsClient, 0, len(s.clients))
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.mu.Unlock()
+
+	for _, client := range clients {
+		if err := client.send(msg); err != nil {
+			log.Printf("[FakeMattermost] Failed to send %s event: %v", msg.Event, err)
+		}
+	}
+}
+
+func (s *Server) nextSeq() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return s.seq
+}
+
+func fillChannel(channel *mattermost.Channel) *mattermost.Channel {
+	if channel.Name == "" {
+		channel.Name = channel.ID
+	}
+	if channel.DisplayName == "" {
+		channel.DisplayName = channel.Name
+	}
+	if channel.Type == "" {
+		channel.Type = "O"
+	}
+	if channel.TeamID == "" && channel.Type != "D" && channel.Type != "G" {
+		channel.TeamID = DefaultTeamID
+	}
+	return channel
+}
+
+func copyPost(post *mattermost.Post) *mattermost.Post {
+	copied := *post
+	if post.Props != nil {
+		copied.Props = make(map[string]interface{}, len(post.Props))
+		for key, value := range post.Props {
+			copied.Props[key] = value
+		}
+	}
+	if post.FileIDs != nil {
+		copied.FileIDs = append([]string(nil), post.FileIDs...)
+	}
+	return &copied
+}
+
+// hashtags 按Mattermost的规则提取消息中的话题标签，以空格分隔
+func hashtags(message string) string {
+	return strings.Join(hashtagPattern.FindAllString(message, -1), " ")
+}
+
+// nextMillis 返回当前毫秒时间戳，保证严格大于previous
+func nextMillis(previous int64) int64 {
+	now := time.Now().UnixMilli()
+	if now <= previous {
+		return previous + 1
+	}
+	return now
+}
+
+// newID 生成Mattermost风格的26位ID
+func newID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:26]
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, id, message string) {
+	writeJSON(w, status, &mattermost.APIError{StatusCode: status, ID: id, Message: message})
+}
@@ -0,0 +1,257 @@
+package mattermost
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxRecentEvents 用于去重的最近事件数量上限
+const maxRecentEvents = 1000
+
+// PostFetcher 获取频道中错过的消息(由REST客户端实现)
+type PostFetcher interface {
+	GetChannel(channelID string) (*Channel, error)
+	GetPostsSince(channelID string, since int64) ([]*Post, error)
+}
+
+// Checkpoint 记录每个频道最后处理的消息时间和最近处理过的事件，
+// 用于断线重连或重启后补齐错过的消息并去重
+//
+// 事件收到时(Observe)只记录去重键；分发完成后(Done)才推进最后处理时间并持久化去重键，
+// 这样仍在事件队列中的事件在进程崩溃后会由启动时的补齐重新获取。
+type Checkpoint struct {
+	path     string
+	mu       sync.Mutex
+	lastSeen map[string]int64 // 频道ID -> 最后处理完成消息的create_at(毫秒)
+	recent   map[string]bool  // 最近收到的事件键 -> 是否已处理完成
+	order    []string         // recent的插入顺序，用于淘汰
+	dirty    bool
+}
+
+// checkpointFile 是检查点文件的持久化格式
+type checkpointFile struct {
+	LastSeen map[string]int64 `json:"last_seen"`
+	Recent   []string         `json:"recent"`
+}
+
+// LoadCheckpoint 从文件加载检查点，文件不存在时返回空检查点
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		path:     path,
+		lastSeen: make(map[string]int64),
+		recent:   make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file checkpointFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %v", path, err)
+	}
+
+	for channelID, createAt := range file.LastSeen {
+		cp.lastSeen[channelID] = createAt
+	}
+	for _, key := range file.Recent {
+		cp.remember(key)
+		cp.recent[key] = true
+	}
+
+	return cp, nil
+}
+
+// Observe 记录事件的去重键，事件已收到过(无论是否已处理完成)时返回false
+func (c *Checkpoint) Observe(event *Event) bool {
+	key := eventKey(event)
+	if key == "" {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, seen := c.recent[key]; seen {
+		return false
+	}
+
+	c.remember(key)
+	return true
+}
+
+// Done 在事件分发完成(或被过滤器拒绝)后调用，推进频道的最后处理时间，
+// 去重键从此时起写入检查点文件
+func (c *Checkpoint) Done(event *Event) {
+	key := eventKey(event)
+	if key == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, seen := c.recent[key]; seen {
+		c.recent[key] = true
+	}
+	if event.Post.CreateAt > c.lastSeen[event.Post.ChannelID] {
+		c.lastSeen[event.Post.ChannelID] = event.Post.CreateAt
+	}
+	c.dirty = true
+}
+
+// LastSeen 返回频道最后处理消息的create_at，从未处理过时返回0
+func (c *Checkpoint) LastSeen(channelID string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastSeen[channelID]
+}
+
+// Channels 返回所有已记录的频道
+func (c *Checkpoint) Channels() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	channels := make([]string, 0, len(c.lastSeen))
+	for channelID := range c.lastSeen {
+		channels = append(channels, channelID)
+	}
+	return channels
+}
+
+// Save 将检查点写入文件(没有变化时跳过)
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	if !c.dirty || c.path == "" {
+		c.mu.Unlock()
+		return nil
+	}
+	file := checkpointFile{
+		LastSeen: make(map[string]int64, len(c.lastSeen)),
+		Recent:   make([]string, 0, len(c.order)),
+	}
+	for channelID, createAt := range c.lastSeen {
+		file.LastSeen[channelID] = createAt
+	}
+	// 只保存已处理完成的事件键，仍在队列中的事件重启后需要重新补齐
+	for _, key := range c.order {
+		if c.recent[key] {
+			file.Recent = append(file.Recent, key)
+		}
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.path)
+}
+
+// remember 记录尚未处理完成的事件键，超过上限时淘汰最早的记录，调用方需持有锁
+func (c *Checkpoint) remember(key string) {
+	if _, seen := c.recent[key]; seen {
+		return
+	}
+
+	c.recent[key] = false
+	c.order = append(c.order, key)
+	if len(c.order) > maxRecentEvents {
+		delete(c.recent, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// eventKey 返回用于去重的事件键，只有消息的发布、编辑和删除事件参与去重
+func eventKey(event *Event) string {
+	if event.Post == nil || event.Post.ID == "" {
+		return ""
+	}
+
+	switch event.Type {
+	case EventTypePosted:
+		return event.Post.ID
+	case EventTypePostEdited:
+		// update_at也会因反应、置顶等变化，优先使用修改内容的时间
+		editAt := event.Post.EditAt
+		if editAt == 0 {
+			editAt = event.Post.UpdateAt
+		}
+		return fmt.Sprintf("%s:edited:%d", event.Post.ID, editAt)
+	case EventTypePostDeleted:
+		return event.Post.ID + ":deleted"
+	default:
+		return ""
+	}
+}
+
+// recoverChannel 获取频道中自上次处理之后的消息，并转换为事件
+//
+// since是最后处理消息的create_at，同一毫秒内可能还有其他消息，因此从since开始(包含since)获取，
+// 已处理过的消息由检查点的去重键过滤。
+func recoverChannel(fetcher PostFetcher, channelID string, since int64) ([]*Event, error) {
+	posts, err := fetcher.GetPostsSince(channelID, since-1)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, nil
+	}
+
+	channel, err := fetcher.GetChannel(channelID)
+	if err != nil {
+		log.Printf("[MattermostRecovery] Failed to get channel %s: %v", channelID, err)
+		channel = &Channel{ID: channelID}
+	}
+
+	events := make([]*Event, 0, len(posts))
+	for _, post := range posts {
+		var eventType EventType
+		switch {
+		case post.DeleteAt >= since:
+			eventType = EventTypePostDeleted
+		case post.DeleteAt > 0:
+			continue
+		case post.CreateAt >= since:
+			eventType = EventTypePosted
+		case post.EditAt >= since:
+			// 断线前已存在、断线期间被修改内容的消息；只有update_at变化(反应、置顶、线程回复)的不是编辑
+			eventType = EventTypePostEdited
+		default:
+			continue
+		}
+
+		events = append(events, &Event{
+			Type:      eventType,
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"channel_id": channelID,
+				"replayed":   true,
+			},
+			Post:    post,
+			Channel: channel,
+		})
+	}
+
+	return events, nil
+}
@@ -0,0 +1,127 @@
+package mattermost
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 收到事件时只记录去重键，分发完成后才推进最后处理时间；只有处理完成的事件键写入检查点文件，
+// 崩溃前仍在队列中的事件重启后可以被补齐
+func TestCheckpointAdvancesOnlyAfterDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := &Event{Type: EventTypePosted, Post: &Post{ID: "p1", ChannelID: "ch", CreateAt: 1000}}
+	queued := &Event{Type: EventTypePosted, Post: &Post{ID: "p2", ChannelID: "ch", CreateAt: 2000}}
+	for _, event := range []*Event{done, queued} {
+		if !cp.Observe(event) {
+			t.Fatalf("first Observe of %s returned false", event.Post.ID)
+		}
+	}
+	if cp.Observe(queued) {
+		t.Error("an event still in the queue was not deduplicated")
+	}
+	if since := cp.LastSeen("ch"); since != 0 {
+		t.Errorf("LastSeen = %d before any event was dispatched, want 0", since)
+	}
+
+	cp.Done(done)
+	if since := cp.LastSeen("ch"); since != 1000 {
+		t.Errorf("LastSeen = %d, want the create_at of the dispatched event", since)
+	}
+	if err := cp.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 模拟崩溃后重启: p2未处理完成，不应被去重，补齐从p1之后开始
+	reloaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.LastSeen("ch") != 1000 {
+		t.Errorf("reloaded LastSeen = %d, want 1000", reloaded.LastSeen("ch"))
+	}
+	if reloaded.Observe(done) {
+		t.Error("a dispatched event was not deduplicated after the restart")
+	}
+	if !reloaded.Observe(queued) {
+		t.Error("an event that was never dispatched was deduplicated after the restart")
+	}
+}
+
+// fakePostFetcher 返回固定消息的PostFetcher，记录请求的since
+type fakePostFetcher struct {
+	posts []*Post
+	since []int64
+}
+
+func (f *fakePostFetcher) GetChannel(channelID string) (*Channel, error) {
+	return &Channel{ID: channelID, Name: "ops"}, nil
+}
+
+func (f *fakePostFetcher) GetPostsSince(channelID string, since int64) ([]*Post, error) {
+	f.since = append(f.since, since)
+	var posts []*Post
+	for _, post := range f.posts {
+		if post.UpdateAt > since {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func TestRecoverChannel(t *testing.T) {
+	const since = 1000
+	fetcher := &fakePostFetcher{posts: []*Post{
+		// 与最后处理的消息在同一毫秒创建
+		{ID: "same-ms", CreateAt: since, UpdateAt: since},
+		{ID: "new", CreateAt: 1500, UpdateAt: 1500},
+		// 断线期间被修改内容
+		{ID: "edited", CreateAt: 500, UpdateAt: 1200, EditAt: 1200},
+		// 断线前修改过，断线期间只是收到了反应
+		{ID: "reacted", CreateAt: 500, UpdateAt: 1300, EditAt: 700},
+		// 断线期间收到线程回复，从未修改
+		{ID: "replied", CreateAt: 600, UpdateAt: 1400},
+		{ID: "deleted", CreateAt: 500, UpdateAt: 1100, DeleteAt: 1100},
+		{ID: "deleted-before", CreateAt: 100, UpdateAt: 1600, DeleteAt: 900},
+	}}
+
+	events, err := recoverChannel(fetcher, "ch", since)
+	if err != nil {
+		t.Fatalf("recoverChannel: %v", err)
+	}
+	if len(fetcher.since) != 1 || fetcher.since[0] != since-1 {
+		t.Errorf("fetched since %v, want %d so posts created at %d are included", fetcher.since, since-1, since)
+	}
+
+	got := make(map[string]EventType)
+	for _, event := range events {
+		got[event.Post.ID] = event.Type
+		if event.Channel == nil || event.Channel.Name != "ops" || event.Data["replayed"] != true {
+			t.Errorf("event for %s = %+v", event.Post.ID, event)
+		}
+	}
+	want := map[string]EventType{
+		"same-ms": EventTypePosted,
+		"new":     EventTypePosted,
+		"edited":  EventTypePostEdited,
+		"deleted": EventTypePostDeleted,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered events = %v, want %v", got, want)
+	}
+
+	// 已实时处理过的消息由检查点去重
+	cp, _ := LoadCheckpoint("")
+	live := &Event{Type: EventTypePosted, Post: &Post{ID: "same-ms", ChannelID: "ch", CreateAt: since}}
+	cp.Observe(live)
+	for _, event := range events {
+		if event.Post.ID == "same-ms" && cp.Observe(event) {
+			t.Error("recovered post already processed live was not deduplicated")
+		}
+	}
+}
@@ -18,6 +18,9 @@ const (
 	StatusFailed    TaskStatus = "FAILED"
 	StatusTimeout   TaskStatus = "TIMEOUT"
 	StatusRetry     TaskStatus = "RETRY"
+	// StatusAwaitingApproval - task is held until an authorized user approves it
+	StatusAwaitingApproval TaskStatus = "AWAITING_APPROVAL"
+	StatusCancelled        TaskStatus = "CANCELLED"
 
 	// Task Type Constants
 	TypeImmediate TaskType = "IMMEDIATE"
@@ -58,6 +61,19 @@ type Task struct {
 	ExecutionResult map[string]interface{} `json:"execution_result,omitempty"`
 	RetryCount      int                    `json:"retry_count,omitempty"`
 	NextRunAt       time.Time              `json:"next_run_at,omitempty"`
+	// RequiresApproval holds the task in AWAITING_APPROVAL until it is approved
+	RequiresApproval bool   `json:"requires_approval,omitempty"`
+	ApprovedBy       string `json:"approved_by,omitempty"`
+	// Artifacts lists the files produced by the task's runs, oldest first
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+}
+
+// Artifact is a file produced by a task run (e.g. a Jira export), downloadable via the API
+type Artifact struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 func (t *Task) UpdateStatus(newStatus TaskStatus) {
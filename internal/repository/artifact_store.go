@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"my-scheduler-go/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrArtifactNotFound = errors.New("artifact not found")
+)
+
+// DefaultMaxArtifactsPerTask is the number of artifacts kept per task; older ones are deleted
+const DefaultMaxArtifactsPerTask = 10
+
+// ArtifactStore keeps task artifacts as files under <dir>/<task id>/<name> and records them
+// on the task through the repository
+type ArtifactStore struct {
+	dir        string
+	maxPerTask int
+	repo       TaskRepository
+	mu         sync.Mutex // serializes saves, the only writers of the recorded artifacts
+}
+
+func NewArtifactStore(dir string, maxPerTask int, repo TaskRepository) *ArtifactStore {
+	if maxPerTask <= 0 {
+		maxPerTask = DefaultMaxArtifactsPerTask
+	}
+	return &ArtifactStore{
+		dir:        dir,
+		maxPerTask: maxPerTask,
+		repo:       repo,
+	}
+}
+
+// Save writes an artifact of the task and records it in the task's artifacts (replacing one with
+// the same name). The oldest artifacts beyond the per-task limit are removed.
+func (s *ArtifactStore) Save(taskID, name, contentType string, data []byte) (*models.Artifact, error) {
+	if err := validateArtifactName(taskID); err != nil {
+		return nil, err
+	}
+	if err := validateArtifactName(name); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.repo.GetTaskArtifacts(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	taskDir := filepath.Join(s.dir, taskID)
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so a download never sees a partial artifact
+	path := filepath.Join(taskDir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+
+	artifact := models.Artifact{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		CreatedAt:   time.Now(),
+	}
+
+	artifacts := make([]models.Artifact, 0, len(existing)+1)
+	for _, previous := range existing {
+		if previous.Name != name {
+			artifacts = append(artifacts, previous)
+		}
+	}
+	artifacts = append(artifacts, artifact)
+
+	for len(artifacts) > s.maxPerTask {
+		if err := os.Remove(filepath.Join(taskDir, artifacts[0].Name)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		artifacts = artifacts[1:]
+	}
+	if err := s.repo.SetTaskArtifacts(taskID, artifacts); err != nil {
+		return nil, err
+	}
+
+	return &artifact, nil
+}
+
+// Path returns the file of a task artifact
+func (s *ArtifactStore) Path(taskID, name string) (string, *models.Artifact, error) {
+	if validateArtifactName(taskID) != nil || validateArtifactName(name) != nil {
+		return "", nil, ErrArtifactNotFound
+	}
+
+	artifacts, err := s.repo.GetTaskArtifacts(taskID)
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range artifacts {
+		if artifacts[i].Name != name {
+			continue
+		}
+		path := filepath.Join(s.dir, taskID, name)
+		if _, err := os.Stat(path); err != nil {
+			return "", nil, ErrArtifactNotFound
+		}
+		return path, &artifacts[i], nil
+	}
+	return "", nil, ErrArtifactNotFound
+}
+
+// DeleteAll removes every artifact of a task
+func (s *ArtifactStore) DeleteAll(taskID string) error {
+	if err := validateArtifactName(taskID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.RemoveAll(filepath.Join(s.dir, taskID))
+}
+
+// validateArtifactName rejects names that could escape the task directory
+func validateArtifactName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid artifact name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"my-scheduler-go/internal/models"
+)
+
+func TestArtifactStoreSaveReplacesAndPrunes(t *testing.T) {
+	repo := NewInMemoryTaskRepository()
+	task := &models.Task{Name: "export"}
+	if err := repo.AddTask(task); err != nil {
+		t.Fatal(err)
+	}
+	store := NewArtifactStore(t.TempDir(), 2, repo)
+
+	for _, name := range []string{"a.csv", "b.csv", "a.csv", "c.csv"} {
+		if _, err := store.Save(task.ID, name, "text/csv", []byte(name)); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	artifacts, err := repo.GetTaskArtifacts(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, artifact := range artifacts {
+		names = append(names, artifact.Name)
+	}
+	if fmt.Sprint(names) != "[a.csv c.csv]" {
+		t.Errorf("artifacts = %v, want [a.csv c.csv] (b.csv pruned, a.csv replaced)", names)
+	}
+
+	if _, _, err := store.Path(task.ID, "b.csv"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("Path(b.csv) error = %v, want ErrArtifactNotFound", err)
+	}
+	path, artifact, err := store.Path(task.ID, "c.csv")
+	if err != nil {
+		t.Fatalf("Path(c.csv): %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "c.csv" || artifact.Size != 5 {
+		t.Errorf("c.csv = %q (size %d)", data, artifact.Size)
+	}
+	if _, _, err := store.Path(task.ID, "../"+task.ID); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("Path with traversal error = %v, want ErrArtifactNotFound", err)
+	}
+	if _, err := store.Save("missing", "x.csv", "text/csv", nil); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Save for unknown task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+// 保存产物的同时读取列表(GET /tasks/:id/artifacts)，读到的列表必须完整，使用 -race 运行可检测数据竞争
+func TestArtifactStoreConcurrentSaveAndList(t *testing.T) {
+	repo := NewInMemoryTaskRepository()
+	task := &models.Task{Name: "export"}
+	if err := repo.AddTask(task); err != nil {
+		t.Fatal(err)
+	}
+	store := NewArtifactStore(t.TempDir(), 5, repo)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				name := fmt.Sprintf("w%d-%d.json", worker, i)
+				if _, err := store.Save(task.ID, name, "application/json", []byte("{}")); err != nil {
+					t.Errorf("save %s: %v", name, err)
+					return
+				}
+			}
+		}(worker)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			artifacts, err := repo.GetTaskArtifacts(task.ID)
+			if err != nil {
+				t.Errorf("list: %v", err)
+				return
+			}
+			if len(artifacts) > 5 {
+				t.Errorf("listed %d artifacts, limit is 5", len(artifacts))
+			}
+			for _, artifact := range artifacts {
+				if artifact.Name == "" || artifact.Size != 2 {
+					t.Errorf("listed incomplete artifact %+v", artifact)
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+	if artifacts, _ := repo.GetTaskArtifacts(task.ID); len(artifacts) != 5 {
+		t.Errorf("kept %d artifacts, want 5", len(artifacts))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"my-scheduler-go/internal/models"
+	"slices"
 	"sync"
 	"time"
 
@@ -12,6 +13,8 @@ import (
 
 var (
 	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskStatusChanged is returned by UpdateTaskIfStatus when the task is no longer in one of the expected statuses
+	ErrTaskStatusChanged = errors.New("task status changed")
 )
 
 type TaskRepository interface {
@@ -23,9 +26,16 @@ type TaskRepository interface {
 	GetTaskByID(id string) (*models.Task, error)
 	UpdateTaskStatus(id string, newStatus models.TaskStatus) error
 	UpdateTask(task *models.Task) error
+	// UpdateTaskIfStatus applies update to the stored task under the repository lock,
+	// provided the task is still in one of the given statuses
+	UpdateTaskIfStatus(id string, statuses []models.TaskStatus, update func(task *models.Task)) (*models.Task, error)
 	DeleteTask(id string) error
 	GetDependentTasks(taskID string) []*models.Task
 	GetCompletedTaskIDs() map[string]bool
+	// GetTaskArtifacts returns a copy of the artifacts recorded for a task
+	GetTaskArtifacts(id string) ([]models.Artifact, error)
+	// SetTaskArtifacts replaces the artifacts recorded for a task
+	SetTaskArtifacts(id string, artifacts []models.Artifact) error
 }
 
 type InMemoryTaskRepository struct {
@@ -174,6 +184,25 @@ func (r *InMemoryTaskRepository) UpdateTask(task *models.Task) error {
 	return nil
 }
 
+// UpdateTaskIfStatus checks the status and applies update in one critical section, so two callers
+// racing on the same transition (e.g. a double approval) cannot both succeed. update must replace
+// maps and slices rather than modify them in place, since other goroutines may still hold them.
+func (r *InMemoryTaskRepository) UpdateTaskIfStatus(id string, statuses []models.TaskStatus, update func(task *models.Task)) (*models.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	if !slices.Contains(statuses, task.Status) {
+		return nil, fmt.Errorf("%w: task %s is %s", ErrTaskStatusChanged, id, task.Status)
+	}
+	update(task)
+	task.UpdatedAt = time.Now()
+	return task, nil
+}
+
 func (r *InMemoryTaskRepository) DeleteTask(id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -215,3 +244,31 @@ func (r *InMemoryTaskRepository) GetCompletedTaskIDs() map[string]bool {
 	}
 	return result
 }
+
+// GetTaskArtifacts returns a copy of a task's artifacts, taken under the repository lock so a
+// concurrent SetTaskArtifacts is never observed half-way
+func (r *InMemoryTaskRepository) GetTaskArtifacts(id string) ([]models.Artifact, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return append([]models.Artifact{}, task.Artifacts...), nil
+}
+
+// SetTaskArtifacts replaces a task's artifacts with a copy of the given list; the previous slice
+// is never modified, so readers holding it keep a consistent view
+func (r *InMemoryTaskRepository) SetTaskArtifacts(id string, artifacts []models.Artifact) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	task.Artifacts = append([]models.Artifact{}, artifacts...)
+	task.UpdatedAt = time.Now()
+	return nil
+}
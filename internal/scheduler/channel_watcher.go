@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"log"
+	"my-scheduler-go/internal/mattermost"
+)
+
+// ChannelWatcher 根据静态配置和当前的Mattermost配置、路由规则维护监听的频道和团队，
+// 配置更新时同步更新监听过滤器
+type ChannelWatcher struct {
+	filter         *mattermost.WatchFilter
+	staticChannels []string
+	staticTeams    []string
+}
+
+// NewChannelWatcher 创建频道监听集合，并在Mattermost配置或路由规则发生变化后重新计算
+func NewChannelWatcher(configService *ConfigurationService, filter *mattermost.WatchFilter, staticChannels, staticTeams []string) *ChannelWatcher {
+	watcher := &ChannelWatcher{
+		filter:         filter,
+		staticChannels: staticChannels,
+		staticTeams:    staticTeams,
+	}
+
+	kinds := []string{ConfigKindMattermost, ConfigKindRoutingRule}
+	watcher.Update(configService.ConfigurationsOfKind(kinds...))
+	configService.Subscribe(watcher.Update, kinds...)
+
+	return watcher
+}
+
+// Update 将监听集合更新为静态频道/团队与配置中引用的频道/团队的并集
+func (w *ChannelWatcher) Update(configs []Configuration) {
+	channels := append([]string(nil), w.staticChannels...)
+	teams := append([]string(nil), w.staticTeams...)
+
+	for _, config := range configs {
+		switch c := config.(type) {
+		case MattermostConfig:
+			if c.ChannelID != "" {
+				channels = append(channels, c.ChannelID)
+			} else if c.TeamID != "" {
+				teams = append(teams, c.TeamID)
+			}
+		case RoutingRule:
+			channels = append(channels, c.When.Channels...)
+		}
+	}
+
+	w.filter.Update(channels, teams)
+	log.Printf("[ChannelWatcher] Watching channels %v and teams %v", w.filter.Channels(), w.filter.Teams())
+}
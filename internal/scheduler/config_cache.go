@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 校验闸门的默认阈值
+const (
+	defaultMaxDropRatio     = 0.5 // 相对上一份有效配置最多删除一半
+	defaultMaxRowErrorRatio = 0.2 // 最多20%的行解析失败
+)
+
+// RowErrorCounter 由能报告被跳过行数的配置获取器实现，用于校验闸门
+type RowErrorCounter interface {
+	// RowErrorCount 返回最近一次获取时因校验失败被跳过的行数
+	RowErrorCount() int
+}
+
+// ConfigGate 新获取的配置在生效前必须通过的校验
+//
+// 阈值为nil时使用默认值，为0时不允许任何删除(或行错误)，设置为1或更大时不限制。
+type ConfigGate struct {
+	MaxDropRatio     *float64 // 相对当前配置被删除的配置项比例上限
+	MaxRowErrorRatio *float64 // 获取时被跳过的行占总行数的比例上限
+}
+
+// ratioOrDefault 返回设置的阈值，未设置时返回默认值
+func ratioOrDefault(ratio *float64, defaultRatio float64) float64 {
+	if ratio == nil {
+		return defaultRatio
+	}
+	return *ratio
+}
+
+// Check 校验新配置: 每个配置项都必须有效，且删除比例和行错误比例不能超过阈值
+func (g ConfigGate) Check(current, next []Configuration, rowErrors int) error {
+	if err := ValidateConfigurations(next); err != nil {
+		return err
+	}
+
+	maxRowErrorRatio := ratioOrDefault(g.MaxRowErrorRatio, defaultMaxRowErrorRatio)
+	if total := rowErrors + len(next); rowErrors > 0 {
+		if ratio := float64(rowErrors) / float64(total); ratio > maxRowErrorRatio {
+			return fmt.Errorf("%d of %d rows failed validation (%.0f%% > %.0f%%)", rowErrors, total, ratio*100, maxRowErrorRatio*100)
+		}
+	}
+
+	maxDropRatio := ratioOrDefault(g.MaxDropRatio, defaultMaxDropRatio)
+	if len(current) > 0 {
+		removed := 0
+		for _, change := range DiffConfigurations(current, next) {
+			if change.Type == ConfigChangeRemoved {
+				removed++
+			}
+		}
+		if ratio := float64(removed) / float64(len(current)); ratio > maxDropRatio {
+			return fmt.Errorf("%d of %d configurations would be removed (%.0f%% > %.0f%%)", removed, len(current), ratio*100, maxDropRatio*100)
+		}
+	}
+
+	return nil
+}
+
+// ValidateConfigurations 校验一组配置: 种类已注册、各自的字段有效且同一种类内ID不重复
+func ValidateConfigurations(configs []Configuration) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, config := range configs {
+		kind := config.ConfigKind()
+		if _, ok := LookupConfigKind(kind); !ok {
+			errs = append(errs, fmt.Errorf("configuration %d: unknown kind %q", i+1, kind))
+			continue
+		}
+		if err := config.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", kind, err))
+			continue
+		}
+
+		key := kind + "/" + config.ConfigID()
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("%s: duplicate id %q", kind, config.ConfigID()))
+		}
+		seen[key] = true
+	}
+	return errors.Join(errs...)
+}
+
+// ConfigCache 将最近一份通过校验的配置保存到磁盘，数据源不可用时用于启动
+type ConfigCache struct {
+	path string
+}
+
+// cachedConfig 缓存文件中的一个配置项，按种类区分配置类型
+type cachedConfig struct {
+	Kind   string          `json:"kind"`
+	Config json.RawMessage `json:"config"`
+}
+
+// cacheFile 缓存文件的内容
+type cacheFile struct {
+	SavedAt        time.Time      `json:"saved_at"`
+	Configurations []cachedConfig `json:"configurations"`
+}
+
+// NewConfigCache 创建配置缓存
+func NewConfigCache(path string) *ConfigCache {
+	return &ConfigCache{path: path}
+}
+
+// Path 返回缓存文件路径
+func (c *ConfigCache) Path() string {
+	return c.path
+}
+
+// Load 读取缓存的配置，文件不存在时返回nil
+func (c *ConfigCache) Load() ([]Configuration, time.Time, error) {
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid configuration cache %s: %v", c.path, err)
+	}
+
+	configs := make([]Configuration, 0, len(file.Configurations))
+	for i, entry := range file.Configurations {
+		config, err := decodeConfiguration(entry.Kind, entry.Config)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid configuration cache %s: entry %d: %v", c.path, i+1, err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, file.SavedAt, nil
+}
+
+// Save 保存配置，先写临时文件再重命名
+func (c *ConfigCache) Save(configs []Configuration) error {
+	file := cacheFile{SavedAt: time.Now(), Configurations: make([]cachedConfig, 0, len(configs))}
+	for _, config := range configs {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return err
+		}
+		file.Configurations = append(file.Configurations, cachedConfig{Kind: config.ConfigKind(), Config: data})
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.path)
+}
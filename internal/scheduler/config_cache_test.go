@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// forwardConfigs 返回n个有效的转发配置
+func forwardConfigs(n int) []Configuration {
+	configs := make([]Configuration, n)
+	for i := range configs {
+		configs[i] = MattermostConfig{ID: string(rune('a' + i)), ChannelID: "ch", ForwardType: "channel_message"}
+	}
+	return configs
+}
+
+func ratio(value float64) *float64 {
+	return &value
+}
+
+func TestConfigGateCheck(t *testing.T) {
+	current := forwardConfigs(4)
+	tests := []struct {
+		name      string
+		gate      ConfigGate
+		current   []Configuration
+		next      []Configuration
+		rowErrors int
+		errMsg    string
+	}{
+		{name: "unchanged", next: forwardConfigs(4)},
+		{name: "default allows half removed", next: forwardConfigs(2)},
+		{name: "default rejects more than half removed", next: forwardConfigs(1), errMsg: "3 of 4 configurations would be removed (75% > 50%)"},
+		{name: "zero rejects any removal", gate: ConfigGate{MaxDropRatio: ratio(0)}, next: forwardConfigs(3), errMsg: "1 of 4 configurations would be removed (25% > 0%)"},
+		{name: "zero allows additions", gate: ConfigGate{MaxDropRatio: ratio(0)}, next: forwardConfigs(6)},
+		{name: "one disables the drop check", gate: ConfigGate{MaxDropRatio: ratio(1)}, next: nil},
+		{name: "first load has nothing to drop", current: []Configuration{}, next: forwardConfigs(1)},
+		{name: "default allows some row errors", next: forwardConfigs(4), rowErrors: 1},
+		{name: "default rejects many row errors", next: forwardConfigs(4), rowErrors: 2, errMsg: "2 of 6 rows failed validation (33% > 20%)"},
+		{name: "zero rejects any row error", gate: ConfigGate{MaxRowErrorRatio: ratio(0)}, next: forwardConfigs(4), rowErrors: 1, errMsg: "1 of 5 rows failed validation"},
+		{name: "invalid configuration", next: []Configuration{MattermostConfig{ID: "x", ChannelID: "ch"}}, errMsg: "forward_type is required"},
+		{name: "duplicate id", next: append(forwardConfigs(4), forwardConfigs(1)...), errMsg: `duplicate id "a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := current
+			if tt.current != nil {
+				base = tt.current
+			}
+			err := tt.gate.Check(base, tt.next, tt.rowErrors)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Check: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Check error = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestConfigCacheRoundTrip(t *testing.T) {
+	cache := NewConfigCache(filepath.Join(t.TempDir(), "cache", "configurations.json"))
+	if configs, _, err := cache.Load(); err != nil || configs != nil {
+		t.Fatalf("Load without a file = %v, %v; want nil", configs, err)
+	}
+
+	rule := RoutingRule{
+		ID:      "urgent",
+		When:    RuleConditions{Channels: []string{"ops"}, Pattern: "(?i)urgent"},
+		Actions: []RuleAction{{Type: RuleActionSetPriority, Priority: "high"}},
+	}
+	if err := rule.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	forward := MattermostConfig{
+		ID: "ops-forward", ChannelID: "ch-ops", ForwardType: "channel_message", OnMatch: ConfigOnMatchStop,
+		Custom: map[string]interface{}{"target_channel_id": "ch-archive", "include_files": "true"},
+	}
+	if err := cache.Save([]Configuration{forward, rule}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	configs, savedAt, err := cache.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if savedAt.IsZero() || len(configs) != 2 {
+		t.Fatalf("Load = %d configurations saved at %v", len(configs), savedAt)
+	}
+	if !reflect.DeepEqual(configs[0], forward) {
+		t.Errorf("forward config = %+v, want %+v", configs[0], forward)
+	}
+	loaded, ok := configs[1].(RoutingRule)
+	if !ok || loaded.ID != rule.ID || !reflect.DeepEqual(loaded.When, rule.When) || !reflect.DeepEqual(loaded.Actions, rule.Actions) {
+		t.Errorf("routing rule = %#v, want %+v", configs[1], rule)
+	}
+	if loaded.pattern == nil {
+		t.Error("cached routing rule was not compiled")
+	}
+	if _, err := os.Stat(cache.Path() + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	if err := os.WriteFile(cache.Path(), []byte(`{"configurations":[{"kind":"widget","config":{}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cache.Load(); err == nil || !strings.Contains(err.Error(), `unknown kind "widget"`) {
+		t.Errorf("Load of an unknown kind error = %v", err)
+	}
+}
+
+// 删除比例检查拒绝的配置在重启后仍会被拒绝(缓存中是旧配置)，只有强制刷新能接受它
+func TestForceRefreshAcceptsRejectedDrop(t *testing.T) {
+	dir := t.TempDir()
+	fetcher := &switchingFetcher{configs: forwardConfigs(4)}
+	service := NewConfigurationService(fetcher, 0)
+	service.SetDebounce(0)
+	if err := service.EnableCache(NewConfigCache(filepath.Join(dir, "configurations.json"))); err != nil {
+		t.Fatal(err)
+	}
+	if result := service.Refresh(RefreshTriggerStartup); result.Error != "" {
+		t.Fatalf("initial refresh: %s", result.Error)
+	}
+
+	fetcher.configs = forwardConfigs(1)
+	if result := service.Refresh(RefreshTriggerManual); !result.Rejected {
+		t.Fatalf("refresh removing 3 of 4 configurations was not rejected: %+v", result)
+	}
+
+	// 重启: 缓存中仍是4个配置，新获取的配置依然被拒绝
+	restarted := NewConfigurationService(fetcher, 0)
+	restarted.SetDebounce(0)
+	if err := restarted.EnableCache(NewConfigCache(filepath.Join(dir, "configurations.json"))); err != nil {
+		t.Fatal(err)
+	}
+	if result := restarted.Refresh(RefreshTriggerStartup); !result.Rejected || len(restarted.GetCurrentConfigurations()) != 4 {
+		t.Fatalf("refresh after restart = %+v with %d configurations", result, len(restarted.GetCurrentConfigurations()))
+	}
+
+	result := restarted.ForceRefresh(RefreshTriggerManual)
+	if result.Rejected || !result.Forced || !result.Changed || result.Summary()[ConfigChangeRemoved] != 3 {
+		t.Fatalf("ForceRefresh = %+v", result)
+	}
+	if got := len(restarted.GetCurrentConfigurations()); got != 1 || restarted.LastRejection() != nil {
+		t.Errorf("after ForceRefresh: %d configurations, last rejection %+v", got, restarted.LastRejection())
+	}
+	if cached, _, err := NewConfigCache(filepath.Join(dir, "configurations.json")).Load(); err != nil || len(cached) != 1 {
+		t.Errorf("cache after ForceRefresh = %d configurations, %v", len(cached), err)
+	}
+
+	// 强制刷新只跳过删除比例检查，无效的配置仍被拒绝
+	fetcher.configs = []Configuration{MattermostConfig{ID: "broken"}}
+	if result := restarted.ForceRefresh(RefreshTriggerManual); !result.Rejected {
+		t.Errorf("ForceRefresh accepted an invalid configuration: %+v", result)
+	}
+}
+
+// switchingFetcher 返回可在测试中替换的配置
+type switchingFetcher struct {
+	configs []Configuration
+}
+
+func (f *switchingFetcher) FetchConfigurations() ([]Configuration, error) {
+	return f.configs, nil
+}
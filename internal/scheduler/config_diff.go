@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// 配置变更的类型
+const (
+	ConfigChangeAdded    = "added"
+	ConfigChangeRemoved  = "removed"
+	ConfigChangeModified = "modified"
+)
+
+// ConfigChange 一个配置项的变更
+type ConfigChange struct {
+	Type   string        `json:"type"` // added, removed或modified
+	Kind   string        `json:"kind"`
+	ID     string        `json:"id"`
+	Fields []string      `json:"fields,omitempty"` // modified时发生变化的字段
+	Before Configuration `json:"before,omitempty"`
+	After  Configuration `json:"after,omitempty"`
+}
+
+// ConfigSnapshot 配置的一个版本: 获取到的全部配置以及相对上一版本的变更
+type ConfigSnapshot struct {
+	Version        int             `json:"version"`
+	Time           time.Time       `json:"time"`
+	Configurations []Configuration `json:"configurations"`
+	Changes        []ConfigChange  `json:"changes"`
+	FromCache      bool            `json:"from_cache,omitempty"` // 启动时从磁盘缓存加载
+}
+
+// Summary 按变更类型统计数量
+func (s *ConfigSnapshot) Summary() map[string]int {
+	summary := map[string]int{ConfigChangeAdded: 0, ConfigChangeRemoved: 0, ConfigChangeModified: 0}
+	for _, change := range s.Changes {
+		summary[change.Type]++
+	}
+	return summary
+}
+
+// DiffConfigurations 按种类和ID比较两组配置，返回新增、删除和修改的配置项
+func DiffConfigurations(before, after []Configuration) []ConfigChange {
+	type key struct{ kind, id string }
+	index := func(configs []Configuration) (map[key]Configuration, []key) {
+		result := make(map[key]Configuration, len(configs))
+		var order []key
+		for _, config := range configs {
+			k := key{config.ConfigKind(), config.ConfigID()}
+			if _, exists := result[k]; !exists {
+				order = append(order, k)
+			}
+			result[k] = config
+		}
+		return result, order
+	}
+
+	oldConfigs, oldOrder := index(before)
+	newConfigs, newOrder := index(after)
+
+	var changes []ConfigChange
+	for _, k := range newOrder {
+		config := newConfigs[k]
+		old, exists := oldConfigs[k]
+		if !exists {
+			changes = append(changes, ConfigChange{Type: ConfigChangeAdded, Kind: k.kind, ID: k.id, After: config})
+			continue
+		}
+		if fields := changedFields(old, config); len(fields) > 0 {
+			changes = append(changes, ConfigChange{Type: ConfigChangeModified, Kind: k.kind, ID: k.id, Fields: fields, Before: old, After: config})
+		}
+	}
+	for _, k := range oldOrder {
+		if _, exists := newConfigs[k]; !exists {
+			changes = append(changes, ConfigChange{Type: ConfigChangeRemoved, Kind: k.kind, ID: k.id, Before: oldConfigs[k]})
+		}
+	}
+
+	return changes
+}
+
+// changedFields 比较两个配置的JSON表示，返回值不同的字段
+func changedFields(before, after Configuration) []string {
+	oldFields, oldErr := configFields(before)
+	newFields, newErr := configFields(after)
+	if oldErr != nil || newErr != nil {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return []string{"*"}
+	}
+
+	var fields []string
+	for name, value := range newFields {
+		if !reflect.DeepEqual(oldFields[name], value) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range oldFields {
+		if _, exists := newFields[name]; !exists {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// configFields 将配置转换为字段名到值的映射
+func configFields(config Configuration) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
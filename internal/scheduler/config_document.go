@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigWatcher 由能感知数据源变化的配置获取器实现(如监听文件)，
+// 配置服务启动后调用Watch，数据源变化时调用onChange立即重新获取
+type ConfigWatcher interface {
+	Watch(stop <-chan struct{}, onChange func()) error
+}
+
+// DecodeConfigurations 解析YAML或JSON格式的配置文档
+//
+// 文档是配置项的列表，或包含configurations列表的对象。每个配置项用kind字段区分种类，
+// 其余字段与该种类配置的JSON字段相同，例如:
+//
+//	configurations:
+//	  - kind: mattermost
+//	    id: cfg1
+//	    channel_id: channel1
+//	    forward_type: user
+//	  - kind: routing_rule
+//	    id: urgent
+//	    when: {pattern: "(?i)urgent"}
+//	    actions: [{type: set_priority, priority: high}]
+func DecodeConfigurations(data []byte) ([]Configuration, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	var entries []interface{}
+	switch doc := document.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		entries = doc
+	case map[string]interface{}:
+		list, ok := doc["configurations"].([]interface{})
+		if !ok && doc["configurations"] != nil {
+			return nil, fmt.Errorf("configurations must be a list")
+		}
+		entries = list
+	default:
+		return nil, fmt.Errorf("expected a list of configurations")
+	}
+
+	configs := make([]Configuration, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("configuration %d: expected an object", i+1)
+		}
+		kind, _ := fields["kind"].(string)
+		delete(fields, "kind")
+
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("configuration %d: %v", i+1, err)
+		}
+		config, err := decodeConfiguration(kind, data)
+		if err != nil {
+			return nil, fmt.Errorf("configuration %d: %v", i+1, err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, nil
+}
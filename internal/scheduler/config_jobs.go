@@ -0,0 +1,241 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"my-scheduler-go/internal/models"
+)
+
+// 由配置生成的定时任务使用的标签
+const (
+	TaskTagJiraExport = "JIRA_TASK_EXP"
+	TaskTagReport     = "REPORT"
+)
+
+// Jira导出的范围
+const (
+	JiraKeyTypeRootTicket = "root_ticket" // 根问题及其所有子问题
+	JiraKeyTypeProject    = "project"     // 项目中的所有问题
+)
+
+// JiraSyncJob 定时从Jira导出问题的配置
+type JiraSyncJob struct {
+	ID          string `json:"id"`
+	Environment string `json:"environment"` // Jira环境名称，为空时使用默认环境
+	KeyType     string `json:"key_type"`    // root_ticket或project
+	KeyValue    string `json:"key_value"`   // 根问题的key或项目key
+	Schedule    string `json:"schedule"`    // cron表达式(包含秒)
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// ConfigKind 实现Configuration
+func (j JiraSyncJob) ConfigKind() string { return ConfigKindJiraSync }
+
+// ConfigID 实现Configuration
+func (j JiraSyncJob) ConfigID() string { return j.ID }
+
+// Validate 实现Configuration
+func (j JiraSyncJob) Validate() error {
+	switch {
+	case j.ID == "":
+		return errors.New("id is required")
+	case j.KeyType != JiraKeyTypeRootTicket && j.KeyType != JiraKeyTypeProject:
+		return fmt.Errorf("jira sync %s: invalid key_type %q (expected root_ticket or project)", j.ID, j.KeyType)
+	case j.KeyValue == "":
+		return fmt.Errorf("jira sync %s: key_value is required", j.ID)
+	}
+	return validateSchedule(j.ID, j.Schedule)
+}
+
+// Enabled 实现TaskConfiguration
+func (j JiraSyncJob) Enabled() bool { return !j.Disabled }
+
+// ScheduledTask 实现TaskConfiguration
+func (j JiraSyncJob) ScheduledTask() *models.Task {
+	return &models.Task{
+		Name:     "Jira导出: " + j.KeyValue,
+		TaskType: models.TypeScheduled,
+		CronExpr: j.Schedule,
+		Status:   models.StatusPending,
+		Priority: models.PriorityMedium,
+		Tags:     []string{TaskTagJiraExport},
+		Parameters: map[string]interface{}{
+			"environment": j.Environment,
+			"key_type":    j.KeyType,
+			"key_value":   j.KeyValue,
+		},
+	}
+}
+
+// JiraSyncJobFromRow 将配置表格的一行转换为Jira导出配置
+func JiraSyncJobFromRow(row map[string]string) (JiraSyncJob, error) {
+	job := JiraSyncJob{
+		ID:          row["id"],
+		Environment: row["environment"],
+		KeyType:     row["key_type"],
+		KeyValue:    row["key_value"],
+		Schedule:    row["schedule"],
+		Disabled:    parseBool(row["disabled"]),
+	}
+	return job, job.Validate()
+}
+
+// ReportSchedule 定时生成并发布报告的配置
+type ReportSchedule struct {
+	ID         string `json:"id"`
+	ReportType string `json:"report_type"` // reporting.report_types中的一种，如confluence或mattermost
+	Schedule   string `json:"schedule"`    // cron表达式(包含秒)
+	Disabled   bool   `json:"disabled,omitempty"`
+}
+
+// ConfigKind 实现Configuration
+func (r ReportSchedule) ConfigKind() string { return ConfigKindReportSchedule }
+
+// ConfigID 实现Configuration
+func (r ReportSchedule) ConfigID() string { return r.ID }
+
+// Validate 实现Configuration
+func (r ReportSchedule) Validate() error {
+	switch {
+	case r.ID == "":
+		return errors.New("id is required")
+	case r.ReportType == "":
+		return fmt.Errorf("report schedule %s: report_type is required", r.ID)
+	}
+	return validateSchedule(r.ID, r.Schedule)
+}
+
+// Enabled 实现TaskConfiguration
+func (r ReportSchedule) Enabled() bool { return !r.Disabled }
+
+// ScheduledTask 实现TaskConfiguration
+func (r ReportSchedule) ScheduledTask() *models.Task {
+	return &models.Task{
+		Name:     "定时报告: " + r.ReportType,
+		TaskType: models.TypeScheduled,
+		CronExpr: r.Schedule,
+		Status:   models.StatusPending,
+		Priority: models.PriorityLow,
+		Tags:     []string{TaskTagReport},
+		Parameters: map[string]interface{}{
+			"report_type": r.ReportType,
+		},
+	}
+}
+
+// ReportScheduleFromRow 将配置表格的一行转换为报告计划
+func ReportScheduleFromRow(row map[string]string) (ReportSchedule, error) {
+	schedule := ReportSchedule{
+		ID:         row["id"],
+		ReportType: row["report_type"],
+		Schedule:   row["schedule"],
+		Disabled:   parseBool(row["disabled"]),
+	}
+	return schedule, schedule.Validate()
+}
+
+// validateSchedule 校验cron表达式
+func validateSchedule(id, schedule string) error {
+	if schedule == "" {
+		return fmt.Errorf("%s: schedule is required", id)
+	}
+	if err := ValidateCronExpr(schedule); err != nil {
+		return fmt.Errorf("%s: %v", id, err)
+	}
+	return nil
+}
+
+// TaskConfiguration 由定义定时任务的配置种类实现
+type TaskConfiguration interface {
+	Configuration
+	// ScheduledTask 返回配置对应的定时任务(尚未保存)
+	ScheduledTask() *models.Task
+	// Enabled 为false时不创建任务
+	Enabled() bool
+}
+
+// configJob 由配置创建的任务
+type configJob struct {
+	taskID      string
+	fingerprint string // 配置的JSON表示，变化时重新创建任务
+}
+
+// ConfigJobSync 将jira_sync和report_schedule配置同步为调度服务中的定时任务
+//
+// 配置新增时创建任务，修改时取消旧任务并创建新任务，删除或disabled时取消任务。
+type ConfigJobSync struct {
+	scheduler *SchedulerService
+	mu        sync.Mutex
+	jobs      map[string]configJob // 种类/ID -> 任务
+}
+
+// NewConfigJobSync 创建同步器，订阅定时任务类配置的变化并立即同步当前配置
+func NewConfigJobSync(configService *ConfigurationService, scheduler *SchedulerService) *ConfigJobSync {
+	jobSync := &ConfigJobSync{
+		scheduler: scheduler,
+		jobs:      make(map[string]configJob),
+	}
+
+	kinds := []string{ConfigKindJiraSync, ConfigKindReportSchedule}
+	jobSync.Sync(configService.ConfigurationsOfKind(kinds...))
+	configService.Subscribe(jobSync.Sync, kinds...)
+
+	return jobSync
+}
+
+// Sync 使定时任务与给定的配置一致
+func (s *ConfigJobSync) Sync(configs []Configuration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	desired := make(map[string]TaskConfiguration)
+	for _, config := range configs {
+		taskConfig, ok := config.(TaskConfiguration)
+		if !ok || !taskConfig.Enabled() {
+			continue
+		}
+		desired[config.ConfigKind()+"/"+config.ConfigID()] = taskConfig
+	}
+
+	// 取消已删除、已禁用或已修改的配置的任务
+	for key, job := range s.jobs {
+		config, exists := desired[key]
+		if exists && fingerprint(config) == job.fingerprint {
+			continue
+		}
+		if err := s.scheduler.UnscheduleTask(job.taskID); err != nil {
+			log.Printf("[ConfigJobSync] Failed to unschedule task %s of %s: %v", job.taskID, key, err)
+		} else {
+			log.Printf("[ConfigJobSync] Unscheduled task %s of %s", job.taskID, key)
+		}
+		delete(s.jobs, key)
+	}
+
+	for key, config := range desired {
+		if _, exists := s.jobs[key]; exists {
+			continue
+		}
+
+		task := config.ScheduledTask()
+		task.Metadata = map[string]interface{}{
+			"config_kind": config.ConfigKind(),
+			"config_id":   config.ConfigID(),
+		}
+		if err := s.scheduler.AddTask(task); err != nil {
+			log.Printf("[ConfigJobSync] Failed to create task for %s: %v", key, err)
+			continue
+		}
+		s.jobs[key] = configJob{taskID: task.ID, fingerprint: fingerprint(config)}
+		log.Printf("[ConfigJobSync] Scheduled task %s for %s (%s)", task.ID, key, task.CronExpr)
+	}
+}
+
+// fingerprint 返回配置的JSON表示，用于判断配置是否变化
+func fingerprint(config Configuration) string {
+	data, _ := json.Marshal(config)
+	return string(data)
+}
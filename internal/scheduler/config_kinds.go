@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// 内置的配置种类
+const (
+	ConfigKindMattermost     = "mattermost"
+	ConfigKindRoutingRule    = "routing_rule"
+	ConfigKindJiraSync       = "jira_sync"
+	ConfigKindReportSchedule = "report_schedule"
+)
+
+// ConfigKindSpec 描述一种配置: 如何从JSON和配置表格的一行解析
+type ConfigKindSpec struct {
+	Kind        string
+	Description string
+	// Decode 从JSON解析配置，不允许未知字段
+	Decode func(data []byte) (Configuration, error)
+	// FromRow 从配置表格的一行(表头名到单元格文本)解析配置，为nil时该种类不能在表格中定义
+	FromRow func(row map[string]string) (Configuration, error)
+}
+
+var (
+	configKindsMu sync.RWMutex
+	configKinds   = make(map[string]ConfigKindSpec)
+	configKindSeq []string
+)
+
+// RegisterConfigKind 注册配置种类，种类重复注册时panic
+func RegisterConfigKind(spec ConfigKindSpec) {
+	configKindsMu.Lock()
+	defer configKindsMu.Unlock()
+
+	if spec.Kind == "" || spec.Decode == nil {
+		panic("scheduler: config kind requires a name and a decoder")
+	}
+	if _, exists := configKinds[spec.Kind]; exists {
+		panic("scheduler: config kind " + spec.Kind + " registered twice")
+	}
+	configKinds[spec.Kind] = spec
+	configKindSeq = append(configKindSeq, spec.Kind)
+}
+
+// LookupConfigKind 查找已注册的配置种类
+func LookupConfigKind(kind string) (ConfigKindSpec, bool) {
+	configKindsMu.RLock()
+	defer configKindsMu.RUnlock()
+	spec, ok := configKinds[kind]
+	return spec, ok
+}
+
+// ConfigKinds 按注册顺序返回所有配置种类
+func ConfigKinds() []ConfigKindSpec {
+	configKindsMu.RLock()
+	defer configKindsMu.RUnlock()
+
+	specs := make([]ConfigKindSpec, 0, len(configKindSeq))
+	for _, kind := range configKindSeq {
+		specs = append(specs, configKinds[kind])
+	}
+	return specs
+}
+
+// decodeConfiguration 按种类将JSON解码为配置
+func decodeConfiguration(kind string, data []byte) (Configuration, error) {
+	if kind == "" {
+		return nil, fmt.Errorf("kind is required")
+	}
+	spec, ok := LookupConfigKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q", kind)
+	}
+	return spec.Decode(data)
+}
+
+// strictUnmarshal 解码JSON，遇到未知字段时返回错误
+func strictUnmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func init() {
+	RegisterConfigKind(ConfigKindSpec{
+		Kind:        ConfigKindMattermost,
+		Description: "Mattermost事件转发配置",
+		Decode: func(data []byte) (Configuration, error) {
+			var config MattermostConfig
+			err := strictUnmarshal(data, &config)
+			return config, err
+		},
+		FromRow: func(row map[string]string) (Configuration, error) {
+			return MattermostConfigFromRow(row)
+		},
+	})
+	RegisterConfigKind(ConfigKindSpec{
+		Kind:        ConfigKindRoutingRule,
+		Description: "事件路由规则",
+		Decode: func(data []byte) (Configuration, error) {
+			var rule RoutingRule
+			if err := strictUnmarshal(data, &rule); err != nil {
+				return nil, err
+			}
+			if err := rule.Compile(); err != nil {
+				return nil, err
+			}
+			return rule, nil
+		},
+		FromRow: func(row map[string]string) (Configuration, error) {
+			return RoutingRuleFromRow(row)
+		},
+	})
+	RegisterConfigKind(ConfigKindSpec{
+		Kind:        ConfigKindJiraSync,
+		Description: "定时导出Jira问题",
+		Decode: func(data []byte) (Configuration, error) {
+			var job JiraSyncJob
+			err := strictUnmarshal(data, &job)
+			return job, err
+		},
+		FromRow: func(row map[string]string) (Configuration, error) {
+			return JiraSyncJobFromRow(row)
+		},
+	})
+	RegisterConfigKind(ConfigKindSpec{
+		Kind:        ConfigKindReportSchedule,
+		Description: "定时生成并发布报告",
+		Decode: func(data []byte) (Configuration, error) {
+			var schedule ReportSchedule
+			err := strictUnmarshal(data, &schedule)
+			return schedule, err
+		},
+		FromRow: func(row map[string]string) (Configuration, error) {
+			return ReportScheduleFromRow(row)
+		},
+	})
+}
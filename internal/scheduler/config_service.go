@@ -1,25 +1,83 @@
 package scheduler
 
 import (
+	"fmt"
 	"log"
+	"slices"
 	"sync"
 	"time"
 )
 
-// Configuration 定义配置接口
-type Configuration interface{}
+// Configuration 定义配置接口，每种配置在ConfigKinds中注册
+type Configuration interface {
+	// ConfigKind 返回配置的种类
+	ConfigKind() string
+	// ConfigID 返回配置在同一种类内唯一的ID
+	ConfigID() string
+	// Validate 校验配置的字段
+	Validate() error
+}
 
 // ConfigurationService 管理配置的获取和更新
 type ConfigurationService struct {
 	fetcher        ConfigurationFetcher
 	configurations []Configuration
 	mu             sync.RWMutex
+	updateMu       sync.Mutex // 串行化定时更新和数据源变化触发的更新
 	updateInterval time.Duration
 	isRunning      bool
 	stopChan       chan struct{}
 	lastUpdateTime time.Time
+	history        []*ConfigSnapshot                // 配置版本，最新的在最后
+	listeners      []func(snapshot *ConfigSnapshot) // 配置发生变化后的回调
+	gate           ConfigGate                       // 新配置生效前的校验
+	cache          *ConfigCache                     // 最近一份有效配置的磁盘缓存，可以为nil
+	lastRejection  *ConfigRejection                 // 最近一次被拒绝的配置
+	lastRefresh    *RefreshResult                   // 最近一次获取配置的结果
+	debounce       time.Duration                    // 两次手动刷新之间的最小间隔
+	refreshTimer   *time.Timer                      // RequestRefresh的延迟刷新
+}
+
+// 配置刷新的触发来源
+const (
+	RefreshTriggerStartup  = "startup"
+	RefreshTriggerInterval = "interval"
+	RefreshTriggerWatch    = "watch"
+	RefreshTriggerManual   = "manual"
+	RefreshTriggerWebhook  = "webhook"
+)
+
+// defaultRefreshDebounce 默认的刷新防抖间隔
+const defaultRefreshDebounce = 5 * time.Second
+
+// RefreshResult 一次获取配置的结果
+type RefreshResult struct {
+	Time      time.Time      `json:"time"`
+	Trigger   string         `json:"trigger"`
+	Version   int            `json:"version"` // 获取后的当前版本
+	Changed   bool           `json:"changed"` // 是否产生了新版本
+	Changes   []ConfigChange `json:"changes"`
+	Forced    bool           `json:"forced,omitempty"`    // 强制刷新，跳过了删除比例检查
+	Rejected  bool           `json:"rejected,omitempty"`  // 未通过校验闸门
+	Error     string         `json:"error,omitempty"`     // 获取失败或被拒绝的原因
+	Debounced bool           `json:"debounced,omitempty"` // 距上次获取不足防抖间隔，返回的是上次的结果
+}
+
+// Summary 按变更类型统计数量
+func (r *RefreshResult) Summary() map[string]int {
+	return (&ConfigSnapshot{Changes: r.Changes}).Summary()
 }
 
+// ConfigRejection 记录一次未通过校验闸门的配置获取
+type ConfigRejection struct {
+	Time   time.Time `json:"time"`
+	Count  int       `json:"count"` // 获取到的配置项数量
+	Reason string    `json:"reason"`
+}
+
+// maxConfigHistory 保留的配置版本数量
+const maxConfigHistory = 50
+
 // ConfigurationFetcher 定义配置获取接口
 type ConfigurationFetcher interface {
 	// FetchConfigurations 从数据源获取配置
@@ -32,11 +90,49 @@ func NewConfigurationService(fetcher ConfigurationFetcher, updateInterval time.D
 		fetcher:        fetcher,
 		configurations: make([]Configuration, 0),
 		updateInterval: updateInterval,
+		debounce:       defaultRefreshDebounce,
 		isRunning:      false,
 		stopChan:       make(chan struct{}),
 	}
 }
 
+// SetDebounce 设置刷新防抖间隔: 间隔内的手动刷新返回上次的结果，webhook触发的刷新合并为一次
+func (s *ConfigurationService) SetDebounce(debounce time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.debounce = debounce
+}
+
+// SetGate 设置新配置生效前的校验阈值
+func (s *ConfigurationService) SetGate(gate ConfigGate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate = gate
+}
+
+// EnableCache 启用最近有效配置的磁盘缓存，并立即加载缓存的配置作为初始版本，
+// 使数据源不可用时服务仍以上次的配置启动。应在Start之前调用。
+func (s *ConfigurationService) EnableCache(cache *ConfigCache) error {
+	s.mu.Lock()
+	s.cache = cache
+	s.mu.Unlock()
+
+	configs, savedAt, err := cache.Load()
+	if err != nil {
+		return err
+	}
+	if configs == nil {
+		return nil
+	}
+	if err := ValidateConfigurations(configs); err != nil {
+		return fmt.Errorf("cached configurations are invalid: %v", err)
+	}
+
+	log.Printf("[ConfigurationService] Loaded %d cached configurations saved at %s", len(configs), savedAt.Format(time.RFC3339))
+	s.applyConfigurations(configs, true)
+	return nil
+}
+
 // Start 启动配置服务
 func (s *ConfigurationService) Start() {
 	s.mu.Lock()
@@ -50,10 +146,18 @@ func (s *ConfigurationService) Start() {
 	log.Println("[ConfigurationService] Starting configuration service")
 
 	// 立即获取一次配置
-	s.updateConfigurations()
+	s.updateConfigurations(RefreshTriggerStartup)
 
 	// 启动定时更新
 	go s.runUpdateLoop()
+
+	// 数据源支持监听时，变化后立即更新
+	if watcher, ok := s.fetcher.(ConfigWatcher); ok {
+		onChange := func() { s.updateConfigurations(RefreshTriggerWatch) }
+		if err := watcher.Watch(s.stopChan, onChange); err != nil {
+			log.Printf("[ConfigurationService] Failed to watch configuration source, relying on periodic updates: %v", err)
+		}
+	}
 }
 
 // Stop 停止配置服务
@@ -70,6 +174,88 @@ func (s *ConfigurationService) Stop() {
 	log.Println("[ConfigurationService] Stopping configuration service")
 }
 
+// AddChangeListener 添加配置发生变化(包括首次加载)后的回调，回调参数为新的配置版本
+func (s *ConfigurationService) AddChangeListener(listener func(snapshot *ConfigSnapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// CurrentSnapshot 返回当前的配置版本，尚未加载配置时返回nil
+func (s *ConfigurationService) CurrentSnapshot() *ConfigSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.currentSnapshotLocked()
+}
+
+// currentSnapshotLocked 返回当前的配置版本，调用方需持有锁
+func (s *ConfigurationService) currentSnapshotLocked() *ConfigSnapshot {
+	if len(s.history) == 0 {
+		return nil
+	}
+	return s.history[len(s.history)-1]
+}
+
+// History 返回保留的配置版本，最新的在前
+func (s *ConfigurationService) History() []*ConfigSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*ConfigSnapshot, len(s.history))
+	for i, snapshot := range s.history {
+		result[len(s.history)-1-i] = snapshot
+	}
+	return result
+}
+
+// ConfigurationsOfKind 返回当前配置中属于指定种类的配置，按获取顺序排列
+func (s *ConfigurationService) ConfigurationsOfKind(kinds ...string) []Configuration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return filterKinds(s.configurations, kinds)
+}
+
+// GetConfiguration 按种类和ID查找当前配置
+func (s *ConfigurationService) GetConfiguration(kind, id string) (Configuration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, config := range s.configurations {
+		if config.ConfigKind() == kind && config.ConfigID() == id {
+			return config, true
+		}
+	}
+	return nil, false
+}
+
+// Subscribe 订阅指定种类的配置: 首次加载以及这些种类的配置发生变化时，
+// 以这些种类的全部当前配置调用listener，其他种类的变化不会触发
+func (s *ConfigurationService) Subscribe(listener func(configs []Configuration), kinds ...string) {
+	s.AddChangeListener(func(snapshot *ConfigSnapshot) {
+		changed := snapshot.Version == 1
+		for _, change := range snapshot.Changes {
+			if slices.Contains(kinds, change.Kind) {
+				changed = true
+				break
+			}
+		}
+		if changed {
+			listener(filterKinds(snapshot.Configurations, kinds))
+		}
+	})
+}
+
+// filterKinds 返回属于指定种类的配置
+func filterKinds(configs []Configuration, kinds []string) []Configuration {
+	result := make([]Configuration, 0, len(configs))
+	for _, config := range configs {
+		if slices.Contains(kinds, config.ConfigKind()) {
+			result = append(result, config)
+		}
+	}
+	return result
+}
+
 // GetCurrentConfigurations 获取当前配置
 func (s *ConfigurationService) GetCurrentConfigurations() []Configuration {
 	s.mu.RLock()
@@ -82,6 +268,13 @@ func (s *ConfigurationService) GetCurrentConfigurations() []Configuration {
 	return result
 }
 
+// LastRejection 返回最近一次被校验闸门拒绝的配置获取，没有时返回nil
+func (s *ConfigurationService) LastRejection() *ConfigRejection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastRejection
+}
+
 // GetLastUpdateTime 获取上次更新时间
 func (s *ConfigurationService) GetLastUpdateTime() time.Time {
 	s.mu.RLock()
@@ -89,20 +282,171 @@ func (s *ConfigurationService) GetLastUpdateTime() time.Time {
 	return s.lastUpdateTime
 }
 
-// 更新配置
-func (s *ConfigurationService) updateConfigurations() {
+// Refresh 立即获取配置并返回变更摘要，trigger记录触发来源(如manual)
+//
+// 距上次获取不足防抖间隔时不再获取，直接返回上次的结果(Debounced为true)。
+func (s *ConfigurationService) Refresh(trigger string) *RefreshResult {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	s.mu.RLock()
+	last, debounce := s.lastRefresh, s.debounce
+	s.mu.RUnlock()
+
+	if last != nil && time.Since(last.Time) < debounce {
+		result := *last
+		result.Debounced = true
+		return &result
+	}
+	return s.update(trigger, false)
+}
+
+// ForceRefresh 立即获取配置，不受防抖间隔限制，并跳过删除比例检查
+//
+// 用于管理员确认大量删除配置是有意为之: 删除比例检查会拒绝之后的每一次获取，
+// 重启也会从缓存加载旧配置，只能通过强制刷新接受新配置。配置项的校验和行错误比例检查仍然有效。
+func (s *ConfigurationService) ForceRefresh(trigger string) *RefreshResult {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	return s.update(trigger, true)
+}
+
+// RequestRefresh 在防抖间隔后异步获取配置，间隔内的多次请求合并为一次
+func (s *ConfigurationService) RequestRefresh(trigger string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refreshTimer != nil {
+		s.refreshTimer.Reset(s.debounce)
+		return
+	}
+	s.refreshTimer = time.AfterFunc(s.debounce, func() {
+		s.mu.Lock()
+		s.refreshTimer = nil
+		s.mu.Unlock()
+		s.updateConfigurations(trigger)
+	})
+}
+
+// LastRefresh 返回最近一次获取配置的结果，尚未获取时返回nil
+func (s *ConfigurationService) LastRefresh() *RefreshResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastRefresh
+}
+
+// updateConfigurations 获取并更新配置
+func (s *ConfigurationService) updateConfigurations(trigger string) *RefreshResult {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	return s.update(trigger, false)
+}
+
+// update 获取配置，通过校验后生效，force为true时跳过删除比例检查，调用方需持有updateMu
+func (s *ConfigurationService) update(trigger string, force bool) *RefreshResult {
+	result := &RefreshResult{Time: time.Now(), Trigger: trigger, Forced: force}
+	defer func() {
+		s.mu.Lock()
+		if snapshot := s.currentSnapshotLocked(); snapshot != nil {
+			result.Version = snapshot.Version
+		}
+		s.lastRefresh = result
+		s.mu.Unlock()
+	}()
+
 	configs, err := s.fetcher.FetchConfigurations()
 	if err != nil {
-		log.Printf("[ConfigurationService] Failed to fetch configurations: %v", err)
-		return
+		log.Printf("[ConfigurationService] Failed to fetch configurations (%s): %v", trigger, err)
+		result.Error = err.Error()
+		return result
+	}
+
+	log.Printf("[ConfigurationService] Fetched %d configurations (%s)", len(configs), trigger)
+
+	rowErrors := 0
+	if counter, ok := s.fetcher.(RowErrorCounter); ok {
+		rowErrors = counter.RowErrorCount()
 	}
 
-	log.Printf("[ConfigurationService] Fetched %d configurations", len(configs))
+	s.mu.Lock()
+	current := s.configurations
+	gate := s.gate
+	s.mu.Unlock()
+
+	// 删除比例只相对当前配置计算，强制刷新时不与当前配置比较
+	baseline := current
+	if force {
+		log.Printf("[ConfigurationService] Forced refresh (%s), skipping the drop ratio check", trigger)
+		baseline = nil
+	}
+
+	// 未通过校验的配置不生效，继续使用当前(最近一份有效的)配置
+	if err := gate.Check(baseline, configs, rowErrors); err != nil {
+		log.Printf("[ConfigurationService] Rejected fetched configurations, keeping last known good: %v", err)
+		s.mu.Lock()
+		s.lastRejection = &ConfigRejection{Time: time.Now(), Count: len(configs), Reason: err.Error()}
+		s.mu.Unlock()
+		result.Error = err.Error()
+		result.Rejected = true
+		return result
+	}
+
+	if snapshot := s.applyConfigurations(configs, false); snapshot != nil {
+		result.Changed = true
+		result.Changes = snapshot.Changes
+	}
+	return result
+}
 
+// applyConfigurations 将配置设为当前配置，有变化时生成新版本、更新缓存并通知监听者，
+// 返回新版本，没有变化时返回nil
+func (s *ConfigurationService) applyConfigurations(configs []Configuration, fromCache bool) *ConfigSnapshot {
 	s.mu.Lock()
+	changes := DiffConfigurations(s.configurations, configs)
 	s.configurations = configs
 	s.lastUpdateTime = time.Now()
+	s.lastRejection = nil
+
+	// 配置没有变化时不产生新版本
+	if len(changes) == 0 && len(s.history) > 0 {
+		s.mu.Unlock()
+		return nil
+	}
+
+	version := 1
+	if len(s.history) > 0 {
+		version = s.history[len(s.history)-1].Version + 1
+	}
+	snapshot := &ConfigSnapshot{
+		Version:        version,
+		Time:           s.lastUpdateTime,
+		Configurations: append([]Configuration(nil), configs...),
+		Changes:        changes,
+		FromCache:      fromCache,
+	}
+	s.history = append(s.history, snapshot)
+	if len(s.history) > maxConfigHistory {
+		s.history = s.history[len(s.history)-maxConfigHistory:]
+	}
+	listeners := make([]func(snapshot *ConfigSnapshot), len(s.listeners))
+	copy(listeners, s.listeners)
+	cache := s.cache
 	s.mu.Unlock()
+
+	if cache != nil && !fromCache {
+		if err := cache.Save(configs); err != nil {
+			log.Printf("[ConfigurationService] Failed to save configuration cache: %v", err)
+		}
+	}
+
+	summary := snapshot.Summary()
+	log.Printf("[ConfigurationService] Configuration version %d: %d added, %d removed, %d modified",
+		version, summary[ConfigChangeAdded], summary[ConfigChangeRemoved], summary[ConfigChangeModified])
+
+	for _, listener := range listeners {
+		listener(snapshot)
+	}
+	return snapshot
 }
 
 // 运行更新循环
@@ -113,7 +457,7 @@ func (s *ConfigurationService) runUpdateLoop() {
 	for {
 		select {
 		case <-ticker.C:
-			s.updateConfigurations()
+			s.updateConfigurations(RefreshTriggerInterval)
 		case <-s.stopChan:
 			return
 		}
@@ -5,8 +5,12 @@ import (
 	"log"
 	"my-scheduler-go/internal/mattermost"
 	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // PostedMessageProcessor 处理新发布的消息
@@ -64,7 +68,7 @@ func (p *PostedMessageProcessor) ProcessEvent(event *mattermost.Event) (*models.
 
 	// 分析消息内容，查找任务相关信息
 	// 这里可以添加特定的业务逻辑，根据消息内容创建不同类型的任务
-	taskType := determineTaskType(message)
+	taskType, cronExpr := determineSchedule(message)
 	priority := determinePriority(message)
 
 	// 创建任务参数
@@ -73,6 +77,9 @@ func (p *PostedMessageProcessor) ProcessEvent(event *mattermost.Event) (*models.
 		"channel_id":      channelID,
 		"message":         message,
 		"user_id":         userID,
+		"post_id":         event.Post.ID,
+		"root_id":         event.Post.RootID,
+		"file_ids":        event.Post.FileIDs,
 		"original_post":   event.Post,
 		"processing_time": time.Now().Format(time.RFC3339),
 	}
@@ -90,7 +97,8 @@ func (p *PostedMessageProcessor) ProcessEvent(event *mattermost.Event) (*models.
 	// 创建任务
 	task := &models.Task{
 		Name:       fmt.Sprintf("处理消息: %s", truncateString(message, 30)),
-		TaskType:   models.TaskType(taskType),
+		TaskType:   taskType,
+		CronExpr:   cronExpr,
 		Status:     models.StatusPending,
 		Priority:   models.TaskPriority(priority),
 		Tags:       []string{"MATTERMOST", "MESSAGE"},
@@ -143,18 +151,303 @@ func (p *UserAddedProcessor) ProcessEvent(event *mattermost.Event) (*models.Task
 	return task, nil
 }
 
+// CommandReplier 在消息线程中回复命令的处理结果
+type CommandReplier interface {
+	SendReply(channelID, rootID, message string) (*mattermost.Post, error)
+}
+
+// taskCommandUsage 任务命令的用法说明
+const taskCommandUsage = "Usage: `task: <name> [cron: \"0 0 9 * * *\"] [priority: high] [tags: a,b] [depends: <task-id>] [param.key=value]`"
+
+// TaskCommandProcessor 将 "task: ..." 形式的消息解析为任务，并在消息线程中回复结果
+type TaskCommandProcessor struct {
+	repo    repository.TaskRepository
+	replier CommandReplier
+}
+
+// NewTaskCommandProcessor 创建任务命令处理器
+func NewTaskCommandProcessor(repo repository.TaskRepository, replier CommandReplier) *TaskCommandProcessor {
+	return &TaskCommandProcessor{
+		repo:    repo,
+		replier: replier,
+	}
+}
+
+// ShouldProcess 判断是否处理事件
+func (p *TaskCommandProcessor) ShouldProcess(event *mattermost.Event) bool {
+	return event.Type == mattermost.EventTypePosted && event.Post != nil && IsTaskCommand(event.Post.Message)
+}
+
+// Standalone 任务命令创建的任务与配置无关，每条消息只处理一次
+func (p *TaskCommandProcessor) Standalone() bool {
+	return true
+}
+
+// ProcessEvent 处理事件，命令无效时回复错误原因且不创建任务；创建成功的确认在任务保存后发送(见TaskAdded)
+func (p *TaskCommandProcessor) ProcessEvent(event *mattermost.Event) (*models.Task, error) {
+	post := event.Post
+	log.Printf("[TaskCommandProcessor] Processing command from post %s", post.ID)
+
+	task, err := ParseTaskCommand(post.Message)
+	if err == nil {
+		err = ValidateDependencies(p.repo, task)
+	}
+	if err != nil {
+		p.reply(post, fmt.Sprintf("Unable to create task: %v\n\n%s", err, taskCommandUsage))
+		return nil, nil
+	}
+
+	username := ""
+	if event.User != nil {
+		username = event.User.Username
+	}
+
+	task.ID = uuid.New().String()
+	task.Owner = username
+	task.CreatedAt = time.Now()
+	task.Tags = append(task.Tags, "MATTERMOST_COMMAND")
+	task.Metadata = map[string]interface{}{
+		"source":     "mattermost_message",
+		"channel_id": post.ChannelID,
+		"post_id":    post.ID,
+		"user_id":    post.UserID,
+	}
+
+	// 记录源消息，用于在线程中回复任务状态
+	for key, value := range map[string]interface{}{
+		"channel_id": post.ChannelID,
+		"post_id":    post.ID,
+		"root_id":    post.RootID,
+	} {
+		if _, exists := task.Parameters[key]; !exists {
+			task.Parameters[key] = value
+		}
+	}
+
+	return task, nil
+}
+
+// TaskAdded 任务保存后在线程中确认，保存失败时回复失败原因
+func (p *TaskCommandProcessor) TaskAdded(event *mattermost.Event, task *models.Task, err error) {
+	if err != nil {
+		p.reply(event.Post, fmt.Sprintf("Unable to create task: %v", err))
+		return
+	}
+
+	text := fmt.Sprintf("Created %s task **%s** (`%s`) with priority %s",
+		strings.ToLower(string(task.TaskType)), task.Name, task.ID, strings.ToLower(string(task.Priority)))
+	if task.CronExpr != "" {
+		text += fmt.Sprintf(", schedule `%s`", task.CronExpr)
+	}
+	if len(task.Dependencies) > 0 {
+		text += fmt.Sprintf(", depends on `%s`", strings.Join(task.Dependencies, "`, `"))
+	}
+	p.reply(event.Post, text+".")
+}
+
+// reply 在源消息所在线程中回复
+func (p *TaskCommandProcessor) reply(post *mattermost.Post, message string) {
+	if p.replier == nil {
+		return
+	}
+	if _, err := p.replier.SendReply(post.ChannelID, post.ThreadRootID(), message); err != nil {
+		log.Printf("[TaskCommandProcessor] Failed to reply to post %s: %v", post.ID, err)
+	}
+}
+
+// PostEditedProcessor 处理消息编辑事件：更新尚未执行的任务，或编辑已转发的消息
+type PostEditedProcessor struct {
+	repo      repository.TaskRepository
+	postIndex *PostTaskIndex
+}
+
+// NewPostEditedProcessor 创建消息编辑事件处理器
+func NewPostEditedProcessor(repo repository.TaskRepository, postIndex *PostTaskIndex) *PostEditedProcessor {
+	return &PostEditedProcessor{
+		repo:      repo,
+		postIndex: postIndex,
+	}
+}
+
+// ShouldProcess 判断是否处理事件
+func (p *PostEditedProcessor) ShouldProcess(event *mattermost.Event) bool {
+	return event.Type == mattermost.EventTypePostEdited && event.Post != nil
+}
+
+// ProcessEvent 处理事件，只返回第一个同步编辑任务；事件源通过ProcessEventTasks获取全部任务
+func (p *PostEditedProcessor) ProcessEvent(event *mattermost.Event) (*models.Task, error) {
+	tasks, err := p.ProcessEventTasks(event)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return tasks[0], nil
+}
+
+// ProcessEventTasks 更新尚未执行的任务，并为每个已完成转发的任务创建一个同步编辑任务，
+// 使每个任务按自己的参数(目标频道、转发格式)重新生成转发内容
+func (p *PostEditedProcessor) ProcessEventTasks(event *mattermost.Event) ([]*models.Task, error) {
+	log.Printf("[PostEditedProcessor] Processing edit of post %s", event.Post.ID)
+
+	var followUps []*models.Task
+	for _, task := range findPostTasks(p.repo, p.postIndex, event.Post.ID) {
+		switch {
+		case isWaitingTask(task):
+			// 任务尚未执行，在仓库锁内确认状态后替换参数，执行器可能同时在读取原参数
+			_, err := p.repo.UpdateTaskIfStatus(task.ID, waitingStatuses, func(stored *models.Task) {
+				params := copyParameters(stored.Parameters)
+				params["message"] = event.Post.Message
+				params["original_post"] = event.Post
+				stored.Parameters = params
+			})
+			if err != nil {
+				log.Printf("[PostEditedProcessor] Failed to update task %s: %v", task.ID, err)
+			} else {
+				log.Printf("[PostEditedProcessor] Updated message of pending task %s", task.ID)
+			}
+		case task.Status == models.StatusDone:
+			if postID, ok := task.ExecutionResult["forwarded_post_id"].(string); ok {
+				followUps = append(followUps, newEditForwardTask(event, task, postID))
+			}
+		default:
+			log.Printf("[PostEditedProcessor] Task %s is %s, edit not applied", task.ID, task.Status)
+		}
+	}
+	return followUps, nil
+}
+
+// newEditForwardTask 创建修改已转发消息的任务，沿用原任务的参数，以便按相同格式重新生成转发内容
+func newEditForwardTask(event *mattermost.Event, source *models.Task, forwardedPostID string) *models.Task {
+	params := copyParameters(source.Parameters)
+	params["event_type"] = string(event.Type)
+	params["message"] = event.Post.Message
+	params["original_post"] = event.Post
+	params["forward_type"] = "edit_forward"
+	params["forwarded_post_ids"] = []string{forwardedPostID}
+
+	return &models.Task{
+		Name:       fmt.Sprintf("同步编辑消息: %s", truncateString(event.Post.Message, 30)),
+		TaskType:   models.TypeImmediate,
+		Status:     models.StatusPending,
+		Priority:   models.PriorityHigh,
+		Tags:       []string{"MATTERMOST", "POST_EDITED"},
+		Parameters: params,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// TaskCanceller 取消任务，同时将其移出执行队列并删除定时调度(由SchedulerService实现)
+type TaskCanceller interface {
+	CancelTask(id string) (*models.Task, error)
+}
+
+// PostDeletedProcessor 处理消息删除事件：取消尚未执行的任务，或删除已转发的消息
+type PostDeletedProcessor struct {
+	repo      repository.TaskRepository
+	postIndex *PostTaskIndex
+	canceller TaskCanceller
+}
+
+// NewPostDeletedProcessor 创建消息删除事件处理器，任务通过canceller取消，以免定时任务继续触发
+func NewPostDeletedProcessor(repo repository.TaskRepository, postIndex *PostTaskIndex, canceller TaskCanceller) *PostDeletedProcessor {
+	return &PostDeletedProcessor{
+		repo:      repo,
+		postIndex: postIndex,
+		canceller: canceller,
+	}
+}
+
+// ShouldProcess 判断是否处理事件
+func (p *PostDeletedProcessor) ShouldProcess(event *mattermost.Event) bool {
+	return event.Type == mattermost.EventTypePostDeleted && event.Post != nil
+}
+
+// ProcessEvent 处理事件
+func (p *PostDeletedProcessor) ProcessEvent(event *mattermost.Event) (*models.Task, error) {
+	log.Printf("[PostDeletedProcessor] Processing deletion of post %s", event.Post.ID)
+
+	var forwardedPostIDs []string
+	for _, task := range findPostTasks(p.repo, p.postIndex, event.Post.ID) {
+		switch {
+		case isWaitingTask(task):
+			// 任务尚未执行，直接取消(定时任务同时删除调度)
+			if _, err := p.canceller.CancelTask(task.ID); err != nil {
+				log.Printf("[PostDeletedProcessor] Failed to cancel task %s: %v", task.ID, err)
+			} else {
+				log.Printf("[PostDeletedProcessor] Cancelled task %s", task.ID)
+			}
+		case task.Status == models.StatusDone:
+			if postID, ok := task.ExecutionResult["forwarded_post_id"].(string); ok {
+				forwardedPostIDs = append(forwardedPostIDs, postID)
+			}
+		default:
+			log.Printf("[PostDeletedProcessor] Task %s is %s, deletion not applied", task.ID, task.Status)
+		}
+	}
+	p.postIndex.Remove(event.Post.ID)
+
+	if len(forwardedPostIDs) == 0 {
+		return nil, nil
+	}
+
+	params := map[string]interface{}{
+		"event_type":         string(event.Type),
+		"channel_id":         event.Post.ChannelID,
+		"post_id":            event.Post.ID,
+		"forward_type":       "delete_forward",
+		"forwarded_post_ids": forwardedPostIDs,
+	}
+
+	return &models.Task{
+		Name:       fmt.Sprintf("同步删除消息: %s", event.Post.ID),
+		TaskType:   models.TypeImmediate,
+		Status:     models.StatusPending,
+		Priority:   models.PriorityHigh,
+		Tags:       []string{"MATTERMOST", "POST_DELETED"},
+		Parameters: params,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
 // 辅助函数
 
-// determineTaskType 根据消息决定任务类型
-func determineTaskType(message string) string {
-	// 查找调度标记
-	if strings.Contains(strings.ToLower(message), "schedule:") ||
-		strings.Contains(strings.ToLower(message), "cron:") {
-		return string(models.TypeScheduled)
+// findPostTasks 查找由源消息创建的任务
+func findPostTasks(repo repository.TaskRepository, postIndex *PostTaskIndex, postID string) []*models.Task {
+	var tasks []*models.Task
+	for _, taskID := range postIndex.TaskIDs(postID) {
+		task, err := repo.GetTaskByID(taskID)
+		if err != nil {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// waitingStatuses 任务尚未开始执行的状态
+var waitingStatuses = []models.TaskStatus{models.StatusPending, models.StatusQueued, models.StatusScheduled, models.StatusAwaitingApproval}
+
+// isWaitingTask 判断任务是否尚未开始执行
+func isWaitingTask(task *models.Task) bool {
+	return slices.Contains(waitingStatuses, task.Status)
+}
+
+// copyParameters 复制任务参数，避免修改其他goroutine可能正在读取的map
+func copyParameters(params map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(params)+1)
+	for key, value := range params {
+		copied[key] = value
+	}
+	return copied
+}
+
+// determineSchedule 根据消息决定任务类型，消息中带有有效的 cron:/schedule: 表达式时为定时任务
+func determineSchedule(message string) (models.TaskType, string) {
+	if cronExpr, ok := extractCronExpr(message); ok {
+		return models.TypeScheduled, cronExpr
 	}
 
 	// 默认为即时任务
-	return string(models.TypeImmediate)
+	return models.TypeImmediate, ""
 }
 
 // determinePriority 根据消息决定优先级
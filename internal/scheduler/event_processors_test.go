@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+)
+
+// 删除源消息时，由它创建的定时任务必须被取消并删除定时调度，否则会继续按cron触发
+func TestPostDeletedCancelsScheduledTask(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	sched := NewSchedulerService(repo, NewTaskExecutor(repo), time.Hour)
+	postIndex := NewPostTaskIndex()
+
+	task := &models.Task{
+		Name:       "forward",
+		TaskType:   models.TypeScheduled,
+		CronExpr:   "0 0 2 * * *",
+		Status:     models.StatusPending,
+		Parameters: map[string]interface{}{},
+	}
+	if err := sched.AddTask(task); err != nil {
+		t.Fatalf("add task: %v", err)
+	}
+	postIndex.Add("post-1", task.ID)
+	if _, scheduled := sched.cronJobs[task.ID]; !scheduled {
+		t.Fatalf("task %s has no cron job", task.ID)
+	}
+
+	processor := NewPostDeletedProcessor(repo, postIndex, sched)
+	event := &mattermost.Event{
+		Type: mattermost.EventTypePostDeleted,
+		Post: &mattermost.Post{ID: "post-1", ChannelID: "ch-ops"},
+	}
+	if !processor.ShouldProcess(event) {
+		t.Fatal("processor should handle post deletions")
+	}
+	if followUp, err := processor.ProcessEvent(event); err != nil || followUp != nil {
+		t.Fatalf("ProcessEvent = %v, %v; want no follow-up task", followUp, err)
+	}
+
+	if _, scheduled := sched.cronJobs[task.ID]; scheduled {
+		t.Error("cron job of the cancelled task is still registered")
+	}
+	if len(sched.cron.Entries()) != 0 {
+		t.Errorf("cron has %d entries, want 0", len(sched.cron.Entries()))
+	}
+	if got, _ := repo.GetTaskByID(task.ID); got.Status != models.StatusCancelled {
+		t.Errorf("status = %s, want %s", got.Status, models.StatusCancelled)
+	}
+	if ids := postIndex.TaskIDs("post-1"); len(ids) != 0 {
+		t.Errorf("post index still maps post-1 to %v", ids)
+	}
+}
+
+// editEvent 返回源消息post-1被编辑的事件
+func editEvent(message string) *mattermost.Event {
+	return &mattermost.Event{
+		Type: mattermost.EventTypePostEdited,
+		Post: &mattermost.Post{ID: "post-1", ChannelID: "ch-ops", Message: message},
+	}
+}
+
+// 同一条源消息被多个配置转发时，每个已完成的转发任务各生成一个同步编辑任务，沿用各自的目标频道
+func TestPostEditedCreatesEditTaskPerForwardedTask(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	postIndex := NewPostTaskIndex()
+	for i, target := range []string{"ch-archive", "ch-oncall"} {
+		task := &models.Task{
+			Name:            "forward to " + target,
+			Status:          models.StatusDone,
+			Parameters:      map[string]interface{}{"target_channel_id": target, "message": "disk full"},
+			ExecutionResult: map[string]interface{}{"forwarded_post_id": fmt.Sprintf("fwd-%d", i+1)},
+		}
+		if err := repo.AddTask(task); err != nil {
+			t.Fatal(err)
+		}
+		postIndex.Add("post-1", task.ID)
+	}
+
+	tasks, err := NewPostEditedProcessor(repo, postIndex).ProcessEventTasks(editEvent("disk full again"))
+	if err != nil {
+		t.Fatalf("ProcessEventTasks: %v", err)
+	}
+	got := make(map[string]string)
+	for _, task := range tasks {
+		ids, _ := task.Parameters["forwarded_post_ids"].([]string)
+		if len(ids) != 1 || task.Parameters["forward_type"] != "edit_forward" || task.Parameters["message"] != "disk full again" {
+			t.Errorf("edit task parameters = %v", task.Parameters)
+			continue
+		}
+		got[ids[0]] = task.Parameters["target_channel_id"].(string)
+	}
+	want := map[string]string{"fwd-1": "ch-archive", "fwd-2": "ch-oncall"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("edit tasks = %v, want %v", got, want)
+	}
+}
+
+// 编辑尚未执行的任务时替换参数map而不是修改原map，执行器开始执行后编辑不再生效
+func TestPostEditedUpdatesWaitingTaskAtomically(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	executor := NewTaskExecutor(repo)
+	postIndex := NewPostTaskIndex()
+	processor := NewPostEditedProcessor(repo, postIndex)
+
+	started, release := make(chan string, 1), make(chan struct{})
+	executor.RegisterHandler("MATTERMOST", func(task *models.Task) error {
+		started <- task.Parameters["message"].(string)
+		<-release
+		return nil
+	})
+
+	original := map[string]interface{}{"message": "deploy at 10"}
+	task := &models.Task{Name: "forward", Status: models.StatusQueued, Tags: []string{"MATTERMOST"}, Parameters: original}
+	if err := repo.AddTask(task); err != nil {
+		t.Fatal(err)
+	}
+	postIndex.Add("post-1", task.ID)
+
+	// 排队中的任务使用编辑后的内容执行，执行器可能仍持有的原参数map保持不变
+	if tasks, err := processor.ProcessEventTasks(editEvent("deploy at 11")); err != nil || len(tasks) != 0 {
+		t.Fatalf("ProcessEventTasks = %v, %v; want no follow-up task", tasks, err)
+	}
+	if original["message"] != "deploy at 10" {
+		t.Errorf("original parameters were modified in place: %v", original)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- executor.ExecuteTask(task) }()
+	if message := <-started; message != "deploy at 11" {
+		t.Errorf("handler saw message %q, want the edited message", message)
+	}
+
+	// 任务运行中时编辑不再修改参数
+	if _, err := processor.ProcessEventTasks(editEvent("deploy at 12")); err != nil {
+		t.Fatalf("ProcessEventTasks: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	stored, _ := repo.GetTaskByID(task.ID)
+	if stored.Status != models.StatusDone || stored.Parameters["message"] != "deploy at 11" {
+		t.Errorf("task = %s %v, want DONE with the message it ran with", stored.Status, stored.Parameters)
+	}
+
+	// 已开始执行的任务不能再次执行
+	if err := executor.ExecuteTask(stored); !errors.Is(err, repository.ErrTaskStatusChanged) {
+		t.Errorf("second ExecuteTask error = %v, want ErrTaskStatusChanged", err)
+	}
+}
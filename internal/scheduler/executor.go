@@ -12,11 +12,16 @@ import (
 // TaskHandler 定义任务处理函数类型
 type TaskHandler func(task *models.Task) error
 
+// TaskStatusListener 在任务状态变化后被调用
+type TaskStatusListener func(task *models.Task)
+
 // TaskExecutor 负责执行任务的组件
 type TaskExecutor struct {
 	repo         repository.TaskRepository
 	handlerMutex sync.RWMutex
 	taskHandlers map[string]TaskHandler // 通过标签映射到处理函数
+	listenerMu   sync.RWMutex
+	listeners    []TaskStatusListener
 }
 
 // NewTaskExecutor 创建新的任务执行器
@@ -35,23 +40,43 @@ func (e *TaskExecutor) RegisterHandler(tag string, handler TaskHandler) {
 	log.Printf("[TaskExecutor] Registered handler for tag: %s", tag)
 }
 
+// AddStatusListener 注册任务状态变化监听器
+func (e *TaskExecutor) AddStatusListener(listener TaskStatusListener) {
+	e.listenerMu.Lock()
+	defer e.listenerMu.Unlock()
+	e.listeners = append(e.listeners, listener)
+}
+
+// notifyStatus 通知所有监听器任务状态已变化
+func (e *TaskExecutor) notifyStatus(task *models.Task) {
+	e.listenerMu.RLock()
+	listeners := make([]TaskStatusListener, len(e.listeners))
+	copy(listeners, e.listeners)
+	e.listenerMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(task)
+	}
+}
+
+// executableStatuses 可以开始执行的任务状态
+var executableStatuses = []models.TaskStatus{models.StatusPending, models.StatusQueued, models.StatusRetry}
+
 // ExecuteTask 执行单个任务
 func (e *TaskExecutor) ExecuteTask(task *models.Task) error {
 	log.Printf("[TaskExecutor] Executing task '%s' (ID: %s)", task.Name, task.ID)
 
-	// 已存在的执行器代码...
-	if task.Status != models.StatusPending && task.Status != models.StatusRetry {
-		return fmt.Errorf("task not in executable state: %s", task.Status)
-	}
-
-	// 更新任务状态
-	task.Status = models.StatusRunning
-	task.StartTime = time.Now()
-	if err := e.repo.UpdateTask(task); err != nil {
-		return err
+	// 在仓库锁内检查状态并标记为运行中，与编辑事件对等待中任务参数的更新互斥
+	task, err := e.repo.UpdateTaskIfStatus(task.ID, executableStatuses, func(stored *models.Task) {
+		stored.Status = models.StatusRunning
+		stored.StartTime = time.Now()
+		stored.ExecutionResult = make(map[string]interface{}) // 处理器可在执行过程中写入结果
+	})
+	if err != nil {
+		return fmt.Errorf("task not in executable state: %w", err)
 	}
+	e.notifyStatus(task)
 
-	var err error
 	var result string
 
 	// 首先查找匹配的处理器
@@ -72,9 +97,7 @@ func (e *TaskExecutor) ExecuteTask(task *models.Task) error {
 
 	// 更新任务结果
 	task.EndTime = time.Now()
-	task.ExecutionResult = map[string]interface{}{
-		"result": result,
-	}
+	task.ExecutionResult["result"] = result
 	task.Status = models.StatusDone
 
 	if err != nil {
@@ -92,7 +115,12 @@ func (e *TaskExecutor) ExecuteTask(task *models.Task) error {
 	}
 
 	// 保存任务状态
-	return e.repo.UpdateTask(task)
+	if err := e.repo.UpdateTask(task); err != nil {
+		return err
+	}
+	e.notifyStatus(task)
+
+	return nil
 }
 
 // 查找匹配的处理器
@@ -129,56 +157,3 @@ func (e *TaskExecutor) executeTaskLogic(task *models.Task) (string, error) {
 
 	return "Task executed successfully", nil
 }
-
-// handleTaskExecution handles the execution of a task based on its tags
-func (te *TaskExecutor) handleTaskExecution(task *models.Task) map[string]interface{} {
-	result := make(map[string]interface{})
-
-	// Check for JIRA related tasks
-	if containsTag(task.Tags, "JIRA_TASK_EXP") {
-		// Here would be the JIRA API integration
-		log.Printf("[TaskExecutor] Executing JIRA task: %s", task.Name)
-
-		// Simulate JIRA task execution
-		if task.Parameters != nil {
-			if keyType, ok := task.Parameters["key_type"].(string); ok {
-				if keyValue, ok := task.Parameters["key_value"].(string); ok {
-					log.Printf("[TaskExecutor] Processing JIRA %s: %s", keyType, keyValue)
-					result["jira_processed"] = true
-					result["jira_key_type"] = keyType
-					result["jira_key_value"] = keyValue
-				}
-			}
-		}
-	}
-
-	// Check for Confluence related tasks
-	if containsTag(task.Tags, "CONFLUENCE_TASK") {
-		// Here would be the Confluence API integration
-		log.Printf("[TaskExecutor] Executing Confluence task: %s", task.Name)
-
-		// Simulate Confluence task execution
-		result["confluence_processed"] = true
-	}
-
-	// Generic task processing for other task types
-	if len(task.Tags) == 0 || (!containsTag(task.Tags, "JIRA_TASK_EXP") && !containsTag(task.Tags, "CONFLUENCE_TASK")) {
-		log.Printf("[TaskExecutor] Executing generic task: %s", task.Name)
-		result["generic_processed"] = true
-	}
-
-	// Simulate some processing time
-	time.Sleep(1 * time.Second)
-
-	return result
-}
-
-// containsTag checks if a tag is in the tags slice
-func containsTag(tags []string, tag string) bool {
-	for _, t := range tags {
-		if t == tag {
-			return true
-		}
-	}
-	return false
-}
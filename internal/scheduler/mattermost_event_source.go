@@ -1,10 +1,13 @@
 package scheduler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"my-scheduler-go/internal/mattermost"
 	"my-scheduler-go/internal/models"
 	"my-scheduler-go/internal/repository"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,6 +19,9 @@ type MattermostEventSource struct {
 	configService  *ConfigurationService // 配置管理服务
 	processorMutex sync.Mutex
 	processors     map[string]EventProcessor // 根据事件类型或其他条件路由到不同处理器
+	processorOrder []string                  // 处理器的注册顺序，保证匹配结果确定
+	postIndex      *PostTaskIndex            // 源消息ID到任务ID的映射
+	rules          *RuleEngine               // 路由规则，为nil时只使用配置和处理器
 }
 
 // EventProcessor 定义了不同类型事件的处理逻辑
@@ -24,6 +30,24 @@ type EventProcessor interface {
 	ShouldProcess(event *mattermost.Event) bool
 }
 
+// StandaloneProcessor 由生成的任务与配置无关的处理器实现(例如任务命令)，
+// 这类处理器在匹配配置之前处理，每个事件只处理一次，不会注入配置的转发参数
+type StandaloneProcessor interface {
+	Standalone() bool
+}
+
+// MultiTaskProcessor 由一个事件可能生成多个任务的处理器实现(例如为每个已转发的任务同步编辑)，
+// 事件源调用ProcessEventTasks代替ProcessEvent
+type MultiTaskProcessor interface {
+	ProcessEventTasks(event *mattermost.Event) ([]*models.Task, error)
+}
+
+// TaskAddedNotifier 由需要在任务保存后反馈结果的处理器实现(例如在线程中回复任务命令的结果)，
+// err为保存失败的原因
+type TaskAddedNotifier interface {
+	TaskAdded(event *mattermost.Event, task *models.Task, err error)
+}
+
 // NewMattermostEventSource 创建新的事件源
 func NewMattermostEventSource(repo repository.TaskRepository, listener *mattermost.EventListener, configService *ConfigurationService) *MattermostEventSource {
 	source := &MattermostEventSource{
@@ -31,6 +55,7 @@ func NewMattermostEventSource(repo repository.TaskRepository, listener *mattermo
 		listener:      listener,
 		configService: configService,
 		processors:    make(map[string]EventProcessor),
+		postIndex:     NewPostTaskIndex(),
 	}
 
 	// 注册为事件处理器
@@ -43,81 +68,248 @@ func NewMattermostEventSource(repo repository.TaskRepository, listener *mattermo
 func (s *MattermostEventSource) RegisterProcessor(name string, processor EventProcessor) {
 	s.processorMutex.Lock()
 	defer s.processorMutex.Unlock()
+	if _, exists := s.processors[name]; !exists {
+		s.processorOrder = append(s.processorOrder, name)
+	}
 	s.processors[name] = processor
 }
 
+// SetRuleEngine 设置路由规则引擎，规则在配置和处理器之前求值
+func (s *MattermostEventSource) SetRuleEngine(rules *RuleEngine) {
+	s.rules = rules
+}
+
+// namedProcessor 带有注册名称的事件处理器，名称用于记录事件的处理过程
+type namedProcessor struct {
+	name string
+	EventProcessor
+}
+
+// matchingProcessors 按注册顺序返回可以处理事件的处理器
+func (s *MattermostEventSource) matchingProcessors(event *mattermost.Event) []namedProcessor {
+	s.processorMutex.Lock()
+	defer s.processorMutex.Unlock()
+
+	var result []namedProcessor
+	for _, name := range s.processorOrder {
+		if processor := s.processors[name]; processor.ShouldProcess(event) {
+			result = append(result, namedProcessor{name: name, EventProcessor: processor})
+		}
+	}
+	return result
+}
+
+// recordDecision 在事件存储中记录事件的处理决定，未启用事件存储时忽略
+func (s *MattermostEventSource) recordDecision(event *mattermost.Event, stage, format string, args ...interface{}) {
+	if store := s.listener.EventStore(); store != nil && event.ID != "" {
+		store.AddDecision(event.ID, stage, fmt.Sprintf(format, args...))
+	}
+}
+
+// PostTaskIndex 返回源消息与任务之间的映射，供编辑/删除事件处理器使用
+func (s *MattermostEventSource) PostTaskIndex() *PostTaskIndex {
+	return s.postIndex
+}
+
 // HandleEvent 实现EventHandler接口，处理所有Mattermost事件
 func (s *MattermostEventSource) HandleEvent(event *mattermost.Event) {
 	log.Printf("[MattermostEventSource] Received event: %s", event.Type)
 
-	// 获取当前配置
-	configs := s.configService.GetCurrentConfigurations()
-	if len(configs) == 0 {
-		log.Println("[MattermostEventSource] No configurations available, skipping event")
+	// 忽略本系统自己发送的消息，避免转发和状态回复形成循环
+	if event.Post != nil && event.Post.IsFromScheduler() {
+		s.recordDecision(event, mattermost.EventStageFilter, "ignored: posted by the scheduler")
 		return
 	}
 
-	// 找到匹配的配置
-	var matchedConfigs []MattermostConfig
-	for _, config := range configs {
-		mmConfig, ok := config.(MattermostConfig)
-		if !ok {
-			continue
-		}
+	// 编辑和删除事件针对的是已处理过的消息，不再匹配配置
+	if isFollowUpEvent(event) {
+		s.processFollowUpEvent(event)
+		return
+	}
 
-		// 频道匹配
-		if event.Channel != nil && mmConfig.ChannelID == event.Channel.ID {
-			// 消息类型匹配
-			if string(event.Type) == mmConfig.MessageType || mmConfig.MessageType == "" {
-				matchedConfigs = append(matchedConfigs, mmConfig)
+	// 按顺序求值路由规则: drop直接丢弃，创建任务或转发的规则直接生成任务，
+	// 只设置优先级/标签的规则作用于后续配置和处理器生成的任务
+	var decision *RoutingDecision
+	if s.rules != nil {
+		decision = s.rules.Evaluate(event, time.Now())
+		if decision.Drop {
+			log.Printf("[MattermostEventSource] Event dropped by rule(s) %v", decision.Rules)
+			s.recordDecision(event, mattermost.EventStageRule, "dropped by rule(s) %s", strings.Join(decision.Rules, ", "))
+			return
+		}
+		if len(decision.Rules) > 0 {
+			s.recordDecision(event, mattermost.EventStageRule, "matched rule(s) %s", strings.Join(decision.Rules, ", "))
+		}
+		if decision.ProducesTask() {
+			if task := s.createRuleTask(event, decision); task != nil {
+				s.addTask(event, task)
 			}
+			return
 		}
 	}
 
+	// 找到可处理此事件的处理器: 独立处理器与配置无关，不论频道是否有转发配置都先处理一次，
+	// 其他处理器针对每个匹配的配置各生成一个任务
+	var configProcessors []namedProcessor
+	var standalone []string
+	for _, processor := range s.matchingProcessors(event) {
+		if isStandaloneProcessor(processor) {
+			s.processWith(event, processor, nil, decision)
+			standalone = append(standalone, processor.name)
+			continue
+		}
+		configProcessors = append(configProcessors, processor)
+	}
+
+	// 已由独立处理器处理的事件(如任务命令)不再按配置转发，否则命令消息会被再次转换为任务
+	if len(standalone) > 0 {
+		s.recordDecision(event, mattermost.EventStageConfig, "handled by %s, configurations skipped", strings.Join(standalone, ", "))
+		return
+	}
+
+	// 找到匹配的配置
+	matchedConfigs := s.matchConfigs(event)
 	if len(matchedConfigs) == 0 {
 		log.Println("[MattermostEventSource] No matching configuration for event, skipping")
+		s.recordDecision(event, mattermost.EventStageConfig, "no matching configuration")
 		return
 	}
+	configIDs := make([]string, len(matchedConfigs))
+	for i, config := range matchedConfigs {
+		configIDs[i] = config.ID
+	}
+	s.recordDecision(event, mattermost.EventStageConfig, "matched configuration(s) %s", strings.Join(configIDs, ", "))
 
-	// 找到可处理此事件的处理器
-	s.processorMutex.Lock()
-	var validProcessors []EventProcessor
-	for _, processor := range s.processors {
-		if processor.ShouldProcess(event) {
-			validProcessors = append(validProcessors, processor)
+	for i := range matchedConfigs {
+		config := &matchedConfigs[i]
+
+		// 如果没有处理器可处理此事件，使用默认处理
+		if len(configProcessors) == 0 {
+			log.Printf("[MattermostEventSource] No processor found for event, using default for config %s", config.ID)
+			s.recordDecision(event, mattermost.EventStageProcessor, "no processor, default task for config %s", config.ID)
+			task := s.createDefaultTask(event, *config)
+			if task != nil {
+				decision.Apply(task)
+				s.addTask(event, task)
+			}
+			continue
+		}
+
+		for _, processor := range configProcessors {
+			s.processWith(event, processor, config, decision)
 		}
 	}
-	s.processorMutex.Unlock()
+}
 
-	// 如果没有处理器可处理此事件，使用默认处理
-	if len(validProcessors) == 0 {
-		log.Println("[MattermostEventSource] No processor found for event, using default")
-		task := s.createDefaultTask(event, matchedConfigs[0])
-		if task != nil {
-			if err := s.repo.AddTask(task); err != nil {
-				log.Printf("[MattermostEventSource] Failed to add task: %v", err)
-			} else {
-				log.Printf("[MattermostEventSource] Created new task ID: %s", task.ID)
-			}
+// matchConfigs 按配置顺序返回与事件匹配的Mattermost配置，
+// 遇到on_match为stop的配置后不再继续匹配
+func (s *MattermostEventSource) matchConfigs(event *mattermost.Event) []MattermostConfig {
+	var matched []MattermostConfig
+	for _, config := range s.configService.ConfigurationsOfKind(ConfigKindMattermost) {
+		mmConfig, ok := config.(MattermostConfig)
+		if !ok || !mmConfig.Matches(event) {
+			continue
+		}
+
+		matched = append(matched, mmConfig)
+		if mmConfig.OnMatch == ConfigOnMatchStop {
+			break
 		}
-		return
 	}
+	return matched
+}
 
-	// 使用第一个匹配的处理器处理事件
-	processor := validProcessors[0]
+// processWith 使用处理器处理事件，config不为nil时将配置的转发方式和自定义参数注入任务
+func (s *MattermostEventSource) processWith(event *mattermost.Event, processor namedProcessor, config *MattermostConfig, decision *RoutingDecision) {
 	task, err := processor.ProcessEvent(event)
 	if err != nil {
 		log.Printf("[MattermostEventSource] Failed to process event: %v", err)
+		s.recordDecision(event, mattermost.EventStageProcessor, "%s failed: %v", processor.name, err)
+		return
+	}
+	if task == nil {
+		s.recordDecision(event, mattermost.EventStageProcessor, "%s produced no task", processor.name)
+		return
+	}
+	s.recordDecision(event, mattermost.EventStageProcessor, "%s produced a task", processor.name)
+
+	if config != nil {
+		if task.Parameters == nil {
+			task.Parameters = make(map[string]interface{})
+		}
+		task.Parameters["forward_type"] = config.ForwardType
+		task.Parameters["config_id"] = config.ID
+		task.Parameters["custom"] = config.Custom
+	}
+
+	decision.Apply(task)
+	err = s.addTask(event, task)
+	if notifier, ok := processor.EventProcessor.(TaskAddedNotifier); ok {
+		notifier.TaskAdded(event, task, err)
+	}
+}
+
+// isStandaloneProcessor 判断处理器生成的任务是否与配置无关
+func isStandaloneProcessor(processor namedProcessor) bool {
+	standalone, ok := processor.EventProcessor.(StandaloneProcessor)
+	return ok && standalone.Standalone()
+}
+
+// processFollowUpEvent 使用第一个匹配的处理器处理编辑/删除事件
+func (s *MattermostEventSource) processFollowUpEvent(event *mattermost.Event) {
+	processors := s.matchingProcessors(event)
+	if len(processors) == 0 {
+		log.Printf("[MattermostEventSource] No processor found for %s event, skipping", event.Type)
+		s.recordDecision(event, mattermost.EventStageProcessor, "no processor for %s event", event.Type)
 		return
 	}
 
-	if task != nil {
-		if err := s.repo.AddTask(task); err != nil {
-			log.Printf("[MattermostEventSource] Failed to add task: %v", err)
-		} else {
-			log.Printf("[MattermostEventSource] Created new task ID: %s", task.ID)
+	var tasks []*models.Task
+	var err error
+	if multi, ok := processors[0].EventProcessor.(MultiTaskProcessor); ok {
+		tasks, err = multi.ProcessEventTasks(event)
+	} else {
+		var task *models.Task
+		task, err = processors[0].ProcessEvent(event)
+		if task != nil {
+			tasks = append(tasks, task)
 		}
 	}
+	if err != nil {
+		log.Printf("[MattermostEventSource] Failed to process event: %v", err)
+		s.recordDecision(event, mattermost.EventStageProcessor, "%s failed: %v", processors[0].name, err)
+		return
+	}
+	if len(tasks) == 0 {
+		s.recordDecision(event, mattermost.EventStageProcessor, "%s produced no task", processors[0].name)
+	}
+
+	for _, task := range tasks {
+		s.addTask(event, task)
+	}
+}
+
+// addTask 保存任务，并记录新消息与任务之间的映射
+func (s *MattermostEventSource) addTask(event *mattermost.Event, task *models.Task) error {
+	if err := s.repo.AddTask(task); err != nil {
+		log.Printf("[MattermostEventSource] Failed to add task: %v", err)
+		s.recordDecision(event, mattermost.EventStageProcessor, "failed to add task: %v", err)
+		return err
+	}
+
+	log.Printf("[MattermostEventSource] Created new task ID: %s", task.ID)
+	if store := s.listener.EventStore(); store != nil && event.ID != "" {
+		store.AddTask(event.ID, task.ID)
+	}
+	if event.Type == mattermost.EventTypePosted && event.Post != nil {
+		s.postIndex.Add(event.Post.ID, task.ID)
+	}
+	return nil
+}
+
+// isFollowUpEvent 判断事件是否是对已有消息的编辑或删除
+func isFollowUpEvent(event *mattermost.Event) bool {
+	return event.Type == mattermost.EventTypePostEdited || event.Type == mattermost.EventTypePostDeleted
 }
 
 // createDefaultTask 创建默认任务
@@ -137,8 +329,12 @@ func (s *MattermostEventSource) createDefaultTask(event *mattermost.Event, confi
 		"channel_id":    event.Post.ChannelID,
 		"message":       event.Post.Message,
 		"user_id":       event.Post.UserID,
+		"post_id":       event.Post.ID,
+		"root_id":       event.Post.RootID,
+		"file_ids":      event.Post.FileIDs,
 		"forward_type":  config.ForwardType,
 		"config_id":     config.ID,
+		"custom":        config.Custom,
 		"original_post": event.Post,
 	}
 
@@ -156,13 +352,144 @@ func (s *MattermostEventSource) createDefaultTask(event *mattermost.Event, confi
 	return task
 }
 
+// createRuleTask 根据路由规则的决定创建任务
+func (s *MattermostEventSource) createRuleTask(event *mattermost.Event, decision *RoutingDecision) *models.Task {
+	params := map[string]interface{}{
+		"event_type": string(event.Type),
+	}
+	params["channel_id"], params["channel_name"] = eventChannel(event)
+	params["user_id"], params["username"] = eventUser(event)
+	if event.Post != nil {
+		params["message"] = event.Post.Message
+		params["post_id"] = event.Post.ID
+		params["root_id"] = event.Post.RootID
+		params["file_ids"] = event.Post.FileIDs
+		params["original_post"] = event.Post
+	}
+	if decision.ForwardType != "" {
+		params["forward_type"] = decision.ForwardType
+		params["custom"] = decision.Custom
+	}
+	for key, value := range decision.Parameters {
+		params[key] = value
+	}
+
+	name := decision.Name
+	if name == "" {
+		name = "Mattermost事件处理"
+		if event.Post != nil {
+			name = fmt.Sprintf("处理消息: %s", truncateString(event.Post.Message, 30))
+		}
+	}
+
+	task := &models.Task{
+		Name:       name,
+		TaskType:   models.TypeImmediate,
+		Status:     models.StatusPending,
+		Priority:   models.PriorityMedium,
+		Tags:       []string{"MATTERMOST_EVENT"},
+		Parameters: params,
+		CreatedAt:  time.Now(),
+	}
+	decision.Apply(task)
+
+	log.Printf("[MattermostEventSource] Rule(s) %v matched, creating task", decision.Rules)
+	return task
+}
+
+// 配置匹配后的处理策略
+const (
+	ConfigOnMatchContinue = "continue" // 继续匹配后续配置(默认)
+	ConfigOnMatchStop     = "stop"     // 不再匹配后续配置
+)
+
 // MattermostConfig 表示Mattermost相关的配置
 type MattermostConfig struct {
 	ID          string                 `json:"id"`
 	ChannelID   string                 `json:"channel_id"`
+	TeamID      string                 `json:"team_id"` // 不指定频道时匹配团队中的所有频道
 	MessageType string                 `json:"message_type"`
 	ForwardType string                 `json:"forward_type"`
 	Custom      map[string]interface{} `json:"custom"`
+	OnMatch     string                 `json:"on_match"` // continue或stop
+}
+
+// ConfigKind 实现Configuration
+func (c MattermostConfig) ConfigKind() string { return ConfigKindMattermost }
+
+// ConfigID 实现Configuration
+func (c MattermostConfig) ConfigID() string { return c.ID }
+
+// mattermostConfigColumns 配置表格中Mattermost配置本身使用的列，其余非空的列作为转发参数
+var mattermostConfigColumns = map[string]bool{
+	"kind": true, "id": true, "channel_id": true, "team_id": true, "message_type": true, "forward_type": true, "on_match": true,
+}
+
+// MattermostConfigFromRow 将配置表格的一行转换为Mattermost配置，其余非空的列作为转发参数
+func MattermostConfigFromRow(row map[string]string) (MattermostConfig, error) {
+	config := MattermostConfig{
+		ID:          row["id"],
+		ChannelID:   row["channel_id"],
+		TeamID:      row["team_id"],
+		MessageType: row["message_type"],
+		ForwardType: row["forward_type"],
+		OnMatch:     strings.ToLower(row["on_match"]),
+		Custom:      make(map[string]interface{}),
+	}
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+
+	for column, value := range row {
+		if value != "" && !mattermostConfigColumns[column] {
+			config.Custom[column] = value
+		}
+	}
+	return config, nil
+}
+
+// Validate 校验配置的必填字段和on_match取值
+func (c MattermostConfig) Validate() error {
+	switch {
+	case c.ID == "":
+		return errors.New("id is required")
+	case c.ChannelID == "" && c.TeamID == "":
+		return fmt.Errorf("config %s: channel_id or team_id is required", c.ID)
+	case c.ForwardType == "":
+		return fmt.Errorf("config %s: forward_type is required", c.ID)
+	case c.OnMatch != "" && c.OnMatch != ConfigOnMatchContinue && c.OnMatch != ConfigOnMatchStop:
+		return fmt.Errorf("config %s: invalid on_match %q (expected continue or stop)", c.ID, c.OnMatch)
+	}
+	return nil
+}
+
+// Matches 判断事件的频道(或团队)和类型是否与配置匹配
+func (c MattermostConfig) Matches(event *mattermost.Event) bool {
+	if event.Channel == nil {
+		return false
+	}
+	switch {
+	case c.ChannelID != "":
+		if c.ChannelID != event.Channel.ID {
+			return false
+		}
+	case c.TeamID != "":
+		if c.TeamID != eventTeam(event) {
+			return false
+		}
+	default:
+		return false
+	}
+	return c.MessageType == "" || c.MessageType == string(event.Type)
+}
+
+// eventTeam 返回事件所在的团队ID
+func eventTeam(event *mattermost.Event) string {
+	if event.Channel != nil && event.Channel.TeamID != "" {
+		return event.Channel.TeamID
+	}
+	teamID, _ := event.Data["team_id"].(string)
+	return teamID
 }
 
 // Start 启动事件源监听
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+)
+
+// staticConfigFetcher 返回固定配置的ConfigurationFetcher
+type staticConfigFetcher []Configuration
+
+func (f staticConfigFetcher) FetchConfigurations() ([]Configuration, error) {
+	return f, nil
+}
+
+// recordingReplier 记录线程回复的CommandReplier
+type recordingReplier struct {
+	mu      sync.Mutex
+	replies []string
+}
+
+func (r *recordingReplier) SendReply(channelID, rootID, message string) (*mattermost.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replies = append(r.replies, message)
+	return &mattermost.Post{ChannelID: channelID, RootID: rootID, Message: message}, nil
+}
+
+func (r *recordingReplier) Replies() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.replies...)
+}
+
+// newTestEventSource 创建使用给定配置、按main.go的方式注册处理器的事件源
+func newTestEventSource(t *testing.T, repo repository.TaskRepository, configs ...Configuration) (*MattermostEventSource, *recordingReplier) {
+	t.Helper()
+
+	configService := NewConfigurationService(staticConfigFetcher(configs), time.Hour)
+	if result := configService.Refresh(RefreshTriggerStartup); result.Error != "" {
+		t.Fatalf("load configurations: %s", result.Error)
+	}
+
+	replier := &recordingReplier{}
+	source := NewMattermostEventSource(repo, mattermost.NewEventListener(nil), configService)
+	source.RegisterProcessor("task_commands", NewTaskCommandProcessor(repo, replier))
+	source.RegisterProcessor("posted_messages", NewPostedMessageProcessor([]string{"task", "schedule", "urgent", "important"}))
+	return source, replier
+}
+
+// postedEvent 创建频道中的新消息事件
+func postedEvent(channelID, postID, message string) *mattermost.Event {
+	return &mattermost.Event{
+		Type:      mattermost.EventTypePosted,
+		Timestamp: time.Now(),
+		Post:      &mattermost.Post{ID: postID, ChannelID: channelID, UserID: "user-1", Message: message},
+		Channel:   &mattermost.Channel{ID: channelID, Name: "ops"},
+		User:      &mattermost.User{ID: "user-1", Username: "alice"},
+	}
+}
+
+func forwardConfig(id, channelID string) MattermostConfig {
+	return MattermostConfig{ID: id, ChannelID: channelID, ForwardType: "channel_forward", Custom: map[string]interface{}{}}
+}
+
+// 任务命令只能由任务命令处理器生成一个任务: 匹配的转发配置和关键词处理器不能再把命令消息
+// 转换为(带有命令中cron表达式的)定时转发任务
+func TestTaskCommandIsNotForwardedByConfigs(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	source, replier := newTestEventSource(t, repo, forwardConfig("ops-a", "ch-ops"), forwardConfig("ops-b", "ch-ops"))
+
+	source.HandleEvent(postedEvent("ch-ops", "post-1", `task: Nightly backup cron: "0 0 2 * * *" priority: high`))
+
+	tasks := repo.GetAllTasks()
+	if len(tasks) != 1 {
+		for _, task := range tasks {
+			t.Logf("task %s tags=%v forward_type=%v", task.Name, task.Tags, task.Parameters["forward_type"])
+		}
+		t.Fatalf("got %d tasks, want exactly the command task", len(tasks))
+	}
+	task := tasks[0]
+	if task.Name != "Nightly backup" || task.TaskType != models.TypeScheduled || task.CronExpr != "0 0 2 * * *" {
+		t.Errorf("task = %q %s %q, want scheduled \"Nightly backup\" at 0 0 2 * * *", task.Name, task.TaskType, task.CronExpr)
+	}
+	if _, ok := task.Parameters["forward_type"]; ok {
+		t.Errorf("command task got forwarding parameters: %v", task.Parameters)
+	}
+
+	replies := replier.Replies()
+	if len(replies) != 1 || !strings.Contains(replies[0], task.ID) {
+		t.Errorf("replies = %q, want one confirmation naming %s", replies, task.ID)
+	}
+}
+
+// 不是任务命令的消息仍按每个匹配的配置各生成一个任务
+func TestKeywordMessageIsForwardedPerConfig(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	source, replier := newTestEventSource(t, repo, forwardConfig("ops-a", "ch-ops"), forwardConfig("ops-b", "ch-ops"))
+
+	source.HandleEvent(postedEvent("ch-ops", "post-1", "urgent: disk almost full"))
+
+	configIDs := map[interface{}]bool{}
+	for _, task := range repo.GetAllTasks() {
+		configIDs[task.Parameters["config_id"]] = true
+	}
+	if len(configIDs) != 2 || !configIDs["ops-a"] || !configIDs["ops-b"] {
+		t.Errorf("forwarded for configs %v, want ops-a and ops-b", configIDs)
+	}
+	if replies := replier.Replies(); len(replies) != 0 {
+		t.Errorf("unexpected replies %q", replies)
+	}
+}
+
+// 没有转发配置的频道中的任务命令同样创建任务并回复
+func TestTaskCommandWithoutConfig(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	source, replier := newTestEventSource(t, repo, forwardConfig("ops", "ch-ops"))
+
+	source.HandleEvent(postedEvent("ch-other", "post-1", "task: Rotate keys"))
+
+	if tasks := repo.GetAllTasks(); len(tasks) != 1 || tasks[0].Name != "Rotate keys" {
+		t.Fatalf("tasks = %v, want the command task", tasks)
+	}
+	if replies := replier.Replies(); len(replies) != 1 || !strings.HasPrefix(replies[0], "Created immediate task") {
+		t.Errorf("replies = %q, want one confirmation", replies)
+	}
+}
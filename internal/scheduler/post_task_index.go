@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// postIndexTTL 源消息与任务映射的保留时间
+const postIndexTTL = 7 * 24 * time.Hour
+
+// postTaskEntry 记录由一条源消息创建的任务
+type postTaskEntry struct {
+	taskIDs   []string
+	createdAt time.Time
+}
+
+// PostTaskIndex 记录Mattermost源消息ID与由其创建的任务ID之间的映射，
+// 用于在消息被编辑或删除时找到对应的任务和转发消息
+type PostTaskIndex struct {
+	mu      sync.RWMutex
+	entries map[string]*postTaskEntry
+}
+
+// NewPostTaskIndex 创建消息任务映射
+func NewPostTaskIndex() *PostTaskIndex {
+	return &PostTaskIndex{
+		entries: make(map[string]*postTaskEntry),
+	}
+}
+
+// Add 记录由源消息创建的任务
+func (i *PostTaskIndex) Add(postID, taskID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entry, ok := i.entries[postID]
+	if !ok {
+		i.pruneLocked()
+		entry = &postTaskEntry{createdAt: time.Now()}
+		i.entries[postID] = entry
+	}
+	entry.taskIDs = append(entry.taskIDs, taskID)
+}
+
+// TaskIDs 返回由源消息创建的任务ID
+func (i *PostTaskIndex) TaskIDs(postID string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	entry, ok := i.entries[postID]
+	if !ok {
+		return nil
+	}
+
+	result := make([]string, len(entry.taskIDs))
+	copy(result, entry.taskIDs)
+	return result
+}
+
+// Remove 删除源消息的映射
+func (i *PostTaskIndex) Remove(postID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.entries, postID)
+}
+
+// pruneLocked 清理过期的映射，调用方需持有锁
+func (i *PostTaskIndex) pruneLocked() {
+	for postID, entry := range i.entries {
+		if time.Since(entry.createdAt) > postIndexTTL {
+			delete(i.entries, postID)
+		}
+	}
+}
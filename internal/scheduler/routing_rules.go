@@ -0,0 +1,674 @@
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 路由规则支持的动作类型
+const (
+	RuleActionCreateTask  = "create_task"
+	RuleActionSetPriority = "set_priority"
+	RuleActionSetTags     = "set_tags"
+	RuleActionForward     = "forward"
+	RuleActionDrop        = "drop"
+)
+
+var hashtagPattern = regexp.MustCompile(`#[\p{L}\p{N}_-]+`)
+
+// RoutingRule 是一条声明式的事件路由规则
+//
+// 规则按Order(相同时按定义顺序)依次求值，所有匹配规则的动作会累加，
+// 遇到Stop为true的规则或drop动作时停止求值。
+type RoutingRule struct {
+	ID      string         `yaml:"id" json:"id"`
+	Order   int            `yaml:"order" json:"order"`
+	When    RuleConditions `yaml:"when" json:"when"`
+	Actions []RuleAction   `yaml:"actions" json:"actions"`
+	Stop    bool           `yaml:"stop" json:"stop"`
+
+	pattern *regexp.Regexp
+	window  *timeWindow
+	name    *template.Template
+	params  map[string]*template.Template
+}
+
+// RuleConditions 规则的匹配条件，未设置的条件视为满足
+type RuleConditions struct {
+	Channels   []string          `yaml:"channels" json:"channels"`       // 频道ID或名称
+	Users      []string          `yaml:"users" json:"users"`             // 用户ID或用户名
+	EventTypes []string          `yaml:"event_types" json:"event_types"` // 默认只匹配posted
+	Pattern    string            `yaml:"pattern" json:"pattern"`         // 消息内容的正则表达式
+	Hashtags   []string          `yaml:"hashtags" json:"hashtags"`       // 包含任意一个即满足
+	Props      map[string]string `yaml:"props" json:"props"`             // 值为"*"时只要求存在
+	TimeWindow string            `yaml:"time_window" json:"time_window"` // 如 "mon-fri 09:00-18:00 Asia/Shanghai"
+}
+
+// RuleAction 规则匹配后执行的动作
+type RuleAction struct {
+	Type        string                 `yaml:"type" json:"type"`
+	Name        string                 `yaml:"name" json:"name"`                 // create_task: 任务名称模板
+	Parameters  map[string]string      `yaml:"parameters" json:"parameters"`     // create_task: 参数模板
+	Priority    string                 `yaml:"priority" json:"priority"`         // set_priority
+	Tags        []string               `yaml:"tags" json:"tags"`                 // create_task/set_tags
+	ForwardType string                 `yaml:"forward_type" json:"forward_type"` // forward
+	Custom      map[string]interface{} `yaml:"custom" json:"custom"`             // forward: 转发目标等参数
+}
+
+// RoutingDecision 规则求值的结果
+type RoutingDecision struct {
+	Rules       []string // 匹配的规则ID
+	Drop        bool
+	CreateTask  bool
+	Name        string
+	Parameters  map[string]interface{}
+	Priority    models.TaskPriority
+	Tags        []string
+	ForwardType string
+	Custom      map[string]interface{}
+}
+
+// Matched 判断是否有规则匹配
+func (d *RoutingDecision) Matched() bool {
+	return d != nil && len(d.Rules) > 0
+}
+
+// ProducesTask 判断规则是否要求直接创建任务(而不是交给处理器和配置)
+func (d *RoutingDecision) ProducesTask() bool {
+	return d != nil && !d.Drop && (d.CreateTask || d.ForwardType != "")
+}
+
+// Apply 将规则设置的优先级和标签应用到任务上
+func (d *RoutingDecision) Apply(task *models.Task) {
+	if d == nil {
+		return
+	}
+	if d.Priority != "" {
+		task.Priority = d.Priority
+	}
+	task.Tags = appendUnique(task.Tags, d.Tags...)
+	if len(d.Rules) > 0 {
+		task.Parameters["routing_rules"] = strings.Join(d.Rules, ",")
+	}
+}
+
+// ConfigKind 实现Configuration
+func (r RoutingRule) ConfigKind() string { return ConfigKindRoutingRule }
+
+// ConfigID 实现Configuration
+func (r RoutingRule) ConfigID() string { return r.ID }
+
+// Validate 实现Configuration，在副本上编译以免修改预编译结果
+func (r RoutingRule) Validate() error {
+	return r.Compile()
+}
+
+// Compile 校验规则并预编译正则、时间窗口和模板
+func (r *RoutingRule) Compile() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule id is required")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("rule %s: at least one action is required", r.ID)
+	}
+
+	if r.When.Pattern != "" {
+		pattern, err := regexp.Compile(r.When.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %s: invalid pattern: %v", r.ID, err)
+		}
+		r.pattern = pattern
+	}
+
+	if r.When.TimeWindow != "" {
+		window, err := parseTimeWindow(r.When.TimeWindow)
+		if err != nil {
+			return fmt.Errorf("rule %s: %v", r.ID, err)
+		}
+		r.window = window
+	}
+
+	createTasks := 0
+	for _, action := range r.Actions {
+		switch action.Type {
+		case RuleActionCreateTask:
+			// 一条规则只生成一个任务，多个create_task的名称和参数模板会互相覆盖
+			if createTasks++; createTasks > 1 {
+				return fmt.Errorf("rule %s: only one create_task action is allowed per rule", r.ID)
+			}
+			if action.Name != "" {
+				tmpl, err := template.New(r.ID).Parse(action.Name)
+				if err != nil {
+					return fmt.Errorf("rule %s: invalid name template: %v", r.ID, err)
+				}
+				r.name = tmpl
+			}
+			r.params = make(map[string]*template.Template, len(action.Parameters))
+			for key, value := range action.Parameters {
+				tmpl, err := template.New(r.ID + "." + key).Parse(value)
+				if err != nil {
+					return fmt.Errorf("rule %s: invalid template for parameter %s: %v", r.ID, key, err)
+				}
+				r.params[key] = tmpl
+			}
+		case RuleActionSetPriority:
+			if _, err := parsePriority(action.Priority); err != nil {
+				return fmt.Errorf("rule %s: %v", r.ID, err)
+			}
+		case RuleActionSetTags:
+			if len(action.Tags) == 0 {
+				return fmt.Errorf("rule %s: set_tags requires tags", r.ID)
+			}
+		case RuleActionForward:
+			if action.ForwardType == "" {
+				return fmt.Errorf("rule %s: forward requires forward_type", r.ID)
+			}
+		case RuleActionDrop:
+		default:
+			return fmt.Errorf("rule %s: unknown action %q", r.ID, action.Type)
+		}
+	}
+
+	return nil
+}
+
+// Matches 判断事件是否满足规则的所有条件
+func (r *RoutingRule) Matches(event *mattermost.Event, now time.Time) bool {
+	when := r.When
+
+	eventTypes := when.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = []string{string(mattermost.EventTypePosted)}
+	}
+	if !containsFold(eventTypes, string(event.Type)) {
+		return false
+	}
+
+	if len(when.Channels) > 0 {
+		channelID, channelName := eventChannel(event)
+		if !containsFold(when.Channels, channelID) && !containsFold(when.Channels, channelName) {
+			return false
+		}
+	}
+
+	if len(when.Users) > 0 {
+		userID, username := eventUser(event)
+		if !containsFold(when.Users, userID) && !containsFold(when.Users, username) {
+			return false
+		}
+	}
+
+	message := ""
+	if event.Post != nil {
+		message = event.Post.Message
+	}
+	if r.pattern != nil && !r.pattern.MatchString(message) {
+		return false
+	}
+
+	if len(when.Hashtags) > 0 {
+		tags := postHashtags(event.Post)
+		matched := false
+		for _, tag := range when.Hashtags {
+			if containsFold(tags, "#"+strings.TrimPrefix(tag, "#")) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for key, expected := range when.Props {
+		if event.Post == nil {
+			return false
+		}
+		value, ok := event.Post.Props[key]
+		if !ok || (expected != "*" && fmt.Sprint(value) != expected) {
+			return false
+		}
+	}
+
+	if r.window != nil && !r.window.contains(now) {
+		return false
+	}
+
+	return true
+}
+
+// RuleEngine 按顺序对事件求值路由规则
+//
+// 规则来自静态规则文件和配置服务中的RoutingRule配置(例如Confluence配置表)。
+type RuleEngine struct {
+	mu            sync.RWMutex
+	static        []RoutingRule
+	configService *ConfigurationService
+}
+
+// NewRuleEngine 创建规则引擎，configService可以为nil
+func NewRuleEngine(rules []RoutingRule, configService *ConfigurationService) (*RuleEngine, error) {
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &RuleEngine{
+		static:        rules,
+		configService: configService,
+	}, nil
+}
+
+// routingRulesFile 是规则文件的格式
+type routingRulesFile struct {
+	Rules []RoutingRule `yaml:"rules"`
+}
+
+// LoadRoutingRules 从YAML(或JSON)文件加载路由规则
+func LoadRoutingRules(path string) ([]RoutingRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file routingRulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+
+	for i := range file.Rules {
+		if err := file.Rules[i].Compile(); err != nil {
+			return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+		}
+	}
+
+	return file.Rules, nil
+}
+
+// SetRules 替换静态规则
+func (e *RuleEngine) SetRules(rules []RoutingRule) error {
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.static = rules
+	return nil
+}
+
+// Rules 返回当前生效的规则，按Order排序，相同Order时静态规则在前并保持定义顺序
+func (e *RuleEngine) Rules() []RoutingRule {
+	e.mu.RLock()
+	rules := make([]RoutingRule, len(e.static))
+	copy(rules, e.static)
+	e.mu.RUnlock()
+
+	if e.configService != nil {
+		for _, config := range e.configService.ConfigurationsOfKind(ConfigKindRoutingRule) {
+			if rule, ok := config.(RoutingRule); ok {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Order < rules[j].Order
+	})
+	return rules
+}
+
+// Evaluate 对事件依次求值所有规则，返回累加后的路由决定
+func (e *RuleEngine) Evaluate(event *mattermost.Event, now time.Time) *RoutingDecision {
+	decision := &RoutingDecision{}
+	data := newRuleTemplateData(event, now)
+
+	rules := e.Rules()
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(event, now) {
+			continue
+		}
+
+		decision.Rules = append(decision.Rules, rule.ID)
+		for _, action := range rule.Actions {
+			switch action.Type {
+			case RuleActionCreateTask:
+				decision.CreateTask = true
+				decision.Name = rule.render(rule.name, action.Name, data)
+				decision.Tags = appendUnique(decision.Tags, action.Tags...)
+				if decision.Parameters == nil {
+					decision.Parameters = make(map[string]interface{})
+				}
+				for key, tmpl := range rule.params {
+					decision.Parameters[key] = rule.render(tmpl, action.Parameters[key], data)
+				}
+			case RuleActionSetPriority:
+				decision.Priority, _ = parsePriority(action.Priority)
+			case RuleActionSetTags:
+				decision.Tags = appendUnique(decision.Tags, action.Tags...)
+			case RuleActionForward:
+				decision.ForwardType = action.ForwardType
+				decision.Custom = action.Custom
+			case RuleActionDrop:
+				decision.Drop = true
+			}
+		}
+
+		if decision.Drop || rule.Stop {
+			break
+		}
+	}
+
+	return decision
+}
+
+// render 执行模板，失败时记录日志并返回原始文本
+func (r *RoutingRule) render(tmpl *template.Template, raw string, data *ruleTemplateData) string {
+	if tmpl == nil {
+		return raw
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Printf("[RuleEngine] Rule %s: failed to render template: %v", r.ID, err)
+		return raw
+	}
+	return buf.String()
+}
+
+// ruleTemplateData 是任务名称和参数模板可以使用的数据
+type ruleTemplateData struct {
+	EventType   string
+	Message     string
+	PostID      string
+	ChannelID   string
+	ChannelName string
+	UserID      string
+	Username    string
+	Hashtags    []string
+	Props       map[string]interface{}
+	Time        time.Time
+}
+
+func newRuleTemplateData(event *mattermost.Event, now time.Time) *ruleTemplateData {
+	data := &ruleTemplateData{
+		EventType: string(event.Type),
+		Hashtags:  postHashtags(event.Post),
+		Time:      now,
+	}
+	data.ChannelID, data.ChannelName = eventChannel(event)
+	data.UserID, data.Username = eventUser(event)
+	if event.Post != nil {
+		data.Message = event.Post.Message
+		data.PostID = event.Post.ID
+		data.Props = event.Post.Props
+	}
+	return data
+}
+
+// eventChannel 返回事件所在频道的ID和名称
+func eventChannel(event *mattermost.Event) (string, string) {
+	if event.Channel != nil {
+		return event.Channel.ID, event.Channel.Name
+	}
+	if event.Post != nil {
+		return event.Post.ChannelID, ""
+	}
+	channelID, _ := event.Data["channel_id"].(string)
+	return channelID, ""
+}
+
+// eventUser 返回事件相关用户的ID和用户名
+func eventUser(event *mattermost.Event) (string, string) {
+	if event.User != nil {
+		return event.User.ID, event.User.Username
+	}
+	if event.Post != nil {
+		return event.Post.UserID, ""
+	}
+	userID, _ := event.Data["user_id"].(string)
+	return userID, ""
+}
+
+// postHashtags 返回消息中的话题标签
+func postHashtags(post *mattermost.Post) []string {
+	if post == nil {
+		return nil
+	}
+	if post.Hashtags != "" {
+		return strings.Fields(post.Hashtags)
+	}
+	return hashtagPattern.FindAllString(post.Message, -1)
+}
+
+// timeWindow 表示按星期和时间段限定的时间窗口
+type timeWindow struct {
+	days     [7]bool
+	start    int // 一天中的分钟数
+	end      int
+	location *time.Location
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseTimeWindow 解析 "[days] [HH:MM-HH:MM] [timezone]" 形式的时间窗口，
+// days可以是 mon-fri 或 sat,sun，结束时间早于开始时间表示跨越午夜
+func parseTimeWindow(text string) (*timeWindow, error) {
+	window := &timeWindow{start: 0, end: 24 * 60, location: time.Local}
+	hasDays := false
+
+	for _, field := range strings.Fields(text) {
+		switch {
+		case strings.Contains(field, ":"):
+			start, end, found := strings.Cut(field, "-")
+			if !found {
+				return nil, fmt.Errorf("invalid time range %q", field)
+			}
+			var err error
+			if window.start, err = parseClock(start); err != nil {
+				return nil, err
+			}
+			if window.end, err = parseClock(end); err != nil {
+				return nil, err
+			}
+		case strings.Contains(field, "/") || field == "UTC" || field == "Local":
+			location, err := time.LoadLocation(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timezone %q: %v", field, err)
+			}
+			window.location = location
+		default:
+			for _, part := range strings.Split(strings.ToLower(field), ",") {
+				first, last, isRange := strings.Cut(part, "-")
+				from, ok := weekdayNames[first]
+				if !ok {
+					return nil, fmt.Errorf("invalid weekday %q", first)
+				}
+				to := from
+				if isRange {
+					if to, ok = weekdayNames[last]; !ok {
+						return nil, fmt.Errorf("invalid weekday %q", last)
+					}
+				}
+				for day := from; ; day = (day + 1) % 7 {
+					window.days[day] = true
+					if day == to {
+						break
+					}
+				}
+			}
+			hasDays = true
+		}
+	}
+
+	if !hasDays {
+		for day := range window.days {
+			window.days[day] = true
+		}
+	}
+
+	return window, nil
+}
+
+// contains 判断时间是否在窗口内，跨午夜的时间段按开始当天的星期判断
+func (w *timeWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+
+	if w.start <= w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	if minute >= w.start {
+		return w.days[day]
+	}
+	return minute < w.end && w.days[(day+6)%7]
+}
+
+// parseClock 将 HH:MM 转换为一天中的分钟数
+func parseClock(text string) (int, error) {
+	hour, minute, found := strings.Cut(text, ":")
+	h, err1 := strconv.Atoi(hour)
+	m, err2 := strconv.Atoi(minute)
+	if !found || err1 != nil || err2 != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", text)
+	}
+	return h*60 + m, nil
+}
+
+// RoutingRuleFromRow 将配置表中的一行转换为路由规则
+//
+// 支持的列: rule_id(在含kind列的表格中为id), order, channels, users, event_types, pattern, hashtags,
+// props(k=v,k=v), time_window, task_name, priority, tags, forward_type, drop, stop。
+// 其他非空列作为转发参数(如target_channel_id)。
+func RoutingRuleFromRow(row map[string]string) (RoutingRule, error) {
+	rule := RoutingRule{
+		ID: row["rule_id"],
+		When: RuleConditions{
+			Channels:   splitList(row["channels"]),
+			Users:      splitList(row["users"]),
+			EventTypes: splitList(row["event_types"]),
+			Pattern:    row["pattern"],
+			Hashtags:   splitList(row["hashtags"]),
+			TimeWindow: row["time_window"],
+		},
+		Stop: parseBool(row["stop"]),
+	}
+
+	if rule.ID == "" {
+		rule.ID = row["id"]
+	}
+
+	if order := row["order"]; order != "" {
+		value, err := strconv.Atoi(order)
+		if err != nil {
+			return rule, fmt.Errorf("rule %s: invalid order %q", rule.ID, order)
+		}
+		rule.Order = value
+	}
+
+	if props := splitList(row["props"]); len(props) > 0 {
+		rule.When.Props = make(map[string]string, len(props))
+		for _, prop := range props {
+			key, value, found := strings.Cut(prop, "=")
+			if !found {
+				return rule, fmt.Errorf("rule %s: invalid prop condition %q (expected key=value)", rule.ID, prop)
+			}
+			rule.When.Props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	if parseBool(row["drop"]) {
+		rule.Actions = append(rule.Actions, RuleAction{Type: RuleActionDrop})
+	}
+	if row["task_name"] != "" {
+		rule.Actions = append(rule.Actions, RuleAction{Type: RuleActionCreateTask, Name: row["task_name"]})
+	}
+	if row["priority"] != "" {
+		rule.Actions = append(rule.Actions, RuleAction{Type: RuleActionSetPriority, Priority: row["priority"]})
+	}
+	if tags := splitList(row["tags"]); len(tags) > 0 {
+		rule.Actions = append(rule.Actions, RuleAction{Type: RuleActionSetTags, Tags: tags})
+	}
+	if row["forward_type"] != "" {
+		custom := make(map[string]interface{})
+		for key, value := range row {
+			if value != "" && !ruleColumns[key] {
+				custom[key] = value
+			}
+		}
+		rule.Actions = append(rule.Actions, RuleAction{Type: RuleActionForward, ForwardType: row["forward_type"], Custom: custom})
+	}
+
+	if err := rule.Compile(); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// ruleColumns 配置表中规则本身使用的列
+var ruleColumns = map[string]bool{
+	"kind": true, "id": true, "rule_id": true, "order": true, "channels": true, "users": true, "event_types": true,
+	"pattern": true, "hashtags": true, "props": true, "time_window": true, "task_name": true,
+	"priority": true, "tags": true, "forward_type": true, "drop": true, "stop": true,
+}
+
+// splitList 按逗号或换行切分并去除空白(表格单元格中的列表项以换行分隔)
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func parseBool(value string) bool {
+	b, _ := strconv.ParseBool(strings.TrimSpace(value))
+	return b
+}
+
+// containsFold 判断列表中是否包含value(忽略大小写)，value为空时返回false
+func containsFold(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if value != "" && !containsFold(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
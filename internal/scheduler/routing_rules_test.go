@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileRejectsMultipleCreateTaskActions(t *testing.T) {
+	rule := RoutingRule{
+		ID: "two-tasks",
+		Actions: []RuleAction{
+			{Type: RuleActionCreateTask, Name: "first {{.Message}}"},
+			{Type: RuleActionSetPriority, Priority: "high"},
+			{Type: RuleActionCreateTask, Name: "second {{.Message}}"},
+		},
+	}
+	err := rule.Compile()
+	if err == nil || !strings.Contains(err.Error(), "only one create_task") {
+		t.Fatalf("Compile() = %v, want an error about multiple create_task actions", err)
+	}
+
+	rule.Actions = rule.Actions[:2]
+	if err := rule.Compile(); err != nil {
+		t.Fatalf("Compile() with one create_task = %v", err)
+	}
+	if rule.name == nil {
+		t.Error("name template was not compiled")
+	}
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sort"
@@ -87,6 +88,10 @@ func (s *SchedulerService) pollForNewTasks() {
 	log.Printf("[SchedulerService] Found %d pending tasks", len(pending))
 
 	for _, task := range pending {
+		if s.holdForApproval(task) {
+			continue
+		}
+
 		if task.TaskType == models.TypeScheduled && task.CronExpr != "" {
 			// Add scheduled task to cron
 			s.addScheduledJob(task)
@@ -122,8 +127,9 @@ func (s *SchedulerService) addScheduledJob(task *models.Task) {
 			return
 		}
 
-		// Skip if task is already queued or running
-		if taskCopy.Status == models.StatusQueued || taskCopy.Status == models.StatusRunning {
+		// Skip if task is already queued, running or has been cancelled
+		if taskCopy.Status == models.StatusQueued || taskCopy.Status == models.StatusRunning ||
+			taskCopy.Status == models.StatusCancelled {
 			return
 		}
 
@@ -287,11 +293,13 @@ func (s *SchedulerService) checkTaskTimeouts() {
 						} else {
 							// Max retries reached, mark as failed
 							_ = s.repo.UpdateTaskStatus(task.ID, models.StatusFailed)
+							s.executor.notifyStatus(task)
 						}
 					}
 				} else {
 					// No retry policy, mark as failed
 					_ = s.repo.UpdateTaskStatus(task.ID, models.StatusFailed)
+					s.executor.notifyStatus(task)
 				}
 			}
 		}
@@ -320,12 +328,135 @@ func (s *SchedulerService) AddTask(task *models.Task) error {
 		return err
 	}
 
-	// If immediate task, queue immediately
+	if s.holdForApproval(task) {
+		return nil
+	}
+
+	s.dispatchTask(task)
+	return nil
+}
+
+// dispatchTask queues an immediate task or registers the cron job of a scheduled task
+func (s *SchedulerService) dispatchTask(task *models.Task) {
 	if task.TaskType == models.TypeImmediate {
 		s.queueTask(task)
 	} else if task.TaskType == models.TypeScheduled && task.CronExpr != "" {
 		s.addScheduledJob(task)
 	}
+}
 
-	return nil
+// RunTask queues an existing task for immediate execution
+func (s *SchedulerService) RunTask(id string) (*models.Task, error) {
+	task, err := s.repo.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Status == models.StatusQueued || task.Status == models.StatusRunning {
+		return nil, fmt.Errorf("task %s is already %s", id, task.Status)
+	}
+
+	if task.RequiresApproval && task.ApprovedBy == "" {
+		return nil, fmt.Errorf("task %s is awaiting approval", id)
+	}
+
+	s.queueTask(task)
+	return task, nil
+}
+
+// holdForApproval moves a task that still needs approval to AWAITING_APPROVAL.
+// It returns true when the task must not be dispatched yet.
+func (s *SchedulerService) holdForApproval(task *models.Task) bool {
+	if !task.RequiresApproval || task.ApprovedBy != "" {
+		return false
+	}
+
+	if err := s.repo.UpdateTaskStatus(task.ID, models.StatusAwaitingApproval); err != nil {
+		log.Printf("[SchedulerService] Failed to update task status: %v", err)
+		return true
+	}
+
+	log.Printf("[SchedulerService] Task %s (%s) is awaiting approval", task.ID, task.Name)
+	s.executor.notifyStatus(task)
+	return true
+}
+
+// ApproveTask releases a task held in AWAITING_APPROVAL. The status check and the update are
+// one compare-and-set, so when two approvers click at the same time only the first one wins
+// and the task is dispatched once.
+func (s *SchedulerService) ApproveTask(id, approver string) (*models.Task, error) {
+	task, err := s.repo.UpdateTaskIfStatus(id, []models.TaskStatus{models.StatusAwaitingApproval}, func(task *models.Task) {
+		task.ApprovedBy = approver
+		task.Status = models.StatusPending
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrTaskStatusChanged) {
+			return nil, fmt.Errorf("task is not awaiting approval: %w", err)
+		}
+		return nil, err
+	}
+
+	log.Printf("[SchedulerService] Task %s approved by %s", id, approver)
+	s.dispatchTask(task)
+	return task, nil
+}
+
+// UnscheduleTask removes the cron job of a scheduled task and cancels it.
+// A running task finishes its current run but is not triggered again.
+func (s *SchedulerService) UnscheduleTask(id string) error {
+	s.cronMutex.Lock()
+	if entryID, exists := s.cronJobs[id]; exists {
+		s.cron.Remove(entryID)
+		delete(s.cronJobs, id)
+	}
+	s.cronMutex.Unlock()
+
+	task, err := s.repo.GetTaskByID(id)
+	if err != nil {
+		return err
+	}
+	switch task.Status {
+	case models.StatusRunning, models.StatusDone, models.StatusCancelled:
+		return nil
+	}
+	_, err = s.CancelTask(id)
+	return err
+}
+
+// CancelTask removes a task from the queue and the cron schedule
+func (s *SchedulerService) CancelTask(id string) (*models.Task, error) {
+	task, err := s.repo.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch task.Status {
+	case models.StatusRunning:
+		return nil, fmt.Errorf("task %s is running and cannot be cancelled", id)
+	case models.StatusDone, models.StatusCancelled:
+		return nil, fmt.Errorf("task %s is already %s", id, task.Status)
+	}
+
+	s.queueMutex.Lock()
+	for i, t := range s.taskQueue {
+		if t.ID == id {
+			s.taskQueue = append(s.taskQueue[:i], s.taskQueue[i+1:]...)
+			break
+		}
+	}
+	s.queueMutex.Unlock()
+
+	s.cronMutex.Lock()
+	if entryID, exists := s.cronJobs[id]; exists {
+		s.cron.Remove(entryID)
+		delete(s.cronJobs, id)
+	}
+	s.cronMutex.Unlock()
+
+	if err := s.repo.UpdateTaskStatus(id, models.StatusCancelled); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[SchedulerService] Task %s cancelled", id)
+	return task, nil
 }
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+)
+
+// 多个审批人同时点击Approve时只有一个成功，任务只被调度一次
+func TestApproveTaskConcurrentApprovals(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	sched := NewSchedulerService(repo, NewTaskExecutor(repo), time.Hour)
+
+	task := &models.Task{
+		Name:             "deploy",
+		TaskType:         models.TypeScheduled,
+		CronExpr:         "0 0 2 * * *",
+		Status:           models.StatusPending,
+		RequiresApproval: true,
+		Parameters:       map[string]interface{}{},
+	}
+	if err := sched.AddTask(task); err != nil {
+		t.Fatalf("add task: %v", err)
+	}
+	if task.Status != models.StatusAwaitingApproval || len(sched.cron.Entries()) != 0 {
+		t.Fatalf("task requiring approval = %s with %d cron entries", task.Status, len(sched.cron.Entries()))
+	}
+
+	const approvers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	var rejected int
+	for i := 0; i < approvers; i++ {
+		wg.Add(1)
+		go func(approver string) {
+			defer wg.Done()
+			_, err := sched.ApproveTask(task.ID, approver)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				winners = append(winners, approver)
+			case errors.Is(err, repository.ErrTaskStatusChanged):
+				rejected++
+			default:
+				t.Errorf("ApproveTask(%s): %v", approver, err)
+			}
+		}(fmt.Sprintf("approver-%d", i))
+	}
+	wg.Wait()
+
+	if len(winners) != 1 || rejected != approvers-1 {
+		t.Fatalf("%d approvals succeeded and %d were rejected, want exactly one success", len(winners), rejected)
+	}
+	stored, _ := repo.GetTaskByID(task.ID)
+	if stored.ApprovedBy != winners[0] || stored.Status != models.StatusScheduled {
+		t.Errorf("approved task = %s by %q, want SCHEDULED by %s", stored.Status, stored.ApprovedBy, winners[0])
+	}
+	if entries := len(sched.cron.Entries()); entries != 1 {
+		t.Errorf("task scheduled %d times, want once", entries)
+	}
+}
+
+func TestApproveTaskRejectsOtherStatuses(t *testing.T) {
+	repo := repository.NewInMemoryTaskRepository()
+	sched := NewSchedulerService(repo, NewTaskExecutor(repo), time.Hour)
+
+	task := &models.Task{Name: "report", TaskType: models.TypeImmediate, Status: models.StatusPending, Parameters: map[string]interface{}{}}
+	if err := sched.AddTask(task); err != nil {
+		t.Fatalf("add task: %v", err)
+	}
+	if _, err := sched.ApproveTask(task.ID, "alice"); !errors.Is(err, repository.ErrTaskStatusChanged) {
+		t.Errorf("approving a %s task error = %v, want ErrTaskStatusChanged", task.Status, err)
+	}
+	if stored, _ := repo.GetTaskByID(task.ID); stored.ApprovedBy != "" {
+		t.Errorf("task not awaiting approval was approved by %q", stored.ApprovedBy)
+	}
+	if _, err := sched.ApproveTask("missing", "alice"); !errors.Is(err, repository.ErrTaskNotFound) {
+		t.Errorf("approving a missing task error = %v, want ErrTaskNotFound", err)
+	}
+}
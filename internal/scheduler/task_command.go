@@ -0,0 +1,239 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser 与SchedulerService使用相同的cron格式(带秒字段)
+var cronParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// ValidateCronExpr 校验cron表达式是否可被调度器接受
+func ValidateCronExpr(expr string) error {
+	if _, err := cronParser.Parse(expr); err != nil {
+		return fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+	return nil
+}
+
+// ParseTaskCommand 将 `key:value` 形式的文本解析为任务
+//
+// 支持的键: name(task), cron(schedule), priority, tags, depends, param.<key>。
+// 值可以使用双引号包含空格, 参数也可以写成 param.key=value, 例如:
+// task: "Daily report" cron: "0 0 9 * * *" priority: high tags: a,b depends: <id> param.team=ops
+// 未带键的词会拼接为任务名称，不是已知键的 `word:`(例如 "login:" 或URL)也是名称的一部分。
+func ParseTaskCommand(text string) (*models.Task, error) {
+	tokens, err := tokenizeCommand(text)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &models.Task{
+		TaskType:   models.TypeImmediate,
+		Status:     models.StatusPending,
+		Priority:   models.PriorityMedium,
+		Parameters: make(map[string]interface{}),
+	}
+
+	var nameParts []string
+	for i := 0; i < len(tokens); i++ {
+		// param.key=value 形式的任务参数
+		if name, value, ok := splitParamAssignment(tokens[i]); ok {
+			task.Parameters[name] = value
+			continue
+		}
+
+		key, value, isPair := splitCommandToken(tokens[i])
+		if !isPair {
+			nameParts = append(nameParts, tokens[i])
+			continue
+		}
+
+		// 支持 "key: value" 写法，值在下一个词中
+		if value == "" && i+1 < len(tokens) {
+			i++
+			value = tokens[i]
+		}
+
+		switch key {
+		case "name", "task":
+			nameParts = append(nameParts, value)
+		case "cron", "schedule":
+			if err := ValidateCronExpr(value); err != nil {
+				return nil, err
+			}
+			task.CronExpr = value
+			task.TaskType = models.TypeScheduled
+		case "priority":
+			priority, err := parsePriority(value)
+			if err != nil {
+				return nil, err
+			}
+			task.Priority = priority
+		case "tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					task.Tags = append(task.Tags, tag)
+				}
+			}
+		case "depends", "depends_on":
+			for _, id := range strings.Split(value, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					task.Dependencies = append(task.Dependencies, id)
+				}
+			}
+		default:
+			// splitCommandToken只返回已知的键，其余的只能是param.<key>
+			task.Parameters[strings.TrimPrefix(key, "param.")] = value
+		}
+	}
+
+	task.Name = strings.TrimSpace(strings.Join(nameParts, " "))
+	if task.Name == "" {
+		return nil, fmt.Errorf("task name is required")
+	}
+
+	return task, nil
+}
+
+// IsTaskCommand 判断消息是否是任务命令(以 "task:" 开头)
+func IsTaskCommand(text string) bool {
+	text = strings.TrimSpace(text)
+	return len(text) >= 5 && strings.EqualFold(text[:5], "task:")
+}
+
+// ValidateDependencies 检查任务依赖的任务是否存在
+func ValidateDependencies(repo repository.TaskRepository, task *models.Task) error {
+	for _, id := range task.Dependencies {
+		if id == task.ID {
+			return fmt.Errorf("task cannot depend on itself")
+		}
+		if _, err := repo.GetTaskByID(id); err != nil {
+			return fmt.Errorf("unknown dependency %q", id)
+		}
+	}
+	return nil
+}
+
+// extractCronExpr 从消息中提取 cron:/schedule: 后的表达式，表达式无效时返回false
+func extractCronExpr(text string) (string, bool) {
+	tokens, err := tokenizeCommand(text)
+	if err != nil {
+		return "", false
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		key, value, isPair := splitCommandToken(tokens[i])
+		if !isPair || (key != "cron" && key != "schedule") {
+			continue
+		}
+		if value == "" && i+1 < len(tokens) {
+			value = tokens[i+1]
+		}
+		if ValidateCronExpr(value) == nil {
+			return value, true
+		}
+		return "", false
+	}
+
+	return "", false
+}
+
+// parsePriority 将文本转换为任务优先级
+func parsePriority(value string) (models.TaskPriority, error) {
+	switch strings.ToUpper(value) {
+	case string(models.PriorityHigh):
+		return models.PriorityHigh, nil
+	case string(models.PriorityMedium):
+		return models.PriorityMedium, nil
+	case string(models.PriorityLow):
+		return models.PriorityLow, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q (expected high, medium or low)", value)
+	}
+}
+
+// commandKeys 任务命令支持的键(param.<key>之外)
+var commandKeys = map[string]bool{
+	"name":       true,
+	"task":       true,
+	"cron":       true,
+	"schedule":   true,
+	"priority":   true,
+	"tags":       true,
+	"depends":    true,
+	"depends_on": true,
+}
+
+// splitCommandToken 将 `key:value` 拆分为键和值，只拆分已知的键，其他带冒号的词不是键值对
+func splitCommandToken(token string) (string, string, bool) {
+	idx := strings.Index(token, ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+
+	key := strings.ToLower(token[:idx])
+	if name, ok := strings.CutPrefix(key, "param."); ok {
+		if name == "" {
+			return "", "", false
+		}
+	} else if !commandKeys[key] {
+		return "", "", false
+	}
+
+	return key, token[idx+1:], true
+}
+
+// splitParamAssignment 将 `param.key=value` 拆分为参数名和值
+func splitParamAssignment(token string) (string, string, bool) {
+	if !strings.HasPrefix(strings.ToLower(token), "param.") {
+		return "", "", false
+	}
+
+	name, value, found := strings.Cut(token[len("param."):], "=")
+	if !found || name == "" || strings.Contains(name, ":") {
+		return "", "", false
+	}
+	return name, value, true
+}
+
+// tokenizeCommand 按空白切分文本，双引号内的空白会被保留
+func tokenizeCommand(text string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	hasToken := false
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasToken = true
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			if hasToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				hasToken = false
+			}
+		default:
+			current.WriteRune(r)
+			hasToken = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in command")
+	}
+	if hasToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"my-scheduler-go/internal/models"
+)
+
+func TestParseTaskCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   models.Task
+		errMsg string
+	}{
+		{
+			name: "all keys",
+			text: `task: "Daily report" cron: "0 0 9 * * *" priority: high tags: ops,report depends: a1,b2 param.team=ops param.channel:town-square`,
+			want: models.Task{
+				Name: "Daily report", TaskType: models.TypeScheduled, CronExpr: "0 0 9 * * *", Priority: models.PriorityHigh,
+				Tags: []string{"ops", "report"}, Dependencies: []string{"a1", "b2"},
+				Parameters: map[string]interface{}{"team": "ops", "channel": "town-square"},
+			},
+		},
+		{
+			name: "values attached to keys",
+			text: `name:backup schedule:@daily priority:LOW depends_on:x`,
+			want: models.Task{
+				Name: "backup", TaskType: models.TypeScheduled, CronExpr: "@daily", Priority: models.PriorityLow,
+				Dependencies: []string{"x"}, Parameters: map[string]interface{}{},
+			},
+		},
+		{
+			// 不是已知键的 word: 是名称的一部分
+			name: "colon in the name",
+			text: `task: fix login: urgent`,
+			want: models.Task{
+				Name: "fix login: urgent", TaskType: models.TypeImmediate, Priority: models.PriorityMedium,
+				Parameters: map[string]interface{}{},
+			},
+		},
+		{
+			name: "url in the name",
+			text: `task: check https://status.example.com/api mailto:ops@example.com priority: high`,
+			want: models.Task{
+				Name: "check https://status.example.com/api mailto:ops@example.com", TaskType: models.TypeImmediate,
+				Priority: models.PriorityHigh, Parameters: map[string]interface{}{},
+			},
+		},
+		{
+			name: "keys are case-insensitive",
+			text: `Task: Rotate PRIORITY: low`,
+			want: models.Task{
+				Name: "Rotate", TaskType: models.TypeImmediate, Priority: models.PriorityLow,
+				Parameters: map[string]interface{}{},
+			},
+		},
+		{name: "invalid cron", text: `task: x cron: "every day"`, errMsg: "invalid cron expression"},
+		{name: "invalid priority", text: `task: x priority: asap`, errMsg: "invalid priority"},
+		{name: "missing name", text: `task:`, errMsg: "task name is required"},
+		{name: "only keys", text: `cron: @hourly`, errMsg: "task name is required"},
+		{name: "unterminated quote", text: `task: "Daily report`, errMsg: "unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := ParseTaskCommand(tt.text)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("ParseTaskCommand(%q) error = %v, want %q", tt.text, err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTaskCommand(%q): %v", tt.text, err)
+			}
+			got := models.Task{
+				Name: task.Name, TaskType: task.TaskType, CronExpr: task.CronExpr, Priority: task.Priority,
+				Tags: task.Tags, Dependencies: task.Dependencies, Parameters: task.Parameters,
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTaskCommand(%q) =\n%+v\nwant\n%+v", tt.text, got, tt.want)
+			}
+			if task.Status != models.StatusPending {
+				t.Errorf("status = %s, want %s", task.Status, models.StatusPending)
+			}
+		})
+	}
+}
+
+func TestIsTaskCommand(t *testing.T) {
+	tests := map[string]bool{
+		"task: backup":        true,
+		"  TASK:backup":       true,
+		"Task:":               true,
+		"task backup":         false,
+		"tasks: backup":       false,
+		"please task: backup": false,
+		"":                    false,
+	}
+	for text, want := range tests {
+		if got := IsTaskCommand(text); got != want {
+			t.Errorf("IsTaskCommand(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/scheduler"
+)
+
+// namedFetcher 带名称的配置获取器，名称用于日志和错误信息
+type namedFetcher struct {
+	name string
+	scheduler.ConfigurationFetcher
+}
+
+// CompositeConfigFetcher 按优先级合并多个配置源
+//
+// 先添加的配置源优先级更高: 多个配置源中种类和ID相同的配置只保留优先级最高的一个。
+// 任何一个配置源获取失败时整体失败，避免部分配置源的配置被当作删除。
+type CompositeConfigFetcher struct {
+	sources []namedFetcher
+}
+
+// NewCompositeConfigFetcher 创建组合配置获取器
+func NewCompositeConfigFetcher() *CompositeConfigFetcher {
+	return &CompositeConfigFetcher{}
+}
+
+// AddSource 添加优先级低于已有配置源的配置源
+func (f *CompositeConfigFetcher) AddSource(name string, fetcher scheduler.ConfigurationFetcher) {
+	f.sources = append(f.sources, namedFetcher{name: name, ConfigurationFetcher: fetcher})
+}
+
+// FetchConfigurations 依次从各配置源获取配置并按优先级合并
+func (f *CompositeConfigFetcher) FetchConfigurations() ([]scheduler.Configuration, error) {
+	var merged []scheduler.Configuration
+	owners := make(map[string]string)
+
+	for _, source := range f.sources {
+		configs, err := source.FetchConfigurations()
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %v", source.name, err)
+		}
+
+		added := 0
+		for _, config := range configs {
+			key := config.ConfigKind() + "/" + config.ConfigID()
+			if owner, exists := owners[key]; exists && owner != source.name {
+				log.Printf("[CompositeConfigFetcher] %s from %s overridden by %s", key, source.name, owner)
+				continue
+			}
+			owners[key] = source.name
+			merged = append(merged, config)
+			added++
+		}
+		log.Printf("[CompositeConfigFetcher] Source %s: %d configurations (%d used)", source.name, len(configs), added)
+	}
+
+	return merged, nil
+}
+
+// RowErrorCount 返回各配置源被跳过的行数之和
+func (f *CompositeConfigFetcher) RowErrorCount() int {
+	count := 0
+	for _, source := range f.sources {
+		if counter, ok := source.ConfigurationFetcher.(scheduler.RowErrorCounter); ok {
+			count += counter.RowErrorCount()
+		}
+	}
+	return count
+}
+
+// Watch 监听所有支持监听的配置源
+func (f *CompositeConfigFetcher) Watch(stop <-chan struct{}, onChange func()) error {
+	var errs []error
+	for _, source := range f.sources {
+		if watcher, ok := source.ConfigurationFetcher.(scheduler.ConfigWatcher); ok {
+			if err := watcher.Watch(stop, onChange); err != nil {
+				errs = append(errs, fmt.Errorf("source %s: %v", source.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewConfigurationFetcher 根据配置源设置创建配置获取器，confluence类型(默认)使用传入的Confluence获取器
+func NewConfigurationFetcher(spec config.ConfigSourceSpec, confluenceFetcher *ConfluenceConfigFetcher) (scheduler.ConfigurationFetcher, error) {
+	switch spec.Type {
+	case "", config.ConfigSourceConfluence:
+		return confluenceFetcher, nil
+	case config.ConfigSourceFile:
+		if spec.Path == "" {
+			return nil, errors.New("file configuration source requires path")
+		}
+		return NewFileConfigFetcher(spec.Path, spec.Watch), nil
+	case config.ConfigSourceHTTP:
+		if spec.URL == "" {
+			return nil, errors.New("http configuration source requires url")
+		}
+		return NewHTTPConfigFetcher(spec.URL, spec.Token, time.Duration(spec.Timeout)*time.Second), nil
+	case config.ConfigSourceGit:
+		if spec.Dir == "" {
+			return nil, errors.New("git configuration source requires dir")
+		}
+		return NewGitConfigFetcher(spec.Dir, spec.Pattern, spec.Pull), nil
+	case config.ConfigSourceComposite:
+		if len(spec.Sources) == 0 {
+			return nil, errors.New("composite configuration source requires sources")
+		}
+		composite := NewCompositeConfigFetcher()
+		for i, sourceSpec := range spec.Sources {
+			if sourceSpec.Type == config.ConfigSourceComposite {
+				return nil, fmt.Errorf("source %d: composite sources cannot be nested", i+1)
+			}
+			fetcher, err := NewConfigurationFetcher(sourceSpec, confluenceFetcher)
+			if err != nil {
+				return nil, fmt.Errorf("source %d: %v", i+1, err)
+			}
+			name := sourceSpec.Name
+			if name == "" {
+				name = fmt.Sprintf("%d:%s", i+1, sourceTypeName(sourceSpec.Type))
+			}
+			composite.AddSource(name, fetcher)
+		}
+		return composite, nil
+	}
+	return nil, fmt.Errorf("unknown configuration source type %q", spec.Type)
+}
+
+// sourceTypeName 返回配置源类型的名称，未设置时为confluence
+func sourceTypeName(sourceType string) string {
+	if sourceType == "" {
+		return config.ConfigSourceConfluence
+	}
+	return sourceType
+}
@@ -2,11 +2,13 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/confluence"
 	"my-scheduler-go/internal/scheduler"
-	"regexp"
 	"strings"
+	"sync"
 )
 
 // ConfluenceConfigFetcher 从Confluence获取配置
@@ -14,6 +16,8 @@ type ConfluenceConfigFetcher struct {
 	confluenceService *ConfluenceService
 	appConfig         *config.AppConfig
 	useMockData       bool
+	mu                sync.Mutex
+	rowErrors         []ConfigRowError // 最近一次解析时被跳过的行
 }
 
 // NewConfluenceConfigFetcher 创建Confluence配置获取器
@@ -41,9 +45,9 @@ func (f *ConfluenceConfigFetcher) FetchConfigurations() ([]scheduler.Configurati
 		return nil, err
 	}
 
-	// 获取页面内容
-	content, ok := page["content"].(string)
-	if !ok {
+	// 获取页面内容(存储格式)
+	content := page.StorageValue()
+	if content == "" {
 		return nil, errors.New("unable to get page content")
 	}
 
@@ -51,54 +55,120 @@ func (f *ConfluenceConfigFetcher) FetchConfigurations() ([]scheduler.Configurati
 	return f.parseTableConfigurations(content)
 }
 
-// parseTableConfigurations 解析页面内容中的表格
-func (f *ConfluenceConfigFetcher) parseTableConfigurations(content string) ([]scheduler.Configuration, error) {
-	// 在实际环境中，您需要实现从HTML或Confluence存储格式中解析表格的逻辑
-	// 这里简化为解析Confluence表格标记语法
+// ConfigRowError 配置表格中一行的校验错误
+type ConfigRowError struct {
+	Table   int    // 表格在页面中的序号(从1开始)
+	Section string // 表格所在的章节标题
+	Row     int    // 数据行的行号(从1开始，不含表头)
+	Message string
+}
+
+func (e ConfigRowError) Error() string {
+	if e.Section != "" {
+		return fmt.Sprintf("table %d (%s) row %d: %s", e.Table, e.Section, e.Row, e.Message)
+	}
+	return fmt.Sprintf("table %d row %d: %s", e.Table, e.Row, e.Message)
+}
+
+// RowErrors 返回最近一次从页面解析配置时被跳过的行及原因
+func (f *ConfluenceConfigFetcher) RowErrors() []ConfigRowError {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ConfigRowError(nil), f.rowErrors...)
+}
 
-	// 表格匹配正则表达式
-	tableRegex := regexp.MustCompile(`(?s)\|\|(.*?)\|\|(.*?)\|\|`)
-	matches := tableRegex.FindAllStringSubmatch(content, -1)
+// RowErrorCount 返回最近一次解析时被跳过的行数，供配置服务的校验闸门使用
+func (f *ConfluenceConfigFetcher) RowErrorCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.rowErrors)
+}
 
-	if len(matches) == 0 {
+// parseTableConfigurations 解析页面(存储格式)中的配置表格
+//
+// 列按表头名称匹配，与顺序无关。页面中可以有多个表格: 含kind和id列的表格每行按kind列
+// 解析为对应种类的配置(见scheduler.ConfigKinds)；没有kind列时，含rule_id列的表格定义路由规则，
+// 含id和forward_type列的表格定义Mattermost转发配置，其他表格被忽略。无效的行会被跳过并记录原因。
+func (f *ConfluenceConfigFetcher) parseTableConfigurations(content string) ([]scheduler.Configuration, error) {
+	tables, err := confluence.ParseStorageTables(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
 		return nil, errors.New("no table found in content")
 	}
 
-	// 解析表头
-	headerMatch := matches[0]
-	headerCells := strings.Split(headerMatch[1], "||")
-
-	// 解析行
-	rows := matches[1:]
 	var configs []scheduler.Configuration
-
-	for _, rowMatch := range rows {
-		cells := strings.Split(rowMatch[1], "|")
-		if len(cells) < 4 {
-			log.Println("[ConfluenceConfigFetcher] Skipping row with insufficient cells")
+	var rowErrors []ConfigRowError
+	recognized := 0
+	for _, table := range tables {
+		tableKind := ""
+		switch {
+		case table.HasHeaders("kind", "id"):
+		case table.HasHeaders("rule_id"):
+			tableKind = scheduler.ConfigKindRoutingRule
+		case table.HasHeaders("id", "forward_type"):
+			tableKind = scheduler.ConfigKindMattermost
+		default:
+			log.Printf("[ConfluenceConfigFetcher] Ignoring table %d with columns %v", table.Index, table.Headers)
 			continue
 		}
-
-		// 解析单元格内容
-		config := scheduler.MattermostConfig{
-			ID:          strings.TrimSpace(cells[0]),
-			ChannelID:   strings.TrimSpace(cells[1]),
-			MessageType: strings.TrimSpace(cells[2]),
-			ForwardType: strings.TrimSpace(cells[3]),
-			Custom:      make(map[string]interface{}),
+		recognized++
+
+		ids := make(map[string]int)
+		for _, row := range table.Rows {
+			if row.IsEmpty() {
+				continue
+			}
+
+			kind := tableKind
+			if kind == "" {
+				kind = strings.ToLower(row.Value("kind"))
+			}
+			config, err := configFromRow(kind, row.Values())
+			if err == nil {
+				key := kind + "/" + config.ConfigID()
+				if first, exists := ids[key]; exists {
+					err = fmt.Errorf("duplicate %s id %q (first defined in row %d)", kind, config.ConfigID(), first)
+				} else {
+					ids[key] = row.Number
+				}
+			}
+			if err != nil {
+				rowErr := ConfigRowError{Table: table.Index, Section: table.Section, Row: row.Number, Message: err.Error()}
+				log.Printf("[ConfluenceConfigFetcher] Skipping %v", rowErr)
+				rowErrors = append(rowErrors, rowErr)
+				continue
+			}
+			configs = append(configs, config)
 		}
+	}
 
-		// 添加额外自定义字段
-		for i := 4; i < len(cells) && i < len(headerCells); i++ {
-			config.Custom[headerCells[i]] = strings.TrimSpace(cells[i])
-		}
+	f.mu.Lock()
+	f.rowErrors = rowErrors
+	f.mu.Unlock()
 
-		configs = append(configs, config)
+	if recognized == 0 {
+		return nil, errors.New("no configuration table found in content")
 	}
-
 	return configs, nil
 }
 
+// configFromRow 按种类将表格的一行解析为配置
+func configFromRow(kind string, row map[string]string) (scheduler.Configuration, error) {
+	if kind == "" {
+		return nil, errors.New("kind is required")
+	}
+	spec, ok := scheduler.LookupConfigKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q", kind)
+	}
+	if spec.FromRow == nil {
+		return nil, fmt.Errorf("kind %s cannot be defined in a table", kind)
+	}
+	return spec.FromRow(row)
+}
+
 // getMockConfigurations 返回模拟配置数据
 func (f *ConfluenceConfigFetcher) getMockConfigurations() []scheduler.Configuration {
 	log.Println("[ConfluenceConfigFetcher] Generating mock configurations")
@@ -133,6 +203,17 @@ func (f *ConfluenceConfigFetcher) getMockConfigurations() []scheduler.Configurat
 				"notify_admin": "true",
 			},
 		},
+		scheduler.ReportSchedule{
+			ID:         "daily_report",
+			ReportType: "mattermost",
+			Schedule:   "0 0 18 * * *",
+		},
+		scheduler.JiraSyncJob{
+			ID:       "sched_project",
+			KeyType:  scheduler.JiraKeyTypeProject,
+			KeyValue: "SCHED",
+			Schedule: "0 0 * * * *",
+		},
 	}
 }
 
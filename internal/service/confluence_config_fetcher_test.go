@@ -0,0 +1,89 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// configTablesPage 是包含kind表格、旧格式转发表格和无关表格的配置页面
+const configTablesPage = `<h2>Configurations</h2>
+<table><tbody>
+<tr><th>Kind</th><th>ID</th><th>Channel ID</th><th>Forward Type</th><th>Report Type</th><th>Schedule</th></tr>
+<tr><td>mattermost</td><td>ops</td><td>ch-ops</td><td>channel_forward</td><td></td><td></td></tr>
+<tr><td>report_schedule</td><td>weekly</td><td></td><td></td><td>confluence</td><td>0 0 9 * * 1</td></tr>
+<tr><td>Mattermost</td><td>ops</td><td>ch-2</td><td>dm</td><td></td><td></td></tr>
+<tr><td>report_schedule</td><td>ops</td><td></td><td></td><td>mattermost</td><td>0 0 9 * * 1</td></tr>
+<tr><td>widget</td><td>w1</td><td></td><td></td><td></td><td></td></tr>
+<tr><td></td><td></td><td></td><td></td><td></td><td></td></tr>
+<tr><td>mattermost</td><td>bad</td><td></td><td>dm</td><td></td><td></td></tr>
+</tbody></table>
+<h2>Legacy</h2>
+<table>
+<tr><th>ID</th><th>Channel ID</th><th>Forward Type</th></tr>
+<tr><td>legacy</td><td>ch-3</td><td>channel_forward</td></tr>
+<tr><td>noforward</td><td>ch-4</td><td></td></tr>
+</table>
+<h2>Owners</h2>
+<table><tr><th>Owner</th><th>Notes</th></tr><tr><td>alice</td><td>ops team</td></tr></table>`
+
+func TestParseTableConfigurations(t *testing.T) {
+	fetcher := NewConfluenceConfigFetcher(nil, nil, false)
+
+	configs, err := fetcher.parseTableConfigurations(configTablesPage)
+	if err != nil {
+		t.Fatalf("parseTableConfigurations: %v", err)
+	}
+
+	var keys []string
+	for _, config := range configs {
+		keys = append(keys, config.ConfigKind()+"/"+config.ConfigID())
+	}
+	wantKeys := []string{"mattermost/ops", "report_schedule/weekly", "report_schedule/ops", "mattermost/legacy"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("configs = %v, want %v", keys, wantKeys)
+	}
+
+	wantErrors := []ConfigRowError{
+		{Table: 1, Section: "Configurations", Row: 3, Message: `duplicate mattermost id "ops" (first defined in row 1)`},
+		{Table: 1, Section: "Configurations", Row: 5, Message: `unknown kind "widget"`},
+		{Table: 1, Section: "Configurations", Row: 7, Message: "config bad: channel_id or team_id is required"},
+		{Table: 2, Section: "Legacy", Row: 2, Message: "config noforward: forward_type is required"},
+	}
+	if got := fetcher.RowErrors(); !reflect.DeepEqual(got, wantErrors) {
+		t.Errorf("row errors = %+v\nwant %+v", got, wantErrors)
+	}
+	if got := fetcher.RowErrorCount(); got != len(wantErrors) {
+		t.Errorf("RowErrorCount = %d, want %d", got, len(wantErrors))
+	}
+	if got := wantErrors[0].Error(); got != `table 1 (Configurations) row 3: duplicate mattermost id "ops" (first defined in row 1)` {
+		t.Errorf("row error text = %q", got)
+	}
+
+	// 下一次解析替换上一次的行错误
+	if _, err := fetcher.parseTableConfigurations(`<table><tr><th>Rule ID</th></tr></table>`); err != nil {
+		t.Fatalf("parse rules table: %v", err)
+	}
+	if got := fetcher.RowErrorCount(); got != 0 {
+		t.Errorf("RowErrorCount after a clean parse = %d, want 0", got)
+	}
+}
+
+func TestParseTableConfigurationsWithoutConfigTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no table", "<p>nothing here</p>", "no table found in content"},
+		{"unrelated tables", "<table><tr><th>Owner</th></tr><tr><td>alice</td></tr></table>", "no configuration table found in content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, err := NewConfluenceConfigFetcher(nil, nil, false).parseTableConfigurations(tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || configs != nil {
+				t.Errorf("got %v, %v; want error %q", configs, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,49 +1,47 @@
 package service
 
 import (
-	"fmt"
 	"log"
 	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/confluence"
 )
 
 // ConfluenceService handles interaction with Confluence API
 type ConfluenceService struct {
 	config *config.AppConfig
+	client *confluence.Client
 }
 
 // NewConfluenceService creates a new Confluence service
 func NewConfluenceService(cfg *config.AppConfig) *ConfluenceService {
+	client := confluence.NewClient(cfg.Confluence.URL, cfg.Confluence.Username, cfg.Confluence.Password, cfg.Confluence.Token)
+	if cfg.Confluence.MaxRetries > 0 {
+		client.MaxRetries = cfg.Confluence.MaxRetries
+	}
+
 	return &ConfluenceService{
 		config: cfg,
+		client: client,
 	}
 }
 
-// GetPage fetches a page from Confluence
-func (s *ConfluenceService) GetPage(pageID string) (map[string]interface{}, error) {
+// GetPage fetches a page from Confluence with its storage-format body, version and space
+func (s *ConfluenceService) GetPage(pageID string) (*confluence.Page, error) {
 	log.Printf("[ConfluenceService] Fetching page %s", pageID)
-
-	// Simulate fetching page
-	// In a real implementation, this would call the Confluence API
-	result := map[string]interface{}{
-		"id":    pageID,
-		"title": "Example Page",
-		"url":   fmt.Sprintf("%s/pages/viewpage.action?pageId=%s", s.config.Confluence.URL, pageID),
-	}
-
-	return result, nil
+	return s.client.GetPage(pageID)
 }
 
-// UpdatePage updates a Confluence page with new content
+// UpdatePage replaces the title and content of a Confluence page.
+// The content is wiki markup (see CreateTable); Confluence converts it to storage format.
 func (s *ConfluenceService) UpdatePage(pageID, title, content string) error {
 	log.Printf("[ConfluenceService] Updating page %s - %s", pageID, title)
 
-	// Simulate updating the page
-	// In a real implementation, this would:
-	// 1. Get the current page version
-	// 2. Update the content
-	// 3. Publish the new version
+	page, err := s.client.UpdatePage(pageID, title, content, confluence.RepresentationWiki)
+	if err != nil {
+		return err
+	}
 
-	log.Printf("[ConfluenceService] Page updated successfully")
+	log.Printf("[ConfluenceService] Page %s updated to version %d", pageID, page.Version.Number)
 	return nil
 }
 
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"my-scheduler-go/internal/scheduler"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileChangeDebounce 文件变化后等待的时间，合并编辑器保存时产生的多个事件
+const fileChangeDebounce = 500 * time.Millisecond
+
+// FileConfigFetcher 从本地YAML或JSON配置文档获取配置(格式见scheduler.DecodeConfigurations)
+type FileConfigFetcher struct {
+	path  string
+	watch bool
+}
+
+// NewFileConfigFetcher 创建文件配置获取器，watch为true时文件变化后立即重新加载
+func NewFileConfigFetcher(path string, watch bool) *FileConfigFetcher {
+	return &FileConfigFetcher{
+		path:  path,
+		watch: watch,
+	}
+}
+
+// FetchConfigurations 读取并解析配置文件
+func (f *FileConfigFetcher) FetchConfigurations() ([]scheduler.Configuration, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return scheduler.DecodeConfigurations(data)
+}
+
+// Watch 监听配置文件所在目录，文件被写入、创建或替换后调用onChange
+//
+// 监听目录而不是文件本身，因为许多编辑器保存时会先写临时文件再重命名。
+func (f *FileConfigFetcher) Watch(stop <-chan struct{}, onChange func()) error {
+	if !f.watch {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(f.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	target := filepath.Clean(f.path)
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != target || event.Op == fsnotify.Chmod {
+					continue
+				}
+				debounce = time.After(fileChangeDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("[FileConfigFetcher] Watch error: %v", err)
+			case <-debounce:
+				debounce = nil
+				log.Printf("[FileConfigFetcher] %s changed, reloading configurations", f.path)
+				onChange()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	log.Printf("[FileConfigFetcher] Watching %s", f.path)
+	return nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"my-scheduler-go/internal/scheduler"
+)
+
+// gitCommandTimeout git命令的超时时间
+const gitCommandTimeout = 60 * time.Second
+
+// GitConfigFetcher 从git工作目录中的配置文档获取配置
+//
+// 每次获取时(可选)先执行git pull --ff-only，再按文件名顺序读取pattern匹配的所有文档，
+// 合并后的配置按文件和文档中的顺序排列。
+type GitConfigFetcher struct {
+	dir     string
+	pattern string
+	pull    bool
+}
+
+// NewGitConfigFetcher 创建git配置获取器，pattern为相对于dir的glob(默认*.yaml)
+func NewGitConfigFetcher(dir, pattern string, pull bool) *GitConfigFetcher {
+	if pattern == "" {
+		pattern = "*.yaml"
+	}
+	return &GitConfigFetcher{
+		dir:     dir,
+		pattern: pattern,
+		pull:    pull,
+	}
+}
+
+// FetchConfigurations 更新工作目录并读取配置文档
+func (f *GitConfigFetcher) FetchConfigurations() ([]scheduler.Configuration, error) {
+	if f.pull {
+		if _, err := f.git("pull", "--ff-only"); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(f.dir, f.pattern))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no configuration file matches %s in %s", f.pattern, f.dir)
+	}
+	sort.Strings(files)
+
+	var configs []scheduler.Configuration
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileConfigs, err := scheduler.DecodeConfigurations(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		configs = append(configs, fileConfigs...)
+	}
+
+	if revision, err := f.git("rev-parse", "--short", "HEAD"); err == nil {
+		log.Printf("[GitConfigFetcher] Read %d files at revision %s", len(files), revision)
+	}
+	return configs, nil
+}
+
+// git 在工作目录中执行git命令，返回去掉首尾空白的输出
+func (f *GitConfigFetcher) git(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", f.dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"my-scheduler-go/internal/scheduler"
+)
+
+// maxConfigDocumentSize 配置文档的大小上限
+const maxConfigDocumentSize = 10 << 20
+
+// HTTPConfigFetcher 从HTTP接口获取JSON配置文档(格式见scheduler.DecodeConfigurations)
+//
+// 服务器返回ETag时，下次请求携带If-None-Match，304响应沿用上次的配置。
+type HTTPConfigFetcher struct {
+	url    string
+	token  string
+	client *http.Client
+
+	mu   sync.Mutex
+	etag string
+	last []scheduler.Configuration
+}
+
+// NewHTTPConfigFetcher 创建HTTP配置获取器，token不为空时以Bearer方式认证
+func NewHTTPConfigFetcher(url, token string, timeout time.Duration) *HTTPConfigFetcher {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &HTTPConfigFetcher{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// FetchConfigurations 请求配置文档并解析
+func (f *HTTPConfigFetcher) FetchConfigurations() ([]scheduler.Configuration, error) {
+	req, err := http.NewRequest(http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	f.mu.Lock()
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	f.mu.Unlock()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return append([]scheduler.Configuration(nil), f.last...), nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigDocumentSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET %s returned %d: %s", f.url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	configs, err := scheduler.DecodeConfigurations(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration document from %s: %v", f.url, err)
+	}
+
+	f.mu.Lock()
+	f.etag = resp.Header.Get("ETag")
+	f.last = configs
+	f.mu.Unlock()
+
+	return configs, nil
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/jira"
+	"my-scheduler-go/internal/models"
+	"my-scheduler-go/internal/repository"
+	"my-scheduler-go/internal/scheduler"
+	"my-scheduler-go/internal/xlsx"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultJiraExportFields are the columns exported when neither jira.export.fields nor the task sets them
+var DefaultJiraExportFields = []string{
+	"summary", "issuetype", "status", "priority", "assignee", "reporter",
+	"created", "updated", "duedate", "labels",
+}
+
+// Artifact formats of JIRA_TASK_EXP tasks
+const (
+	JiraExportFormatXLSX = "xlsx"
+	JiraExportFormatCSV  = "csv"
+	JiraExportFormatJSON = "json"
+)
+
+// DefaultJiraExportFormats are the formats stored when neither jira.export.formats nor the task sets them
+var DefaultJiraExportFormats = []string{JiraExportFormatXLSX, JiraExportFormatCSV}
+
+// jiraExportContentTypes maps each format to the content type of its artifact
+var jiraExportContentTypes = map[string]string{
+	JiraExportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	JiraExportFormatCSV:  "text/csv; charset=utf-8",
+	JiraExportFormatJSON: "application/json",
+}
+
+// How the rows of an XLSX export are split into sheets
+const (
+	JiraExportGroupByIssueType = "issue_type"
+	JiraExportGroupByProject   = "project"
+)
+
+// jiraExportBaseColumns are the headers of the columns every row has, before the exported fields
+var jiraExportBaseColumns = []string{"Key", "Project", "Issue Type", "Parent", "Relation", "Depth"}
+
+// JiraExport is a flattened issue hierarchy: one row per issue, in tree (depth-first) order
+type JiraExport struct {
+	Environment string          `json:"environment"`
+	KeyType     string          `json:"key_type"`
+	KeyValue    string          `json:"key_value"`
+	ExportedAt  time.Time       `json:"exported_at"`
+	Columns     []string        `json:"columns"`
+	Rows        []JiraExportRow `json:"rows"`
+}
+
+// JiraExportRow is one exported issue. Fields holds the flattened value of every column:
+// a string, number, bool, time.Time (date and date-time fields) or nil.
+type JiraExportRow struct {
+	Key       string                 `json:"key"`
+	URL       string                 `json:"url"`
+	Project   string                 `json:"project"`
+	IssueType string                 `json:"issue_type"`
+	Parent    string                 `json:"parent,omitempty"`   // key of the parent in the hierarchy
+	Relation  string                 `json:"relation,omitempty"` // subtask or the link relation to the parent
+	Depth     int                    `json:"depth"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+// Export fetches the issues of a root ticket (with its sub-tasks and linked issues) or a project and
+// flattens them. fields are field IDs or logical names from the environment's field_mappings;
+// empty uses DefaultJiraExportFields.
+func (s *JiraService) Export(environment, keyType, keyValue string, fields []string, user string) (*JiraExport, error) {
+	instance, err := s.instance(environment)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		fields = DefaultJiraExportFields
+	}
+
+	export := &JiraExport{
+		Environment: instance.name,
+		KeyType:     keyType,
+		KeyValue:    keyValue,
+		ExportedAt:  time.Now(),
+		Columns:     fields,
+		Rows:        []JiraExportRow{},
+	}
+	addRow := func(issue *jira.Issue, parent, relation string, depth int) {
+		export.Rows = append(export.Rows, instance.exportRow(issue, fields, parent, relation, depth))
+	}
+
+	switch keyType {
+	case scheduler.JiraKeyTypeRootTicket:
+		tree, err := s.FetchRootTicket(instance.name, keyValue, user)
+		if err != nil {
+			return nil, err
+		}
+		parents := make(map[*jira.IssueNode]string)
+		tree.Walk(func(node *jira.IssueNode) {
+			for _, child := range node.Children {
+				parents[child] = node.Issue.Key
+			}
+			addRow(node.Issue, parents[node], node.Relation, node.Depth)
+		})
+
+	case scheduler.JiraKeyTypeProject:
+		issues, err := s.FetchProjectIssues(instance.name, keyValue, user)
+		if err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			if parent := issues[i].Fields.Parent; parent != nil {
+				addRow(&issues[i], parent.Key, jira.RelationSubtask, 1)
+			} else {
+				addRow(&issues[i], "", "", 0)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid key_type %q (expected %s or %s)", keyType, scheduler.JiraKeyTypeRootTicket, scheduler.JiraKeyTypeProject)
+	}
+
+	return export, nil
+}
+
+// exportRow flattens an issue into the given columns
+func (i *jiraInstance) exportRow(issue *jira.Issue, columns []string, parent, relation string, depth int) JiraExportRow {
+	row := JiraExportRow{
+		Key:      issue.Key,
+		URL:      i.client.IssueURL(issue.Key),
+		Parent:   parent,
+		Relation: relation,
+		Depth:    depth,
+		Fields:   make(map[string]interface{}, len(columns)),
+	}
+	if issue.Fields.Project != nil {
+		row.Project = issue.Fields.Project.Key
+	}
+	if issue.Fields.IssueType != nil {
+		row.IssueType = issue.Fields.IssueType.Name
+	}
+
+	for _, column := range columns {
+		row.Fields[column] = flattenFieldValue(issue.Fields.Field(i.fieldID(column)))
+	}
+	return row
+}
+
+// isoDate matches Jira date fields such as duedate (YYYY-MM-DD)
+var isoDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// flattenFieldValue converts a raw Jira field value to a scalar: objects become their display name,
+// lists a comma-separated string, and date or date-time strings a time.Time
+func flattenFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, float64, bool:
+		return v
+	case string:
+		if isoDate.MatchString(v) {
+			if date, err := time.Parse("2006-01-02", v); err == nil {
+				return date
+			}
+		}
+		if t, err := time.Parse(jira.TimeLayout, v); err == nil {
+			return t
+		}
+		return v
+	case map[string]interface{}:
+		// user, status, priority, option and issue objects
+		for _, name := range []string{"displayName", "name", "value", "key"} {
+			if s, ok := v[name].(string); ok {
+				return s
+			}
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if flattened := flattenFieldValue(item); flattened != nil {
+				items = append(items, fmt.Sprint(flattened))
+			}
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ExportToExcel writes an export as an XLSX workbook with one sheet per issue type or per project
+// (groupBy, empty uses issue_type), in order of first appearance. Each sheet has a frozen, styled
+// header row; keys and parents link back to Jira and date fields are formatted as dates.
+func (s *JiraService) ExportToExcel(export *JiraExport, groupBy string, w io.Writer) error {
+	if groupBy == "" {
+		groupBy = JiraExportGroupByIssueType
+	}
+	if groupBy != JiraExportGroupByIssueType && groupBy != JiraExportGroupByProject {
+		return fmt.Errorf("invalid group_by %q (expected %s or %s)", groupBy, JiraExportGroupByIssueType, JiraExportGroupByProject)
+	}
+
+	header := append(append([]string{}, jiraExportBaseColumns...), export.Columns...)
+	urls := make(map[string]string, len(export.Rows))
+	for _, row := range export.Rows {
+		urls[row.Key] = row.URL
+	}
+
+	workbook := xlsx.NewWorkbook()
+	sheets := make(map[string]*xlsx.Sheet)
+	for _, row := range export.Rows {
+		group := row.IssueType
+		if groupBy == JiraExportGroupByProject {
+			group = row.Project
+		}
+		if group == "" {
+			group = "(none)"
+		}
+		sheet, ok := sheets[group]
+		if !ok {
+			sheet = workbook.AddSheet(group, header...)
+			sheets[group] = sheet
+		}
+
+		cells := []xlsx.Cell{
+			{Value: row.Key, Link: row.URL},
+			{Value: row.Project},
+			{Value: row.IssueType},
+			{Value: nullIfEmpty(row.Parent), Link: urls[row.Parent]},
+			{Value: nullIfEmpty(row.Relation)},
+			{Value: row.Depth},
+		}
+		for _, column := range export.Columns {
+			cells = append(cells, xlsx.Cell{Value: row.Fields[column]})
+		}
+		sheet.AddRow(cells...)
+	}
+	if len(sheets) == 0 {
+		workbook.AddSheet(export.KeyValue, header...)
+	}
+
+	log.Printf("[JiraService] Writing %d issues of %s to %d sheets (by %s)", len(export.Rows), export.KeyValue, max(len(sheets), 1), groupBy)
+	return workbook.Write(w)
+}
+
+// ExportToCSV writes an export as CSV: a header row and one row per issue in tree order.
+// The output starts with a UTF-8 byte order mark so Excel detects the encoding.
+func (s *JiraService) ExportToCSV(export *JiraExport, w io.Writer) error {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, jiraExportBaseColumns...), "URL")
+	if err := cw.Write(append(header, export.Columns...)); err != nil {
+		return err
+	}
+	for _, row := range export.Rows {
+		record := []string{row.Key, row.Project, row.IssueType, row.Parent, row.Relation, strconv.Itoa(row.Depth), row.URL}
+		for _, column := range export.Columns {
+			record = append(record, csvValue(row.Fields[column]))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue formats a flattened field value for CSV; dates without a time of day omit it
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nullIfEmpty returns nil for an empty string so the spreadsheet cell stays blank
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// JiraExportHandler runs JIRA_TASK_EXP tasks: it exports the hierarchy named by the task's
+// environment, key_type and key_value parameters and stores it as task artifacts
+type JiraExportHandler struct {
+	jiraService *JiraService
+	artifacts   *repository.ArtifactStore
+	fields      []string
+	formats     []string
+	groupBy     string
+}
+
+// NewJiraExportHandler creates the handler; cfg holds the default columns, formats and sheet grouping
+func NewJiraExportHandler(jiraService *JiraService, artifacts *repository.ArtifactStore, cfg config.JiraExportConfig) *JiraExportHandler {
+	formats := cfg.Formats
+	if len(formats) == 0 {
+		formats = DefaultJiraExportFormats
+	}
+	return &JiraExportHandler{
+		jiraService: jiraService,
+		artifacts:   artifacts,
+		fields:      cfg.Fields,
+		formats:     formats,
+		groupBy:     cfg.GroupBy,
+	}
+}
+
+// HandleTask exports the issues and stores one artifact per format.
+// The "fields" and "formats" parameters (list or comma-separated string) and "group_by"
+// override the configured defaults.
+func (h *JiraExportHandler) HandleTask(task *models.Task) error {
+	environment, _ := task.Parameters["environment"].(string)
+	keyType, _ := task.Parameters["key_type"].(string)
+	keyValue, _ := task.Parameters["key_value"].(string)
+	if keyValue == "" {
+		return fmt.Errorf("key_value parameter is required")
+	}
+
+	fields := h.fields
+	if override := stringListParam(task.Parameters["fields"]); len(override) > 0 {
+		fields = override
+	}
+	formats, err := exportFormats(h.formats, task.Parameters["formats"])
+	if err != nil {
+		return err
+	}
+	groupBy := h.groupBy
+	if override, ok := task.Parameters["group_by"].(string); ok && override != "" {
+		groupBy = override
+	}
+	if groupBy != "" && groupBy != JiraExportGroupByIssueType && groupBy != JiraExportGroupByProject {
+		return fmt.Errorf("invalid group_by %q (expected %s or %s)", groupBy, JiraExportGroupByIssueType, JiraExportGroupByProject)
+	}
+
+	export, err := h.jiraService.Export(environment, keyType, keyValue, fields, task.Owner)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(formats))
+	for _, format := range formats {
+		var buf bytes.Buffer
+		switch format {
+		case JiraExportFormatXLSX:
+			err = h.jiraService.ExportToExcel(export, groupBy, &buf)
+		case JiraExportFormatCSV:
+			err = h.jiraService.ExportToCSV(export, &buf)
+		case JiraExportFormatJSON:
+			var data []byte
+			if data, err = json.MarshalIndent(export, "", "  "); err == nil {
+				buf.Write(data)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("write %s export: %w", format, err)
+		}
+
+		name := exportFileName(export) + "." + format
+		if _, err := h.artifacts.Save(task.ID, name, jiraExportContentTypes[format], buf.Bytes()); err != nil {
+			return fmt.Errorf("store export artifact: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	task.ExecutionResult["environment"] = export.Environment
+	task.ExecutionResult["issue_count"] = len(export.Rows)
+	task.ExecutionResult["artifacts"] = names
+	log.Printf("[JiraExportHandler] Exported %d issues of %s %s from %s to %s",
+		len(export.Rows), keyType, keyValue, export.Environment, strings.Join(names, ", "))
+	return nil
+}
+
+// exportFormats returns the lower-cased, de-duplicated formats of a task: the "formats" parameter
+// or, when it is not set, the defaults
+func exportFormats(defaults []string, param interface{}) ([]string, error) {
+	requested := stringListParam(param)
+	if len(requested) == 0 {
+		requested = defaults
+	}
+
+	formats := make([]string, 0, len(requested))
+	for _, format := range requested {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if _, ok := jiraExportContentTypes[format]; !ok {
+			return nil, fmt.Errorf("invalid export format %q (expected %s, %s or %s)", format, JiraExportFormatXLSX, JiraExportFormatCSV, JiraExportFormatJSON)
+		}
+		if !slices.Contains(formats, format) {
+			formats = append(formats, format)
+		}
+	}
+	return formats, nil
+}
+
+// exportFileName returns the artifact name (without extension) of an export, unique per run
+func exportFileName(export *JiraExport) string {
+	key := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, export.KeyValue)
+	return fmt.Sprintf("jira-export-%s-%s", key, export.ExportedAt.Format("20060102-150405"))
+}
+
+// stringListParam reads a task parameter given as a list or a comma-separated string
+func stringListParam(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []string:
+		items = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportToCSV(t *testing.T) {
+	export := &JiraExport{
+		KeyValue: "SCHED-1",
+		Columns:  []string{"summary", "created", "updated", "story_points", "labels"},
+		Rows: []JiraExportRow{
+			{
+				Key: "SCHED-1", URL: "https://jira.example.com/browse/SCHED-1", Project: "SCHED", IssueType: "Story",
+				Fields: map[string]interface{}{
+					"summary":      `Export "all", quickly`,
+					"created":      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+					"updated":      time.Date(2024, 5, 2, 13, 45, 30, 0, time.UTC),
+					"story_points": 2.5,
+					"labels":       "ops, export",
+				},
+			},
+			{
+				Key: "SCHED-2", URL: "https://jira.example.com/browse/SCHED-2", Project: "SCHED", IssueType: "Sub-task",
+				Parent: "SCHED-1", Relation: "subtask", Depth: 1,
+				Fields: map[string]interface{}{"summary": "多行\n说明", "created": nil},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&JiraService{}).ExportToCSV(export, &buf); err != nil {
+		t.Fatalf("ExportToCSV: %v", err)
+	}
+
+	// The BOM lets Excel detect UTF-8
+	data, found := bytes.CutPrefix(buf.Bytes(), []byte("\xEF\xBB\xBF"))
+	if !found {
+		t.Fatalf("output does not start with a UTF-8 BOM: %q", buf.Bytes()[:min(buf.Len(), 8)])
+	}
+	if !strings.HasPrefix(string(data), "Key,Project,Issue Type,Parent,Relation,Depth,URL,summary,created,updated,story_points,labels\n") {
+		t.Errorf("header line = %q", strings.SplitN(string(data), "\n", 2)[0])
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	want := [][]string{
+		{"Key", "Project", "Issue Type", "Parent", "Relation", "Depth", "URL", "summary", "created", "updated", "story_points", "labels"},
+		{"SCHED-1", "SCHED", "Story", "", "", "0", "https://jira.example.com/browse/SCHED-1", `Export "all", quickly`, "2024-05-01", "2024-05-02 13:45:30", "2.5", "ops, export"},
+		{"SCHED-2", "SCHED", "Sub-task", "SCHED-1", "subtask", "1", "https://jira.example.com/browse/SCHED-2", "多行\n说明", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q\nwant %q", records, want)
+	}
+}
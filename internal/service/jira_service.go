@@ -1,81 +1,255 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/jira"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultJiraEnvironment is the name of the environment defined by the top-level jira settings
+const DefaultJiraEnvironment = "default"
+
+// ErrUnknownJiraEnvironment is returned when a call names an environment that is not configured
+var ErrUnknownJiraEnvironment = errors.New("unknown Jira environment")
+
+// jiraInstance is a configured Jira environment with its client
+type jiraInstance struct {
+	name          string
+	url           string
+	fieldMappings map[string]string
+	client        *jira.Client
+}
+
+// JiraHealth is the result of checking one Jira environment
+type JiraHealth struct {
+	Environment string `json:"environment"`
+	URL         string `json:"url"`
+	Healthy     bool   `json:"healthy"`
+	Version     string `json:"version,omitempty"`
+	User        string `json:"user,omitempty"`
+	LatencyMS   int64  `json:"latency_ms"`
+	Error       string `json:"error,omitempty"`
+}
+
 // JiraService handles integration with Jira API
+//
+// Every call names the environment (Jira instance) it targets; an empty name uses
+// jira.default_environment.
 type JiraService struct {
-	config *config.AppConfig
+	config             *config.AppConfig
+	instances          map[string]*jiraInstance
+	defaultEnvironment string
 }
 
-// NewJiraService creates a new Jira service
+// NewJiraService creates a new Jira service with a client per configured environment
 func NewJiraService(cfg *config.AppConfig) *JiraService {
-	return &JiraService{
-		config: cfg,
+	s := &JiraService{
+		config:             cfg,
+		instances:          make(map[string]*jiraInstance),
+		defaultEnvironment: strings.ToLower(cfg.Jira.DefaultEnvironment),
+	}
+	if s.defaultEnvironment == "" {
+		s.defaultEnvironment = DefaultJiraEnvironment
 	}
+
+	if cfg.Jira.URL != "" {
+		s.addInstance(DefaultJiraEnvironment, cfg.Jira.JiraEnvironment)
+	}
+	for name, env := range cfg.Jira.Environments {
+		s.addInstance(name, env)
+	}
+
+	return s
 }
 
-// FetchRootTicket fetches a root ticket and all its sub-issues
-func (s *JiraService) FetchRootTicket(environment, key, user string) (map[string]interface{}, error) {
-	log.Printf("[JiraService] Fetching root ticket %s from %s for user %s", key, environment, user)
+// addInstance creates the client of an environment
+func (s *JiraService) addInstance(name string, env config.JiraEnvironment) {
+	client := jira.NewClient(env.URL, env.Username, env.Password, env.Token)
+	client.SetRateLimit(env.RateLimit)
+
+	name = strings.ToLower(name)
+	s.instances[name] = &jiraInstance{
+		name:          name,
+		url:           client.BaseURL,
+		fieldMappings: env.FieldMappings,
+		client:        client,
+	}
+}
 
-	// This is a placeholder for the actual API call
-	// In a real implementation, we would:
-	// 1. Authenticate with the Jira API
-	// 2. Make a GET request to the Jira REST API
-	// 3. Parse the response
-	// 4. Return the structured data
+// Environments returns the names of the configured environments, sorted
+func (s *JiraService) Environments() []string {
+	names := make([]string, 0, len(s.instances))
+	for name := range s.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	// Simulate fetching data
-	result := map[string]interface{}{
-		"key":         key,
-		"environment": environment,
-		"user":        user,
-		"status":      "In Progress",
-		"sub_issues":  []string{"SUB-1", "SUB-2", "SUB-3"},
-		"summary":     fmt.Sprintf("Root issue %s", key),
-		"fetched_at":  fmt.Sprintf("%v", s.config.Jira.URL),
+// instance returns the environment a call is routed to
+func (s *JiraService) instance(environment string) (*jiraInstance, error) {
+	name := strings.ToLower(environment)
+	if name == "" {
+		name = s.defaultEnvironment
 	}
 
-	return result, nil
+	instance, ok := s.instances[name]
+	if !ok {
+		configured := strings.Join(s.Environments(), ", ")
+		if configured == "" {
+			configured = "none"
+		}
+		return nil, fmt.Errorf("%w %q (configured: %s)", ErrUnknownJiraEnvironment, name, configured)
+	}
+	return instance, nil
 }
 
-// FetchProjectIssues fetches all issues in a project
-func (s *JiraService) FetchProjectIssues(environment, project, user string) (map[string]interface{}, error) {
-	log.Printf("[JiraService] Fetching project issues for %s from %s for user %s", project, environment, user)
+// FieldID maps a logical field name (jira.environments.<name>.field_mappings) to the environment's
+// field ID; names without a mapping are returned unchanged
+func (s *JiraService) FieldID(environment, name string) (string, error) {
+	instance, err := s.instance(environment)
+	if err != nil {
+		return "", err
+	}
+	return instance.fieldID(name), nil
+}
 
-	// Simulate fetching data
-	result := map[string]interface{}{
-		"project":     project,
-		"environment": environment,
-		"user":        user,
-		"issue_count": 5,
-		"issues":      []string{project + "-1", project + "-2", project + "-3", project + "-4", project + "-5"},
-		"fetched_at":  fmt.Sprintf("%v", s.config.Jira.URL),
+// fieldID maps a logical field name to the instance's field ID
+func (i *jiraInstance) fieldID(name string) string {
+	if id, ok := i.fieldMappings[strings.ToLower(name)]; ok {
+		return id
 	}
+	return name
+}
 
-	return result, nil
+// IssueURL returns the browse URL of an issue in an environment
+func (s *JiraService) IssueURL(environment, key string) (string, error) {
+	instance, err := s.instance(environment)
+	if err != nil {
+		return "", err
+	}
+	return instance.client.IssueURL(key), nil
 }
 
-// UpdateIssue updates a Jira issue
+// FetchRootTicket fetches a root ticket with its sub-tasks and linked issues, recursively
+func (s *JiraService) FetchRootTicket(environment, key, user string) (*jira.IssueNode, error) {
+	instance, err := s.instance(environment)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[JiraService] Fetching root ticket %s from %s for user %s", key, instance.name, user)
+
+	tree, err := instance.client.GetIssueTree(key, jira.TreeOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("fetch issue tree of %s from %s: %w", key, instance.name, err)
+	}
+
+	log.Printf("[JiraService] Fetched %d issues under %s", tree.Count(), key)
+	return tree, nil
+}
+
+// FetchProjectIssues fetches all issues in a project, following search pagination
+func (s *JiraService) FetchProjectIssues(environment, project, user string) ([]jira.Issue, error) {
+	instance, err := s.instance(environment)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[JiraService] Fetching project issues for %s from %s for user %s", project, instance.name, user)
+
+	issues, err := instance.client.Search(fmt.Sprintf("project = %q ORDER BY key ASC", project))
+	if err != nil {
+		return nil, fmt.Errorf("search issues of project %s in %s: %w", project, instance.name, err)
+	}
+
+	log.Printf("[JiraService] Fetched %d issues of project %s", len(issues), project)
+	return issues, nil
+}
+
+// UpdateIssue updates the fields of a Jira issue. Keys are field IDs (summary, customfield_10010)
+// or logical names from the environment's field_mappings.
 func (s *JiraService) UpdateIssue(environment, key string, fields map[string]interface{}) error {
-	log.Printf("[JiraService] Updating issue %s in %s", key, environment)
+	instance, err := s.instance(environment)
+	if err != nil {
+		return err
+	}
+	log.Printf("[JiraService] Updating issue %s in %s", key, instance.name)
 
-	// Simulate updating the issue
-	log.Printf("[JiraService] Updated fields: %v", fields)
+	mapped := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		mapped[instance.fieldID(name)] = value
+	}
+	if err := instance.client.UpdateIssue(key, mapped); err != nil {
+		return fmt.Errorf("update issue %s in %s: %w", key, instance.name, err)
+	}
 
+	log.Printf("[JiraService] Updated fields of %s: %v", key, fieldNames(mapped))
 	return nil
 }
 
-// ExportToExcel exports Jira data to Excel format
-func (s *JiraService) ExportToExcel(data map[string]interface{}, filename string) error {
-	log.Printf("[JiraService] Exporting data to Excel file: %s", filename)
-
-	// Simulate exporting to Excel
-	log.Printf("[JiraService] Exported %d records", len(data))
+// TransitionIssue moves an issue through its workflow; transition is a transition ID, name or target status
+func (s *JiraService) TransitionIssue(environment, key, transition string) error {
+	instance, err := s.instance(environment)
+	if err != nil {
+		return err
+	}
+	log.Printf("[JiraService] Transitioning issue %s in %s to %s", key, instance.name, transition)
 
+	if err := instance.client.TransitionIssue(key, transition, nil); err != nil {
+		return fmt.Errorf("transition issue %s in %s: %w", key, instance.name, err)
+	}
 	return nil
 }
+
+// HealthCheck checks every environment: the server must answer serverInfo and accept the credentials
+func (s *JiraService) HealthCheck() []JiraHealth {
+	names := s.Environments()
+	results := make([]JiraHealth, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, instance *jiraInstance) {
+			defer wg.Done()
+			results[i] = instance.healthCheck()
+		}(i, s.instances[name])
+	}
+	wg.Wait()
+	return results
+}
+
+// healthCheck checks one environment
+func (i *jiraInstance) healthCheck() JiraHealth {
+	health := JiraHealth{Environment: i.name, URL: i.url}
+	start := time.Now()
+
+	info, err := i.client.ServerInfo()
+	if err == nil {
+		health.Version = info.Version
+		var user *jira.User
+		if user, err = i.client.Myself(); err == nil {
+			health.User = user.Name
+		}
+	}
+
+	health.LatencyMS = time.Since(start).Milliseconds()
+	health.Healthy = err == nil
+	if err != nil {
+		health.Error = err.Error()
+	}
+	return health
+}
+
+// fieldNames returns the names of the updated fields for logging
+func fieldNames(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
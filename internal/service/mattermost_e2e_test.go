@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/mattermost/mattermosttest"
+	"my-scheduler-go/internal/repository"
+	"my-scheduler-go/internal/scheduler"
+)
+
+// staticConfigFetcher 返回固定配置的ConfigurationFetcher
+type staticConfigFetcher []scheduler.Configuration
+
+func (f staticConfigFetcher) FetchConfigurations() ([]scheduler.Configuration, error) {
+	return f, nil
+}
+
+// forwardingSystem 按main.go的方式连接到模拟服务器的事件源、调度器和Mattermost任务处理器
+type forwardingSystem struct {
+	server *mattermosttest.Server
+	client *mattermost.Client
+	repo   repository.TaskRepository
+}
+
+// startForwardingSystem 启动模拟服务器和完整的转发链路:
+// Connection → EventListener → MattermostEventSource → SchedulerService → TaskExecutor → MattermostTaskHandler
+func startForwardingSystem(t *testing.T, configs ...scheduler.Configuration) *forwardingSystem {
+	t.Helper()
+
+	server, err := mattermosttest.NewServer("e2e-token")
+	if err != nil {
+		t.Fatalf("start fake mattermost: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	appConfig := &config.AppConfig{}
+	appConfig.Mattermost.ServerURL = server.URL
+	appConfig.Mattermost.Token = "e2e-token"
+	appConfig.Mattermost.ChannelID = "ch-src"
+	appConfig.Mattermost.ReconnectInterval = 1
+	appConfig.Storage.Path = t.TempDir()
+
+	repo := repository.NewInMemoryTaskRepository()
+	executor := scheduler.NewTaskExecutor(repo)
+	schedService := scheduler.NewSchedulerService(repo, executor, time.Second)
+	schedService.SetMaxConcurrency(1)
+
+	configService := scheduler.NewConfigurationService(staticConfigFetcher(configs), time.Hour)
+	if result := configService.Refresh(scheduler.RefreshTriggerStartup); result.Error != "" {
+		t.Fatalf("load configurations: %s", result.Error)
+	}
+
+	mmService := NewMattermostService(appConfig)
+	listener := mmService.CreateEventListener()
+	if err := mmService.ConfigureEventFilters(listener); err != nil {
+		t.Fatalf("configure event filters: %v", err)
+	}
+	eventSource := scheduler.NewMattermostEventSource(repo, listener, configService)
+	eventSource.RegisterProcessor("task_commands", scheduler.NewTaskCommandProcessor(repo, mmService))
+	eventSource.RegisterProcessor("posted_messages", scheduler.NewPostedMessageProcessor([]string{"task", "schedule", "urgent", "important"}))
+	eventSource.RegisterProcessor("post_edited", scheduler.NewPostEditedProcessor(repo, eventSource.PostTaskIndex()))
+	eventSource.RegisterProcessor("post_deleted", scheduler.NewPostDeletedProcessor(repo, eventSource.PostTaskIndex(), schedService))
+
+	handler := NewMattermostTaskHandler(mmService, appConfig)
+	executor.RegisterHandler("MATTERMOST", handler.HandleTask)
+	executor.RegisterHandler("MATTERMOST_EVENT", handler.HandleTask)
+
+	eventSource.Start()
+	schedService.Start()
+	t.Cleanup(func() {
+		eventSource.Stop()
+		schedService.Stop()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for server.ConnectedClients() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("event listener did not connect to the fake server")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return &forwardingSystem{
+		server: server,
+		client: mattermost.NewClient(server.URL, "e2e-token"),
+		repo:   repo,
+	}
+}
+
+// waitForPost 等待满足条件的消息被创建或修改，超时时测试失败
+func (f *forwardingSystem) waitForPost(t *testing.T, what string, match func(*mattermost.Post) bool) *mattermost.Post {
+	t.Helper()
+	post, ok := f.server.WaitForPost(match, 10*time.Second)
+	if !ok {
+		for _, task := range f.repo.GetAllTasks() {
+			t.Logf("task %s %q: %s %v", task.ID, task.Name, task.Status, task.ExecutionResult)
+		}
+		t.Fatalf("timed out waiting for %s; created posts: %v", what, f.server.CreatedPosts())
+	}
+	return post
+}
+
+// 场景中的消息(带附件)、线程回复和编辑依次经过完整链路转发到目标频道:
+// 附件被复制为目标频道中的新文件，回复转发到目标中的同一线程，编辑同步到已转发的消息
+func TestMattermostForwardingEndToEnd(t *testing.T) {
+	system := startForwardingSystem(t, scheduler.MattermostConfig{
+		ID:          "ops-forward",
+		ChannelID:   "ch-src",
+		ForwardType: "channel_message",
+		Custom: map[string]interface{}{
+			"target_channel_id": "ch-dst",
+			"include_files":     "true",
+		},
+	})
+	server := system.server
+
+	scenario := &mattermosttest.Scenario{
+		Name:     "e2e",
+		Users:    []mattermosttest.ScenarioUser{{ID: "user1", Username: "alice"}, {ID: "user2", Username: "bob"}},
+		Channels: []mattermosttest.ScenarioChannel{{ID: "ch-src", Name: "ops"}, {ID: "ch-dst", Name: "ops-archive"}},
+		Files:    []mattermosttest.ScenarioFile{{Ref: "log", Name: "backup.log", Content: "backup failed: disk full\n"}},
+		Steps: []mattermosttest.ScenarioStep{
+			{Action: mattermosttest.ActionPost, Channel: "ch-src", User: "user1", Message: "urgent: nightly backup failed", Ref: "backup", Files: []string{"log"}},
+		},
+	}
+	if err := server.RunScenario(context.Background(), scenario); err != nil {
+		t.Fatalf("RunScenario: %v", err)
+	}
+	sourcePosts := server.ChannelPosts("ch-src")
+	if len(sourcePosts) != 1 || len(sourcePosts[0].FileIDs) != 1 {
+		t.Fatalf("source posts = %v, want the scenario post with one file", sourcePosts)
+	}
+	source := sourcePosts[0]
+
+	// 源消息被转发到目标频道，附件复制为新文件
+	forwarded := system.waitForPost(t, "the forwarded post", func(post *mattermost.Post) bool {
+		return post.ChannelID == "ch-dst" && strings.Contains(post.Message, "nightly backup failed")
+	})
+	if forwarded.RootID != "" || !forwarded.IsFromScheduler() {
+		t.Errorf("forwarded post root_id = %q, from scheduler = %v", forwarded.RootID, forwarded.IsFromScheduler())
+	}
+	if len(forwarded.FileIDs) != 1 || forwarded.FileIDs[0] == source.FileIDs[0] {
+		t.Fatalf("forwarded file ids = %v, want one copy of %v", forwarded.FileIDs, source.FileIDs)
+	}
+	info, err := system.client.GetFileInfo(forwarded.FileIDs[0])
+	if err != nil {
+		t.Fatalf("GetFileInfo: %v", err)
+	}
+	if info.Name != "backup.log" || info.ChannelID != "ch-dst" {
+		t.Errorf("copied file = %+v, want backup.log in ch-dst", info)
+	}
+	if data, err := system.client.GetFile(forwarded.FileIDs[0], 1024); err != nil || string(data) != "backup failed: disk full\n" {
+		t.Errorf("copied file content = %q, %v", data, err)
+	}
+
+	// 线程中的回复转发为目标频道中已转发消息的回复
+	server.SimulatePost(&mattermost.Post{ChannelID: "ch-src", UserID: "user2", Message: "looking into it", RootID: source.ID})
+	reply := system.waitForPost(t, "the forwarded reply", func(post *mattermost.Post) bool {
+		return post.ChannelID == "ch-dst" && strings.Contains(post.Message, "looking into it")
+	})
+	if reply.RootID != forwarded.ID {
+		t.Errorf("forwarded reply root_id = %q, want the forwarded post %s", reply.RootID, forwarded.ID)
+	}
+
+	// 编辑源消息时同步修改已转发的消息
+	if _, err := server.SimulateEdit(source.ID, "urgent: nightly backup failed again"); err != nil {
+		t.Fatalf("SimulateEdit: %v", err)
+	}
+	system.waitForPost(t, "the edited forwarded post", func(post *mattermost.Post) bool {
+		return post.ID == forwarded.ID && strings.Contains(post.Message, "failed again")
+	})
+
+	if posts := server.ChannelPosts("ch-dst"); len(posts) != 2 {
+		t.Errorf("target channel has %d posts, want the forwarded post and its reply", len(posts))
+	}
+}
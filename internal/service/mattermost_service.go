@@ -1,9 +1,14 @@
 package service
 
 import (
+	"fmt"
 	"log"
 	"my-scheduler-go/internal/config"
 	"my-scheduler-go/internal/mattermost"
+	"my-scheduler-go/internal/scheduler"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -11,6 +16,10 @@ import (
 type MattermostService struct {
 	appConfig *config.AppConfig
 	conn      *mattermost.Connection
+	client    *mattermost.Client
+	botUserMu sync.Mutex
+	botUserID string
+	watch     *mattermost.WatchFilter // 监听的频道和团队，随配置更新
 }
 
 // NewMattermostService 创建新的Mattermost服务
@@ -19,12 +28,26 @@ func NewMattermostService(appConfig *config.AppConfig) *MattermostService {
 	reconnectInterval := time.Duration(appConfig.Mattermost.ReconnectInterval)
 	conn := mattermost.NewConnection(appConfig.Mattermost.ServerURL, appConfig.Mattermost.Token, reconnectInterval)
 
+	// 开发模式下ServerURL指向进程内的模拟服务器
 	return &MattermostService{
 		appConfig: appConfig,
 		conn:      conn,
+		client:    mattermost.NewClient(appConfig.Mattermost.ServerURL, appConfig.Mattermost.Token),
+		watch:     mattermost.NewWatchFilter(StaticWatchedChannels(appConfig), appConfig.Mattermost.WatchTeams),
 	}
 }
 
+// StaticWatchedChannels 返回配置文件中指定监听的频道: channel_id和watch_channels
+func StaticWatchedChannels(appConfig *config.AppConfig) []string {
+	channels := []string{appConfig.Mattermost.ChannelID}
+	return append(channels, appConfig.Mattermost.WatchChannels...)
+}
+
+// WatchFilter 返回监听频道和团队的过滤器，配置更新时由调用方更新监听集合
+func (s *MattermostService) WatchFilter() *mattermost.WatchFilter {
+	return s.watch
+}
+
 // Connect 连接到Mattermost服务器
 func (s *MattermostService) Connect() error {
 	log.Printf("[MattermostService] Connecting to %s", s.appConfig.Mattermost.ServerURL)
@@ -37,31 +60,288 @@ func (s *MattermostService) Disconnect() error {
 	return s.conn.Close()
 }
 
+// CreatePost 创建消息，返回带有ID的消息
+func (s *MattermostService) CreatePost(post *mattermost.Post) (*mattermost.Post, error) {
+	if post.Props == nil {
+		post.Props = make(map[string]interface{})
+	}
+	post.Props[mattermost.PropFromScheduler] = true
+
+	return s.client.CreatePost(post)
+}
+
+// UpdatePost 修改已发送消息的内容
+func (s *MattermostService) UpdatePost(postID, message string) error {
+	_, err := s.client.PatchPost(postID, message)
+	return err
+}
+
+// DeletePost 删除已发送的消息
+func (s *MattermostService) DeletePost(postID string) error {
+	return s.client.DeletePost(postID)
+}
+
+// GetDirectChannelID 获取与指定用户的私聊频道ID
+func (s *MattermostService) GetDirectChannelID(userID string) (string, error) {
+	botUserID, err := s.getBotUserID()
+	if err != nil {
+		return "", err
+	}
+
+	channel, err := s.client.CreateDirectChannel(botUserID, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to open direct channel with %s: %v", userID, err)
+	}
+	return channel.ID, nil
+}
+
+// getBotUserID 获取并缓存访问令牌对应的用户ID
+func (s *MattermostService) getBotUserID() (string, error) {
+	s.botUserMu.Lock()
+	defer s.botUserMu.Unlock()
+
+	if s.botUserID != "" {
+		return s.botUserID, nil
+	}
+
+	me, err := s.client.GetMe()
+	if err != nil {
+		return "", fmt.Errorf("failed to get bot user: %v", err)
+	}
+	s.botUserID = me.ID
+	return s.botUserID, nil
+}
+
+// CopyFile 下载文件并重新上传到目标频道，返回新文件的元数据
+// maxBytes大于0时，超过大小限制的文件会返回错误
+func (s *MattermostService) CopyFile(fileID, channelID string, maxBytes int64) (*mattermost.FileInfo, error) {
+	info, err := s.client.GetFileInfo(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %v", err)
+	}
+	if maxBytes > 0 && info.Size > maxBytes {
+		return nil, fmt.Errorf("file %s (%d bytes) exceeds size limit of %d bytes", info.Name, info.Size, maxBytes)
+	}
+
+	data, err := s.client.GetFile(fileID, maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file %s: %v", info.Name, err)
+	}
+
+	uploaded, err := s.client.UploadFile(channelID, info.Name, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file %s: %v", info.Name, err)
+	}
+
+	log.Printf("[MattermostService] File %s copied to channel %s as %s", info.Name, channelID, uploaded.ID)
+	return uploaded, nil
+}
+
 // SendDirectMessage 发送直接消息给用户
 func (s *MattermostService) SendDirectMessage(userID, message string) error {
 	log.Printf("[MattermostService] Sending direct message to user %s", userID)
+	_, err := s.SendDirectPost(userID, message, "")
+	return err
+}
 
-	// 在真实实现中，这里会调用Mattermost API发送消息
-	// 现在我们只记录日志
-	log.Printf("[MattermostService] Direct message sent to user %s (simulation)", userID)
+// SendDirectPost 发送直接消息给用户，rootID不为空时作为线程回复发送
+func (s *MattermostService) SendDirectPost(userID, message, rootID string) (*mattermost.Post, error) {
+	channelID, err := s.GetDirectChannelID(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return s.CreatePost(&mattermost.Post{
+		ChannelID: channelID,
+		RootID:    rootID,
+		Message:   message,
+	})
 }
 
 // SendChannelMessage 发送消息到频道
 func (s *MattermostService) SendChannelMessage(channelID, message string) error {
 	log.Printf("[MattermostService] Sending message to channel %s", channelID)
+	_, err := s.CreatePost(&mattermost.Post{
+		ChannelID: channelID,
+		Message:   message,
+	})
+	return err
+}
 
-	// 在真实实现中，这里会调用Mattermost API发送消息
-	// 现在我们只记录日志
-	log.Printf("[MattermostService] Message sent to channel %s (simulation)", channelID)
+// SendChannelPost 发送带附件的消息到频道，返回创建的消息
+func (s *MattermostService) SendChannelPost(channelID, message string, attachments []*mattermost.Attachment) (*mattermost.Post, error) {
+	log.Printf("[MattermostService] Sending post with %d attachment(s) to channel %s", len(attachments), channelID)
 
-	return nil
+	post := &mattermost.Post{
+		ChannelID: channelID,
+		Message:   message,
+		Props:     map[string]interface{}{},
+	}
+	if len(attachments) > 0 {
+		post.Props["attachments"] = attachments
+	}
+
+	return s.CreatePost(post)
+}
+
+// SendReply 在指定线程中回复消息
+func (s *MattermostService) SendReply(channelID, rootID, message string) (*mattermost.Post, error) {
+	log.Printf("[MattermostService] Replying in thread %s of channel %s", rootID, channelID)
+	return s.CreatePost(&mattermost.Post{
+		ChannelID: channelID,
+		RootID:    rootID,
+		Message:   message,
+	})
 }
 
 // CreateEventListener 创建事件监听器
-func (s *MattermostService) CreateEventListener(useMockEvents bool) *mattermost.EventListener {
-	return mattermost.NewEventListener(s.conn, useMockEvents)
+func (s *MattermostService) CreateEventListener() *mattermost.EventListener {
+	listener := mattermost.NewEventListener(s.conn)
+
+	// 事件经过有界队列异步处理，避免处理过慢阻塞WebSocket读取
+	queueConfig := s.appConfig.Mattermost.EventQueue
+	spillDir := queueConfig.SpillDir
+	if spillDir == "" {
+		spillDir = filepath.Join(s.appConfig.Storage.Path, "event_spill")
+	}
+	if err := listener.EnableQueue(mattermost.EventQueueOptions{
+		Capacity: queueConfig.Capacity,
+		Workers:  queueConfig.Workers,
+		Overflow: mattermost.OverflowPolicy(queueConfig.Overflow),
+		SpillDir: spillDir,
+	}); err != nil {
+		log.Printf("[MattermostService] Failed to create event queue, events are processed synchronously: %v", err)
+	}
+
+	// 记录接收到的每个事件及其处理过程，供查询和重放
+	storeConfig := s.appConfig.Mattermost.EventStore
+	storePath := storeConfig.Path
+	if storePath == "" {
+		storePath = filepath.Join(s.appConfig.Storage.Path, "mattermost_events.json")
+	}
+	store, err := mattermost.NewEventStore(mattermost.EventStoreOptions{
+		Path:      storePath,
+		Retention: time.Duration(storeConfig.RetentionHours) * time.Hour,
+		MaxEvents: storeConfig.MaxEvents,
+	})
+	if err != nil {
+		log.Printf("[MattermostService] Failed to load event store, events are not recorded: %v", err)
+	} else {
+		listener.EnableEventStore(store)
+	}
+
+	// 启用检查点: 事件去重，并在(重新)连接后通过REST API补齐错过的消息
+	checkpointPath := filepath.Join(s.appConfig.Storage.Path, "mattermost_checkpoint.json")
+	checkpoint, err := mattermost.LoadCheckpoint(checkpointPath)
+	if err != nil {
+		log.Printf("[MattermostService] Failed to load checkpoint, event recovery disabled: %v", err)
+		return listener
+	}
+
+	listener.EnableRecovery(checkpoint, s.client)
+
+	return listener
+}
+
+// defaultEventTypes 未配置事件过滤器时处理的事件类型
+var defaultEventTypes = []string{
+	string(mattermost.EventTypePosted),
+	string(mattermost.EventTypePostEdited),
+	string(mattermost.EventTypePostDeleted),
+	string(mattermost.EventTypeUserAdded),
+}
+
+// ConfigureEventFilters 根据配置的event_filters为事件监听器添加过滤器，
+// 未配置时只处理监听的频道和团队中的消息和用户事件
+func (s *MattermostService) ConfigureEventFilters(listener *mattermost.EventListener) error {
+	filterConfig := s.appConfig.Mattermost.EventFilters
+	if filterConfig.IsEmpty() {
+		filterConfig = config.EventFilterConfig{
+			Watched:    true,
+			EventTypes: defaultEventTypes,
+		}
+	}
+
+	filter, err := s.BuildEventFilter(filterConfig)
+	if err != nil {
+		return fmt.Errorf("invalid event filters: %v", err)
+	}
+	listener.AddFilter(filter)
+	return nil
+}
+
+// BuildEventFilter 根据配置构建事件过滤器，同一节点上的条件需要同时满足
+func (s *MattermostService) BuildEventFilter(filterConfig config.EventFilterConfig) (mattermost.EventFilter, error) {
+	var filters []mattermost.EventFilter
+
+	if filterConfig.Watched {
+		filters = append(filters, s.watch)
+	}
+
+	if len(filterConfig.Channels) > 0 {
+		filters = append(filters, &mattermost.ChannelFilter{ChannelIDs: filterConfig.Channels})
+	}
+	if len(filterConfig.EventTypes) > 0 {
+		eventTypes := make([]mattermost.EventType, len(filterConfig.EventTypes))
+		for i, eventType := range filterConfig.EventTypes {
+			eventTypes[i] = mattermost.EventType(eventType)
+		}
+		filters = append(filters, &mattermost.EventTypeFilter{EventTypes: eventTypes})
+	}
+	if len(filterConfig.Users) > 0 {
+		filters = append(filters, &mattermost.UserFilter{Users: filterConfig.Users})
+	}
+	if len(filterConfig.Teams) > 0 {
+		filters = append(filters, &mattermost.TeamFilter{TeamIDs: filterConfig.Teams})
+	}
+	if len(filterConfig.ChannelTypes) > 0 {
+		filters = append(filters, &mattermost.ChannelTypeFilter{Types: filterConfig.ChannelTypes})
+	}
+	if filterConfig.Pattern != "" {
+		regexFilter, err := mattermost.NewRegexFilter(filterConfig.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", filterConfig.Pattern, err)
+		}
+		filters = append(filters, regexFilter)
+	}
+	if filterConfig.ExcludeBotPosts {
+		filters = append(filters, &mattermost.BotPostFilter{})
+	}
+
+	if len(filterConfig.All) > 0 {
+		allFilter := &mattermost.AllFilter{}
+		for _, child := range filterConfig.All {
+			filter, err := s.BuildEventFilter(child)
+			if err != nil {
+				return nil, err
+			}
+			allFilter.Filters = append(allFilter.Filters, filter)
+		}
+		filters = append(filters, allFilter)
+	}
+	if len(filterConfig.Any) > 0 {
+		anyFilter := &mattermost.AnyFilter{}
+		for _, child := range filterConfig.Any {
+			filter, err := s.BuildEventFilter(child)
+			if err != nil {
+				return nil, err
+			}
+			anyFilter.Filters = append(anyFilter.Filters, filter)
+		}
+		filters = append(filters, anyFilter)
+	}
+	if filterConfig.Not != nil {
+		filter, err := s.BuildEventFilter(*filterConfig.Not)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, &mattermost.NotFilter{Filter: filter})
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return &mattermost.AllFilter{Filters: filters}, nil
 }
 
 // AddChannelFilter 为事件监听器添加频道过滤器
@@ -80,6 +360,37 @@ func (s *MattermostService) AddEventTypeFilter(listener *mattermost.EventListene
 	listener.AddFilter(filter)
 }
 
+// NotifyConfigChange 将配置变更摘要发送到管理频道，首次加载的配置不发送
+func (s *MattermostService) NotifyConfigChange(snapshot *scheduler.ConfigSnapshot) {
+	if snapshot.Version <= 1 || len(snapshot.Changes) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	summary := snapshot.Summary()
+	sb.WriteString(fmt.Sprintf("#### 配置已更新 (版本 %d)\n\n", snapshot.Version))
+	sb.WriteString(fmt.Sprintf("新增 %d，删除 %d，修改 %d\n\n",
+		summary[scheduler.ConfigChangeAdded], summary[scheduler.ConfigChangeRemoved], summary[scheduler.ConfigChangeModified]))
+	sb.WriteString("| 变更 | 种类 | ID | 字段 |\n")
+	sb.WriteString("|:-----|:-----|:---|:-----|\n")
+	for _, change := range snapshot.Changes {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+			change.Type, change.Kind, change.ID, strings.Join(change.Fields, ", ")))
+	}
+
+	if err := s.SendChannelMessage(s.adminChannelID(), sb.String()); err != nil {
+		log.Printf("[MattermostService] Failed to send configuration change summary: %v", err)
+	}
+}
+
+// adminChannelID 返回管理频道，未配置admin_channel_id时使用channel_id
+func (s *MattermostService) adminChannelID() string {
+	if s.appConfig.Mattermost.AdminChannelID != "" {
+		return s.appConfig.Mattermost.AdminChannelID
+	}
+	return s.appConfig.Mattermost.ChannelID
+}
+
 // SendTaskReport 发送任务报告到配置的频道
 func (s *MattermostService) SendTaskReport(report string) error {
 	channelID := s.appConfig.Mattermost.ChannelID
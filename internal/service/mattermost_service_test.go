@@ -0,0 +1,118 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/mattermost"
+)
+
+// README中的event_filters示例，经配置文件加载后构建过滤器
+const eventFiltersYAML = `
+mattermost:
+  channel_id: "ch-ops"
+  watch_teams: ["team-dev"]
+  event_filters:
+    all:
+      - watched: true
+      - any:
+          - event_types: ["post_edited", "post_deleted"]
+          - event_types: ["posted"]
+            pattern: "(?i)urgent|task"
+      - not:
+          users: ["alertbot"]
+`
+
+func TestBuildEventFilterFromYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(eventFiltersYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appConfig, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	filterConfig := appConfig.Mattermost.EventFilters
+	if len(filterConfig.All) != 3 || len(filterConfig.All[1].Any) != 2 || filterConfig.All[2].Not == nil {
+		t.Fatalf("event_filters decoded as %+v", filterConfig)
+	}
+
+	service := NewMattermostService(appConfig)
+	filter, err := service.BuildEventFilter(filterConfig)
+	if err != nil {
+		t.Fatalf("BuildEventFilter: %v", err)
+	}
+
+	ops := &mattermost.Channel{ID: "ch-ops"}
+	post := func(eventType mattermost.EventType, channel *mattermost.Channel, username, message string) *mattermost.Event {
+		return &mattermost.Event{
+			Type:    eventType,
+			Channel: channel,
+			User:    &mattermost.User{ID: "u-" + username, Username: username},
+			Post:    &mattermost.Post{ID: "p1", ChannelID: channel.ID, Message: message},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		event *mattermost.Event
+		want  bool
+	}{
+		{name: "urgent post", event: post(mattermost.EventTypePosted, ops, "alice", "Urgent: disk full"), want: true},
+		{name: "task post in a watched team", event: post(mattermost.EventTypePosted, &mattermost.Channel{ID: "ch-dev", TeamID: "team-dev"}, "alice", "new task"), want: true},
+		{name: "edit without a keyword", event: post(mattermost.EventTypePostEdited, ops, "alice", "fixed typo"), want: true},
+		{name: "post without a keyword", event: post(mattermost.EventTypePosted, ops, "alice", "lunch?"), want: false},
+		{name: "typing in the watched channel", event: &mattermost.Event{Type: mattermost.EventTypeTyping, Channel: ops}, want: false},
+		{name: "user added to the watched channel", event: &mattermost.Event{Type: mattermost.EventTypeUserAdded, Channel: ops}, want: false},
+		{name: "urgent post in an unwatched channel", event: post(mattermost.EventTypePosted, &mattermost.Channel{ID: "ch-random"}, "alice", "urgent"), want: false},
+		{name: "urgent post by an excluded user", event: post(mattermost.EventTypePosted, ops, "AlertBot", "urgent"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.ShouldProcess(tt.event); got != tt.want {
+				t.Errorf("ShouldProcess = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 配置刷新后监听集合变化，已构建的过滤器随之生效
+	service.WatchFilter().Update([]string{"ch-random"}, nil)
+	if !filter.ShouldProcess(post(mattermost.EventTypePosted, &mattermost.Channel{ID: "ch-random"}, "alice", "urgent")) {
+		t.Error("post in a newly watched channel was rejected")
+	}
+	if filter.ShouldProcess(post(mattermost.EventTypePosted, ops, "alice", "urgent")) {
+		t.Error("post in a channel no longer watched was accepted")
+	}
+}
+
+// 未配置event_filters时的默认过滤器要求事件同时来自监听的频道并属于消息或用户事件
+func TestDefaultEventFilterRejectsTyping(t *testing.T) {
+	service := NewMattermostService(&config.AppConfig{})
+	service.WatchFilter().Update([]string{"ch-ops"}, nil)
+	filter, err := service.BuildEventFilter(config.EventFilterConfig{Watched: true, EventTypes: defaultEventTypes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := &mattermost.Channel{ID: "ch-ops"}
+	if filter.ShouldProcess(&mattermost.Event{Type: mattermost.EventTypeTyping, Channel: ops}) {
+		t.Error("typing event in the watched channel was accepted")
+	}
+	if !filter.ShouldProcess(&mattermost.Event{Type: mattermost.EventTypePosted, Channel: ops, Post: &mattermost.Post{ID: "p1"}}) {
+		t.Error("post in the watched channel was rejected")
+	}
+	if filter.ShouldProcess(&mattermost.Event{Type: mattermost.EventTypePosted, Channel: &mattermost.Channel{ID: "ch-random"}, Post: &mattermost.Post{ID: "p2"}}) {
+		t.Error("post in an unwatched channel was accepted")
+	}
+}
+
+func TestBuildEventFilterInvalidPattern(t *testing.T) {
+	service := NewMattermostService(&config.AppConfig{})
+	filterConfig := config.EventFilterConfig{Any: []config.EventFilterConfig{{Pattern: "("}}}
+	if _, err := service.BuildEventFilter(filterConfig); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("BuildEventFilter error = %v, want an invalid pattern error", err)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"fmt"
 	"log"
 	"my-scheduler-go/internal/config"
+	"my-scheduler-go/internal/mattermost"
 	"my-scheduler-go/internal/models"
+	"time"
 )
 
+// defaultMaxFileSize 转发附件的默认大小上限(10MB)
+const defaultMaxFileSize = 10 * 1024 * 1024
+
 // MattermostTaskHandler 处理Mattermost相关的任务
 type MattermostTaskHandler struct {
 	mmService *MattermostService
 	appConfig *config.AppConfig
+	threads   *ThreadTracker
 }
 
 // NewMattermostTaskHandler 创建Mattermost任务处理器
@@ -18,6 +24,7 @@ func NewMattermostTaskHandler(mmService *MattermostService, appConfig *config.Ap
 	return &MattermostTaskHandler{
 		mmService: mmService,
 		appConfig: appConfig,
+		threads:   NewThreadTracker(),
 	}
 }
 
@@ -51,6 +58,10 @@ func (h *MattermostTaskHandler) HandleTask(task *models.Task) error {
 		return h.handleChannelMessage(task)
 	case "notification":
 		return h.handleNotification(task)
+	case "edit_forward":
+		return h.handleEditForward(task)
+	case "delete_forward":
+		return h.handleDeleteForward(task)
 	default:
 		return fmt.Errorf("unknown forward type: %s", forwardType)
 	}
@@ -74,28 +85,18 @@ func (h *MattermostTaskHandler) handleDirectMessage(task *models.Task) error {
 	}
 
 	// 获取消息内容
-	var message string
-	if originalMessage, ok := params["message"].(string); ok {
-		message = fmt.Sprintf("转发消息: %s", originalMessage)
-	} else {
-		message = "收到了一条新通知"
-	}
-
-	// 添加消息元数据
-	if channelName, ok := params["channel_name"].(string); ok {
-		message += fmt.Sprintf("\n\n来源频道: %s", channelName)
-	}
-
-	if username, ok := params["username"].(string); ok {
-		message += fmt.Sprintf("\n原始发送者: %s", username)
-	}
+	message := formatForwardMessage(params, "收到了一条新通知")
 
 	// 发送直接消息
-	err := h.mmService.SendDirectMessage(targetUserID, message)
+	channelID, err := h.mmService.GetDirectChannelID(targetUserID)
 	if err != nil {
 		return fmt.Errorf("failed to send direct message: %v", err)
 	}
 
+	if err := h.forwardPost(task, "user:"+targetUserID, channelID, message); err != nil {
+		return fmt.Errorf("failed to send direct message: %v", err)
+	}
+
 	log.Printf("[MattermostTaskHandler] Sent direct message to user %s", targetUserID)
 	return nil
 }
@@ -118,11 +119,55 @@ func (h *MattermostTaskHandler) handleChannelMessage(task *models.Task) error {
 	}
 
 	// 获取消息内容
+	message := formatForwardMessage(params, "收到了一条需要处理的新通知")
+
+	// 发送频道消息
+	if err := h.forwardPost(task, "channel:"+targetChannelID, targetChannelID, message); err != nil {
+		return fmt.Errorf("failed to send channel message: %v", err)
+	}
+
+	log.Printf("[MattermostTaskHandler] Sent message to channel %s", targetChannelID)
+	return nil
+}
+
+// 同步源消息的编辑到已转发的消息
+func (h *MattermostTaskHandler) handleEditForward(task *models.Task) error {
+	params := task.Parameters
+	message := formatForwardMessage(params, "")
+
+	postIDs := stringSlice(params["forwarded_post_ids"])
+	for _, postID := range postIDs {
+		if err := h.mmService.UpdatePost(postID, message); err != nil {
+			return fmt.Errorf("failed to update forwarded post %s: %v", postID, err)
+		}
+	}
+
+	setExecutionResult(task, "updated_post_ids", postIDs)
+	log.Printf("[MattermostTaskHandler] Updated %d forwarded post(s)", len(postIDs))
+	return nil
+}
+
+// 同步源消息的删除到已转发的消息
+func (h *MattermostTaskHandler) handleDeleteForward(task *models.Task) error {
+	postIDs := stringSlice(task.Parameters["forwarded_post_ids"])
+	for _, postID := range postIDs {
+		if err := h.mmService.DeletePost(postID); err != nil {
+			return fmt.Errorf("failed to delete forwarded post %s: %v", postID, err)
+		}
+	}
+
+	setExecutionResult(task, "deleted_post_ids", postIDs)
+	log.Printf("[MattermostTaskHandler] Deleted %d forwarded post(s)", len(postIDs))
+	return nil
+}
+
+// formatForwardMessage 生成转发消息的内容，没有原始消息时使用fallback
+func formatForwardMessage(params map[string]interface{}, fallback string) string {
 	var message string
 	if originalMessage, ok := params["message"].(string); ok {
 		message = fmt.Sprintf("转发消息: %s", originalMessage)
 	} else {
-		message = "收到了一条需要处理的新通知"
+		message = fallback
 	}
 
 	// 添加消息元数据
@@ -134,16 +179,124 @@ func (h *MattermostTaskHandler) handleChannelMessage(task *models.Task) error {
 		message += fmt.Sprintf("\n原始发送者: %s", username)
 	}
 
-	// 发送频道消息
-	err := h.mmService.SendChannelMessage(targetChannelID, message)
+	return message
+}
+
+// forwardPost 将消息(及附件)转发到目标频道，并把转发结果记录到任务执行结果中
+//
+// 源消息所在线程已被转发到同一目标时，消息会作为该目标线程的回复发送；
+// 否则新创建的消息会被记录为该线程在目标中的根消息。
+func (h *MattermostTaskHandler) forwardPost(task *models.Task, target, channelID, message string) error {
+	params := task.Parameters
+	post := &mattermost.Post{
+		ChannelID: channelID,
+		Message:   message,
+	}
+
+	if customParam(params, "include_files") == "true" {
+		post.FileIDs = h.copyFiles(task, channelID)
+	}
+
+	sourceRootID := sourceThreadRootID(params)
+	if sourceRootID != "" {
+		post.RootID, _ = h.threads.TargetRoot(sourceRootID, target)
+	}
+
+	created, err := h.mmService.CreatePost(post)
 	if err != nil {
-		return fmt.Errorf("failed to send channel message: %v", err)
+		return err
 	}
 
-	log.Printf("[MattermostTaskHandler] Sent message to channel %s", targetChannelID)
+	if sourceRootID != "" && post.RootID == "" {
+		h.threads.SetTargetRoot(sourceRootID, target, created.ID)
+	}
+
+	setExecutionResult(task, "forwarded_post_id", created.ID)
+	setExecutionResult(task, "forwarded_channel_id", channelID)
 	return nil
 }
 
+// copyFiles 将源消息的附件复制到目标频道，返回新文件ID
+// 超过大小限制或复制失败的文件会被跳过，并记录到任务执行结果中
+func (h *MattermostTaskHandler) copyFiles(task *models.Task, channelID string) []string {
+	sourceFileIDs := stringSlice(task.Parameters["file_ids"])
+	if len(sourceFileIDs) == 0 {
+		return nil
+	}
+
+	maxBytes := h.appConfig.Mattermost.MaxFileSize
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFileSize
+	}
+
+	var fileIDs []string
+	var skipped []string
+	for _, fileID := range sourceFileIDs {
+		info, err := h.mmService.CopyFile(fileID, channelID, maxBytes)
+		if err != nil {
+			log.Printf("[MattermostTaskHandler] Skipping file %s: %v", fileID, err)
+			skipped = append(skipped, fmt.Sprintf("%s: %v", fileID, err))
+			continue
+		}
+		fileIDs = append(fileIDs, info.ID)
+	}
+
+	setExecutionResult(task, "source_file_ids", sourceFileIDs)
+	setExecutionResult(task, "forwarded_file_ids", fileIDs)
+	if len(skipped) > 0 {
+		setExecutionResult(task, "skipped_files", skipped)
+	}
+
+	return fileIDs
+}
+
+// customParam 从任务参数或Custom配置中读取字符串参数
+func customParam(params map[string]interface{}, key string) string {
+	if value, ok := params[key].(string); ok {
+		return value
+	}
+	if customMap, ok := params["custom"].(map[string]interface{}); ok {
+		value, _ := customMap[key].(string)
+		return value
+	}
+	return ""
+}
+
+// stringSlice 将[]string或JSON解码后的[]interface{}转换为[]string
+func stringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// setExecutionResult 在任务执行结果中记录一个字段
+func setExecutionResult(task *models.Task, key string, value interface{}) {
+	if task.ExecutionResult == nil {
+		task.ExecutionResult = make(map[string]interface{})
+	}
+	task.ExecutionResult[key] = value
+}
+
+// sourceThreadRootID 从任务参数中获取源消息所在线程的根消息ID
+func sourceThreadRootID(params map[string]interface{}) string {
+	if rootID, ok := params["root_id"].(string); ok && rootID != "" {
+		return rootID
+	}
+	postID, _ := params["post_id"].(string)
+	return postID
+}
+
 // 处理通知
 func (h *MattermostTaskHandler) handleNotification(task *models.Task) error {
 	params := task.Parameters
@@ -174,11 +327,16 @@ func (h *MattermostTaskHandler) handleNotification(task *models.Task) error {
 		message += fmt.Sprintf("\n涉及频道: %s", channelID)
 	}
 
+	// 附带可重试的交互按钮
+	attachments := []*mattermost.Attachment{
+		h.taskAttachment(task, "", h.newAction(mattermost.ActionRetry, "Retry", "primary", task.ID)),
+	}
+
 	// 发送通知
 	if notifyAdmin {
 		// 发送给管理员
 		adminChannelID := h.appConfig.Mattermost.ChannelID // 使用配置的管理员频道
-		err := h.mmService.SendChannelMessage(adminChannelID, message)
+		_, err := h.mmService.SendChannelPost(adminChannelID, message, attachments)
 		if err != nil {
 			return fmt.Errorf("failed to send admin notification: %v", err)
 		}
@@ -186,7 +344,7 @@ func (h *MattermostTaskHandler) handleNotification(task *models.Task) error {
 	} else {
 		// 发送给默认频道
 		defaultChannelID := h.appConfig.Mattermost.ChannelID
-		err := h.mmService.SendChannelMessage(defaultChannelID, message)
+		_, err := h.mmService.SendChannelPost(defaultChannelID, message, attachments)
 		if err != nil {
 			return fmt.Errorf("failed to send notification: %v", err)
 		}
@@ -195,3 +353,91 @@ func (h *MattermostTaskHandler) handleNotification(task *models.Task) error {
 
 	return nil
 }
+
+// OnTaskStatusChanged 实现scheduler.TaskStatusListener，在源消息线程中回复任务进度，
+// 并为失败和待审批的任务发送交互消息
+func (h *MattermostTaskHandler) OnTaskStatusChanged(task *models.Task) {
+	h.postThreadStatus(task)
+
+	var message string
+	var attachment *mattermost.Attachment
+
+	switch task.Status {
+	case models.StatusFailed, models.StatusTimeout:
+		message = fmt.Sprintf("任务执行失败: **%s**", task.Name)
+		attachment = h.taskAttachment(task, "#d24b4e",
+			h.newAction(mattermost.ActionRetry, "Retry", "primary", task.ID),
+			h.newAction(mattermost.ActionCancel, "Cancel", "danger", task.ID),
+		)
+	case models.StatusAwaitingApproval:
+		message = fmt.Sprintf("任务等待审批: **%s**", task.Name)
+		attachment = h.taskAttachment(task, "#ffbc1f",
+			h.newAction(mattermost.ActionApprove, "Approve", "good", task.ID),
+			h.newAction(mattermost.ActionCancel, "Cancel", "danger", task.ID),
+		)
+	default:
+		return
+	}
+
+	channelID := h.appConfig.Mattermost.ChannelID
+	if _, err := h.mmService.SendChannelPost(channelID, message, []*mattermost.Attachment{attachment}); err != nil {
+		log.Printf("[MattermostTaskHandler] Failed to post %s notice for task %s: %v", task.Status, task.ID, err)
+	}
+}
+
+// postThreadStatus 在创建任务的源消息线程中回复任务状态
+func (h *MattermostTaskHandler) postThreadStatus(task *models.Task) {
+	if !h.appConfig.Mattermost.ThreadStatusUpdates {
+		return
+	}
+
+	channelID, _ := task.Parameters["channel_id"].(string)
+	rootID := sourceThreadRootID(task.Parameters)
+	if channelID == "" || rootID == "" {
+		return
+	}
+
+	var message string
+	switch task.Status {
+	case models.StatusRunning:
+		message = fmt.Sprintf("任务 **%s** 已开始执行", task.Name)
+	case models.StatusRetry:
+		message = fmt.Sprintf("任务 **%s** 执行失败，将在 %s 进行第 %d 次重试",
+			task.Name, task.NextRunAt.Format(time.RFC3339), task.RetryCount)
+	case models.StatusDone:
+		message = fmt.Sprintf("任务 **%s** 已完成", task.Name)
+	case models.StatusFailed, models.StatusTimeout:
+		message = fmt.Sprintf("任务 **%s** 执行失败 (%s)", task.Name, task.Status)
+	default:
+		return
+	}
+
+	if _, err := h.mmService.SendReply(channelID, rootID, message); err != nil {
+		log.Printf("[MattermostTaskHandler] Failed to post status of task %s to thread %s: %v", task.ID, rootID, err)
+	}
+}
+
+// taskAttachment 创建包含任务信息和按钮的附件
+func (h *MattermostTaskHandler) taskAttachment(task *models.Task, color string, actions ...*mattermost.PostAction) *mattermost.Attachment {
+	fields := []*mattermost.AttachmentField{
+		{Title: "Task ID", Value: task.ID, Short: true},
+		{Title: "Status", Value: string(task.Status), Short: true},
+	}
+	if result, ok := task.ExecutionResult["result"]; ok {
+		fields = append(fields, &mattermost.AttachmentField{Title: "Result", Value: fmt.Sprintf("%v", result)})
+	}
+
+	return &mattermost.Attachment{
+		Fallback: task.Name,
+		Color:    color,
+		Title:    task.Name,
+		Fields:   fields,
+		Actions:  actions,
+	}
+}
+
+// newAction 创建带签名的任务按钮
+func (h *MattermostTaskHandler) newAction(action, name, style, taskID string) *mattermost.PostAction {
+	cfg := h.appConfig.Mattermost
+	return mattermost.NewTaskAction(action, name, style, cfg.ActionCallbackURL, cfg.ActionSecret, taskID)
+}
@@ -164,6 +164,26 @@ func (s *ResultReportingService) GenerateReport(reportType string) (string, erro
 	return strategy.GenerateReport(doneTasks)
 }
 
+// HandleReportTask generates and publishes the report named by the task's report_type parameter
+func (s *ResultReportingService) HandleReportTask(task *models.Task) error {
+	reportType, _ := task.Parameters["report_type"].(string)
+	strategy, exists := s.reportStrategies[reportType]
+	if !exists {
+		return fmt.Errorf("unknown report type: %s", reportType)
+	}
+
+	reportData, err := strategy.GenerateReport(s.repo.GetTasksByStatus(models.StatusDone))
+	if err != nil {
+		return err
+	}
+	if err := strategy.PublishReport(reportData); err != nil {
+		return err
+	}
+
+	log.Printf("[ReportingService] Published scheduled %s report for task %s", reportType, task.ID)
+	return nil
+}
+
 // =============== Confluence Reporter Implementation =============== //
 
 // GenerateReport creates a report for Confluence
@@ -218,10 +238,6 @@ func (r *ConfluenceReporter) PublishReport(reportData string) error {
 		r.confluenceService = NewConfluenceService(r.config)
 	}
 
-	// In a real implementation, this would:
-	// 1. Connect to the Confluence API
-	// 2. Update the page specified in the config
-
 	pageID := r.config.Confluence.ResultsPage
 	if pageID == "" {
 		// Fallback to the page ID in reporting config
@@ -289,20 +305,11 @@ func (r *MattermostReporter) PublishReport(reportData string) error {
 		r.mattermostService = NewMattermostService(r.config)
 	}
 
-	// Connect to Mattermost
-	err := r.mattermostService.Connect()
-	if err != nil {
-		return fmt.Errorf("failed to connect to Mattermost: %v", err)
-	}
-
-	// Send the report
-	err = r.mattermostService.SendTaskReport(reportData)
+	// Send the report via the REST API (no WebSocket connection needed)
+	err := r.mattermostService.SendTaskReport(reportData)
 	if err != nil {
 		return fmt.Errorf("failed to send report to Mattermost: %v", err)
 	}
 
-	// Disconnect
-	_ = r.mattermostService.Disconnect()
-
 	return nil
 }
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// threadTTL 线程映射的保留时间，超过后回复会作为新消息转发
+const threadTTL = 7 * 24 * time.Hour
+
+// threadEntry 记录一个转发目标中的线程根消息
+type threadEntry struct {
+	targetRootID string
+	updatedAt    time.Time
+}
+
+// ThreadTracker 记录源线程根消息与转发目标中线程根消息之间的映射
+type ThreadTracker struct {
+	mu      sync.Mutex
+	threads map[string]*threadEntry
+}
+
+// NewThreadTracker 创建线程映射记录器
+func NewThreadTracker() *ThreadTracker {
+	return &ThreadTracker{
+		threads: make(map[string]*threadEntry),
+	}
+}
+
+// TargetRoot 查找源线程在转发目标(频道或用户)中对应的根消息ID
+func (t *ThreadTracker) TargetRoot(sourceRootID, target string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.threads[threadKey(sourceRootID, target)]
+	if !ok || time.Since(entry.updatedAt) > threadTTL {
+		return "", false
+	}
+
+	entry.updatedAt = time.Now()
+	return entry.targetRootID, true
+}
+
+// SetTargetRoot 记录源线程在转发目标中对应的根消息ID
+func (t *ThreadTracker) SetTargetRoot(sourceRootID, target, targetRootID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneLocked()
+	t.threads[threadKey(sourceRootID, target)] = &threadEntry{
+		targetRootID: targetRootID,
+		updatedAt:    time.Now(),
+	}
+}
+
+// pruneLocked 清理过期的映射，调用方需持有锁
+func (t *ThreadTracker) pruneLocked() {
+	for key, entry := range t.threads {
+		if time.Since(entry.updatedAt) > threadTTL {
+			delete(t.threads, key)
+		}
+	}
+}
+
+func threadKey(sourceRootID, target string) string {
+	return sourceRootID + "|" + target
+}
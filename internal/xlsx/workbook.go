@@ -0,0 +1,461 @@
+// Package xlsx 生成Office Open XML格式(.xlsx)的工作簿
+//
+// 只支持导出所需的功能: 多个工作表、加粗带底色的表头、冻结表头行、外部超链接、
+// 日期和日期时间格式，以及按内容估算的列宽。字符串以内联字符串写入。
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 单元格样式，与styles.xml中cellXfs的顺序一致
+const (
+	styleDefault = iota
+	styleHeader
+	styleDate
+	styleDateTime
+	styleLink
+)
+
+// 单元格和工作表名称的限制
+const (
+	maxCellLength  = 32767
+	maxSheetName   = 31
+	maxColumnWidth = 60
+	minColumnWidth = 8
+)
+
+// Cell 单元格: Value可以是nil、string、bool、整数、浮点数或time.Time；
+// 设置了Link时单元格是指向该地址的超链接
+type Cell struct {
+	Value interface{}
+	Link  string
+}
+
+// Sheet 工作表，第一行是表头
+type Sheet struct {
+	name   string
+	header []string
+	rows   [][]Cell
+}
+
+// AddRow 添加一行数据
+func (s *Sheet) AddRow(cells ...Cell) {
+	s.rows = append(s.rows, cells)
+}
+
+// Name 返回工作表名称(已去除不允许的字符并保证唯一)
+func (s *Sheet) Name() string {
+	return s.name
+}
+
+// Workbook 工作簿
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// NewWorkbook 创建空工作簿
+func NewWorkbook() *Workbook {
+	return &Workbook{}
+}
+
+// AddSheet 添加工作表，header为表头(加粗、带底色并冻结)
+//
+// 名称中不允许的字符([]:*?/\)替换为下划线，超过31个字符时截断，与已有工作表重名时追加序号。
+func (w *Workbook) AddSheet(name string, header ...string) *Sheet {
+	sheet := &Sheet{name: w.uniqueSheetName(name), header: header}
+	w.sheets = append(w.sheets, sheet)
+	return sheet
+}
+
+// uniqueSheetName 返回合法且不与已有工作表重复的名称(不区分大小写)
+func (w *Workbook) uniqueSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.Trim(strings.TrimSpace(name), "'"))
+	if name == "" {
+		name = "Sheet"
+	}
+
+	candidate := truncateRunes(name, maxSheetName)
+	for n := 2; w.hasSheet(candidate); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncateRunes(name, maxSheetName-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+// hasSheet 判断是否已有同名工作表
+func (w *Workbook) hasSheet(name string) bool {
+	for _, sheet := range w.sheets {
+		if strings.EqualFold(sheet.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write 将工作簿写为.xlsx，没有工作表时写入一个空工作表
+func (w *Workbook) Write(out io.Writer) error {
+	sheets := w.sheets
+	if len(sheets) == 0 {
+		sheets = []*Sheet{{name: "Sheet1"}}
+	}
+
+	zw := zip.NewWriter(out)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", contentTypes(len(sheets))},
+		{"_rels/.rels", []byte(rootRels)},
+		{"xl/workbook.xml", workbookXML(sheets)},
+		{"xl/_rels/workbook.xml.rels", workbookRels(len(sheets))},
+		{"xl/styles.xml", []byte(stylesXML)},
+	}
+	for i, sheet := range sheets {
+		sheetXML, links := sheet.xml()
+		files = append(files, struct {
+			name    string
+			content []byte
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheetXML})
+		if len(links) > 0 {
+			files = append(files, struct {
+				name    string
+				content []byte
+			}{fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", i+1), sheetRels(links)})
+		}
+	}
+
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(file.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xml 生成工作表XML，返回超链接地址(按关系ID rId1、rId2...的顺序)
+func (s *Sheet) xml() ([]byte, []string) {
+	columns := len(s.header)
+	for _, row := range s.rows {
+		columns = max(columns, len(row))
+	}
+	widths := make([]int, columns)
+	for i, name := range s.header {
+		widths[i] = displayWidth(name)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+
+	lastRow := len(s.rows)
+	if len(s.header) > 0 {
+		lastRow++
+	}
+	if columns > 0 && lastRow > 0 {
+		fmt.Fprintf(&buf, `<dimension ref="A1:%s"/>`, cellRef(columns-1, lastRow))
+	}
+
+	// 冻结表头行
+	buf.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	if len(s.header) > 0 {
+		buf.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+			`<selection pane="bottomLeft" activeCell="A2" sqref="A2"/>`)
+	}
+	buf.WriteString(`</sheetView></sheetViews>`)
+
+	// 先生成数据以计算列宽，列宽必须写在sheetData之前
+	var data bytes.Buffer
+	var links []string
+	var linkRefs []string
+	data.WriteString(`<sheetData>`)
+	rowNum := 1
+	if len(s.header) > 0 {
+		fmt.Fprintf(&data, `<row r="%d">`, rowNum)
+		for i, name := range s.header {
+			writeCell(&data, cellRef(i, rowNum), name, styleHeader)
+		}
+		data.WriteString(`</row>`)
+		rowNum++
+	}
+	for _, row := range s.rows {
+		fmt.Fprintf(&data, `<row r="%d">`, rowNum)
+		for i, cell := range row {
+			ref := cellRef(i, rowNum)
+			style := styleDefault
+			if cell.Link != "" {
+				style = styleLink
+				links = append(links, cell.Link)
+				linkRefs = append(linkRefs, ref)
+			}
+			widths[i] = max(widths[i], writeCell(&data, ref, cell.Value, style))
+		}
+		data.WriteString(`</row>`)
+		rowNum++
+	}
+	data.WriteString(`</sheetData>`)
+
+	if columns > 0 {
+		buf.WriteString(`<cols>`)
+		for i, width := range widths {
+			width = min(max(width+2, minColumnWidth), maxColumnWidth)
+			fmt.Fprintf(&buf, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+		}
+		buf.WriteString(`</cols>`)
+	}
+	buf.Write(data.Bytes())
+
+	if len(links) > 0 {
+		buf.WriteString(`<hyperlinks>`)
+		for i, ref := range linkRefs {
+			fmt.Fprintf(&buf, `<hyperlink ref="%s" r:id="rId%d"/>`, ref, i+1)
+		}
+		buf.WriteString(`</hyperlinks>`)
+	}
+
+	buf.WriteString(`</worksheet>`)
+	return buf.Bytes(), links
+}
+
+// writeCell 写入一个单元格，返回内容的显示宽度(用于估算列宽)
+func writeCell(buf *bytes.Buffer, ref string, value interface{}, style int) int {
+	styleAttr := ""
+	if style != styleDefault {
+		styleAttr = fmt.Sprintf(` s="%d"`, style)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		if style != styleDefault {
+			fmt.Fprintf(buf, `<c r="%s"%s/>`, ref, styleAttr)
+		}
+		return 0
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(buf, `<c r="%s" t="b"%s><v>%d</v></c>`, ref, styleAttr, b)
+		return 5
+	case time.Time:
+		if v.IsZero() {
+			return 0
+		}
+		dateStyle, width := styleDateTime, 16
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			dateStyle, width = styleDate, 10
+		}
+		if style == styleDefault {
+			styleAttr = fmt.Sprintf(` s="%d"`, dateStyle)
+		}
+		fmt.Fprintf(buf, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, formatNumber(excelSerial(v)))
+		return width
+	case string:
+		return writeString(buf, ref, styleAttr, v)
+	}
+
+	if number, ok := toFloat(value); ok {
+		text := formatNumber(number)
+		fmt.Fprintf(buf, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, text)
+		return len(text)
+	}
+	return writeString(buf, ref, styleAttr, fmt.Sprint(value))
+}
+
+// writeString 以内联字符串写入单元格
+func writeString(buf *bytes.Buffer, ref, styleAttr, text string) int {
+	text = truncateRunes(text, maxCellLength)
+	fmt.Fprintf(buf, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, styleAttr)
+	_ = xml.EscapeText(buf, []byte(text))
+	buf.WriteString(`</t></is></c>`)
+	return displayWidth(text)
+}
+
+// toFloat 将数值类型转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+	return 0, false
+}
+
+// formatNumber 以最短的十进制表示输出数值
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// excelEpoch Excel(1900日期系统)的序列号起点
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// excelSerial 将时间转换为Excel日期序列号，按时间自身的时区取日期和时刻
+func excelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	serial := wall.Sub(excelEpoch).Hours() / 24
+	// 精确到毫秒，避免浮点误差显示为59.999秒
+	return math.Round(serial*86400000) / 86400000
+}
+
+// cellRef 返回从0开始的列号和从1开始的行号对应的单元格引用，如A1
+func cellRef(column, row int) string {
+	return columnName(column) + strconv.Itoa(row)
+}
+
+// columnName 返回从0开始的列号对应的列名，如0->A、26->AA
+func columnName(column int) string {
+	name := ""
+	for column >= 0 {
+		name = string(rune('A'+column%26)) + name
+		column = column/26 - 1
+	}
+	return name
+}
+
+// displayWidth 估算文本的显示宽度: 全角字符按2计算，多行文本取最长的一行
+func displayWidth(text string) int {
+	widest := 0
+	for _, line := range strings.Split(text, "\n") {
+		width := 0
+		for _, r := range line {
+			if r >= 0x1100 {
+				width += 2
+			} else {
+				width++
+			}
+		}
+		widest = max(widest, width)
+	}
+	return widest
+}
+
+// truncateRunes 截断到最多n个字符
+func truncateRunes(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n])
+}
+
+// contentTypes 生成[Content_Types].xml
+func contentTypes(sheets int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&buf, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	buf.WriteString(`</Types>`)
+	return buf.Bytes()
+}
+
+// rootRels 包的根关系，指向工作簿
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// workbookXML 生成工作簿XML，工作表的关系ID为rId1..rIdN
+func workbookXML(sheets []*Sheet) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		buf.WriteString(`<sheet name="`)
+		_ = xml.EscapeText(&buf, []byte(sheet.name))
+		fmt.Fprintf(&buf, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	buf.WriteString(`</sheets></workbook>`)
+	return buf.Bytes()
+}
+
+// workbookRels 生成工作簿的关系: 工作表rId1..rIdN，样式rId(N+1)
+func workbookRels(sheets int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	buf.WriteString(`</Relationships>`)
+	return buf.Bytes()
+}
+
+// sheetRels 生成工作表的超链接关系
+func sheetRels(links []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, link := range links {
+		fmt.Fprintf(&buf, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="`, i+1)
+		_ = xml.EscapeText(&buf, []byte(link))
+		buf.WriteString(`" TargetMode="External"/>`)
+	}
+	buf.WriteString(`</Relationships>`)
+	return buf.Bytes()
+}
+
+// stylesXML 样式: 表头(白色粗体、蓝色底色)、日期、日期时间和超链接(蓝色下划线)
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2">` +
+	`<numFmt numFmtId="164" formatCode="yyyy-mm-dd"/>` +
+	`<numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm"/>` +
+	`</numFmts>` +
+	`<fonts count="3">` +
+	`<font><sz val="11"/><name val="Calibri"/><family val="2"/></font>` +
+	`<font><b/><sz val="11"/><color rgb="FFFFFFFF"/><name val="Calibri"/><family val="2"/></font>` +
+	`<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/><family val="2"/></font>` +
+	`</fonts>` +
+	`<fills count="3">` +
+	`<fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FF4472C4"/><bgColor indexed="64"/></patternFill></fill>` +
+	`</fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,237 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unzip 写出工作簿并返回包中的所有文件
+func unzip(t *testing.T, w *Workbook) map[string][]byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = data
+	}
+	return files
+}
+
+// worksheet 工作表XML中测试关心的部分
+type worksheet struct {
+	Pane *struct {
+		YSplit string `xml:"ySplit,attr"`
+		State  string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R    string `xml:"r,attr"`
+			S    string `xml:"s,attr"`
+			T    string `xml:"t,attr"`
+			V    string `xml:"v"`
+			Text string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	Hyperlinks []struct {
+		Ref string `xml:"ref,attr"`
+		ID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"hyperlinks>hyperlink"`
+}
+
+// relationships 关系XML
+type relationships struct {
+	Relationships []struct {
+		ID         string `xml:"Id,attr"`
+		Target     string `xml:"Target,attr"`
+		TargetMode string `xml:"TargetMode,attr"`
+	} `xml:"Relationship"`
+}
+
+func decodeXML(t *testing.T, files map[string][]byte, name string, v interface{}) {
+	t.Helper()
+	data, ok := files[name]
+	if !ok {
+		t.Fatalf("%s missing from the package", name)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+}
+
+func TestWorkbookWrite(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+
+	w := NewWorkbook()
+	bugs := w.AddSheet("R&D Bugs", "Key", "Created", "Updated", "Summary", "Parent")
+	bugs.AddRow(
+		Cell{Value: "A-1", Link: "https://jira.example.com/browse/A-1?filter=a&b=<1>"},
+		Cell{Value: created},
+		Cell{Value: updated},
+		Cell{Value: `fails with "<nil>" & panics`},
+		Cell{},
+	)
+	bugs.AddRow(
+		Cell{Value: "A-2", Link: "https://jira.example.com/browse/A-2"},
+		Cell{Value: time.Time{}},
+		Cell{Value: nil},
+		Cell{Value: 3.5},
+		Cell{Value: "A-1", Link: "https://jira.example.com/browse/A-1?filter=a&b=<1>"},
+	)
+	w.AddSheet("Empty")
+	files := unzip(t, w)
+
+	var sheet worksheet
+	decodeXML(t, files, "xl/worksheets/sheet1.xml", &sheet)
+	if sheet.Pane == nil || sheet.Pane.YSplit != "1" || sheet.Pane.State != "frozen" {
+		t.Errorf("header row is not frozen: %+v", sheet.Pane)
+	}
+	if !bytes.Contains(files["xl/worksheets/sheet1.xml"], []byte(`<dimension ref="A1:E3"/>`)) {
+		t.Error("dimension does not cover the header and both rows")
+	}
+
+	// 表头使用表头样式，日期使用样式2，日期时间使用样式3，序列号由excelSerial计算
+	header, first := sheet.Rows[0].Cells, sheet.Rows[1].Cells
+	if header[0].S != "1" || header[0].Text != "Key" {
+		t.Errorf("header cell = %+v", header[0])
+	}
+	if first[1].S != "2" || first[1].V != "45292" || first[1].V != formatNumber(excelSerial(created)) {
+		t.Errorf("date cell = %+v, want style 2 and serial 45292", first[1])
+	}
+	if first[2].S != "3" || first[2].V != "45292.75" {
+		t.Errorf("date-time cell = %+v, want style 3 and serial 45292.75", first[2])
+	}
+	if first[0].S != "4" || first[3].Text != `fails with "<nil>" & panics` {
+		t.Errorf("link cell = %+v, text cell = %+v", first[0], first[3])
+	}
+	// 零时间和nil不写入单元格，带链接的空单元格保留样式
+	second := sheet.Rows[2].Cells
+	var refs []string
+	for _, cell := range second {
+		refs = append(refs, cell.R)
+	}
+	if strings.Join(refs, ",") != "A3,D3,E3" || second[1].V != "3.5" {
+		t.Errorf("second row cells = %+v", second)
+	}
+
+	// 超链接按单元格顺序编号，关系中的地址经过XML转义
+	var links []string
+	for _, link := range sheet.Hyperlinks {
+		links = append(links, link.Ref+"="+link.ID)
+	}
+	if strings.Join(links, " ") != "A2=rId1 A3=rId2 E3=rId3" {
+		t.Errorf("hyperlinks = %v", links)
+	}
+	var rels relationships
+	decodeXML(t, files, "xl/worksheets/_rels/sheet1.xml.rels", &rels)
+	var targets []string
+	for _, rel := range rels.Relationships {
+		targets = append(targets, rel.ID+"="+rel.Target)
+		if rel.TargetMode != "External" {
+			t.Errorf("relationship %s is not external", rel.ID)
+		}
+	}
+	want := []string{
+		"rId1=https://jira.example.com/browse/A-1?filter=a&b=<1>",
+		"rId2=https://jira.example.com/browse/A-2",
+		"rId3=https://jira.example.com/browse/A-1?filter=a&b=<1>",
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("relationship targets = %v, want %v", targets, want)
+	}
+	if !bytes.Contains(files["xl/worksheets/_rels/sheet1.xml.rels"], []byte(`Target="https://jira.example.com/browse/A-1?filter=a&amp;b=&lt;1&gt;"`)) {
+		t.Error("hyperlink target is not XML-escaped")
+	}
+
+	// 没有表头的工作表不冻结，也没有超链接关系
+	var empty worksheet
+	decodeXML(t, files, "xl/worksheets/sheet2.xml", &empty)
+	if empty.Pane != nil || len(empty.Rows) != 0 {
+		t.Errorf("empty sheet = %+v", empty)
+	}
+	if _, ok := files["xl/worksheets/_rels/sheet2.xml.rels"]; ok {
+		t.Error("sheet without links has a relationships part")
+	}
+	if !bytes.Contains(files["xl/workbook.xml"], []byte(`<sheet name="R&amp;D Bugs" sheetId="1" r:id="rId1"/>`)) {
+		t.Errorf("workbook.xml = %s", files["xl/workbook.xml"])
+	}
+}
+
+func TestWriteEmptyWorkbook(t *testing.T) {
+	files := unzip(t, NewWorkbook())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s missing from the package", name)
+		}
+	}
+}
+
+func TestExcelSerial(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	tests := []struct {
+		time time.Time
+		want float64
+	}{
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), 61},
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 45292},
+		{time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC), 45292.25},
+		// 按时间自身的时区取日期，不转换为UTC
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, shanghai), 45292},
+		{time.Date(2024, 1, 1, 12, 0, 0, 0, shanghai), 45292.5},
+		// 精确到毫秒
+		{time.Date(2024, 1, 1, 0, 0, 59, 999999999, time.UTC), 45292 + 60.0/86400},
+	}
+	for _, tt := range tests {
+		if got := excelSerial(tt.time); got != tt.want {
+			t.Errorf("excelSerial(%s) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueSheetName(t *testing.T) {
+	long := strings.Repeat("abcdefghij", 4)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Bug", "Bug"},
+		{"bug", "bug (2)"},
+		{"BUG", "BUG (3)"},
+		{`a/b:c*d?[e]\f`, "a_b_c_d__e__f"},
+		{"  'Quoted'  ", "Quoted"},
+		{"", "Sheet"},
+		{"   ", "Sheet (2)"},
+		{long, long[:31]},
+		{long, long[:27] + " (2)"},
+		{strings.Repeat("任务", 20), strings.Repeat("任务", 15) + "任"},
+	}
+
+	w := NewWorkbook()
+	for _, tt := range tests {
+		if got := w.AddSheet(tt.name).Name(); got != tt.want {
+			t.Errorf("AddSheet(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
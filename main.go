@@ -6,13 +6,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"my-scheduler-go/internal/api"
 	"my-scheduler-go/internal/config"
-	"my-scheduler-go/internal/mattermost"
-	"my-scheduler-go/internal/models"
 	"my-scheduler-go/internal/repository"
 	"my-scheduler-go/internal/scheduler"
 	"my-scheduler-go/internal/service"
@@ -44,52 +44,115 @@ func main() {
 	// 设置最大并发度
 	schedService.SetMaxConcurrency(appConfig.Scheduler.Concurrency)
 
+	// 开发模式下启动进程内的模拟Mattermost、Confluence和Jira服务器，替代真实服务器
+	// (模拟服务器只包含在使用dev构建标签的构建中，见dev.go)
+	var devEnv *devEnvironment
+	if appConfig.Environment == "development" {
+		devEnv, err = startDevEnvironment(appConfig)
+		if err != nil {
+			log.Fatalf("Failed to start development environment: %v", err)
+		}
+	}
+
 	// 6. 初始化Mattermost服务
 	mattermostService := service.NewMattermostService(appConfig)
 	log.Println("[main] Mattermost service initialized")
 
-	// 7. 初始化Confluence服务
+	// 7. 初始化Confluence和Jira服务
 	confluenceService := service.NewConfluenceService(appConfig)
 	log.Println("[main] Confluence service initialized")
+	jiraService := service.NewJiraService(appConfig)
+	log.Printf("[main] Jira service initialized (environments: %s)", strings.Join(jiraService.Environments(), ", "))
 
 	// 8. 创建配置获取器
 	useMockData := appConfig.Environment == "development"
-	configFetcher := service.NewConfluenceConfigFetcher(confluenceService, appConfig, useMockData)
-	log.Println("[main] Configuration fetcher initialized")
+	confluenceFetcher := service.NewConfluenceConfigFetcher(confluenceService, appConfig, useMockData)
+	configFetcher, err := service.NewConfigurationFetcher(appConfig.ConfigSource.ConfigSourceSpec, confluenceFetcher)
+	if err != nil {
+		log.Fatalf("Failed to create configuration source: %v", err)
+	}
+	log.Printf("[main] Configuration fetcher initialized (%s)", appConfig.ConfigSource.Type)
 
-	// 9. 创建配置服务 (每180秒更新一次配置)
-	configService := scheduler.NewConfigurationService(configFetcher, 180*time.Second)
+	// 9. 创建配置服务 (默认每180秒更新一次配置)
+	refreshInterval := 180 * time.Second
+	if appConfig.ConfigSource.RefreshInterval > 0 {
+		refreshInterval = time.Duration(appConfig.ConfigSource.RefreshInterval) * time.Second
+	}
+	configService := scheduler.NewConfigurationService(configFetcher, refreshInterval)
+	if appConfig.ConfigSource.RefreshDebounce > 0 {
+		configService.SetDebounce(time.Duration(appConfig.ConfigSource.RefreshDebounce) * time.Second)
+	}
+	configService.SetGate(scheduler.ConfigGate{
+		MaxDropRatio:     appConfig.ConfigSource.MaxDropRatio,
+		MaxRowErrorRatio: appConfig.ConfigSource.MaxRowErrorRatio,
+	})
+	cacheFile := appConfig.ConfigSource.CacheFile
+	if cacheFile == "" {
+		cacheFile = filepath.Join(appConfig.Storage.Path, "configurations.json")
+	}
+	if err := configService.EnableCache(scheduler.NewConfigCache(cacheFile)); err != nil {
+		log.Printf("[main] Failed to load configuration cache %s: %v", cacheFile, err)
+	}
 	log.Println("[main] Configuration service initialized")
 
 	// 10. 创建Mattermost事件监听器
-	eventListener := mattermostService.CreateEventListener(useMockData)
+	eventListener := mattermostService.CreateEventListener()
 	log.Println("[main] Mattermost event listener created")
 
-	// 11. 添加事件过滤器
-	mattermostService.AddChannelFilter(eventListener, []string{appConfig.Mattermost.ChannelID})
-	mattermostService.AddEventTypeFilter(eventListener, []mattermost.EventType{
-		mattermost.EventTypePosted,
-		mattermost.EventTypeUserAdded,
-	})
+	// 11. 根据配置添加事件过滤器
+	if err := mattermostService.ConfigureEventFilters(eventListener); err != nil {
+		log.Fatalf("Failed to configure event filters: %v", err)
+	}
 	log.Println("[main] Event filters configured")
 
 	// 12. 创建Mattermost事件源
 	eventSource := scheduler.NewMattermostEventSource(repo, eventListener, configService)
 	log.Println("[main] Mattermost event source created")
 
+	// 加载路由规则(规则文件和Confluence配置表中的规则)
+	var staticRules []scheduler.RoutingRule
+	if path := appConfig.Routing.RulesFile; path != "" {
+		staticRules, err = scheduler.LoadRoutingRules(path)
+		if err != nil {
+			log.Fatalf("Failed to load routing rules: %v", err)
+		}
+	}
+	ruleEngine, err := scheduler.NewRuleEngine(staticRules, configService)
+	if err != nil {
+		log.Fatalf("Failed to create routing rule engine: %v", err)
+	}
+	eventSource.SetRuleEngine(ruleEngine)
+	log.Printf("[main] Routing rules loaded (%d static)", len(staticRules))
+
+	// 监听的频道和团队: 配置文件、规则文件以及Confluence配置中引用的频道，配置更新时同步更新
+	watchedChannels := service.StaticWatchedChannels(appConfig)
+	for _, rule := range staticRules {
+		watchedChannels = append(watchedChannels, rule.When.Channels...)
+	}
+	scheduler.NewChannelWatcher(configService, mattermostService.WatchFilter(), watchedChannels, appConfig.Mattermost.WatchTeams)
+	log.Println("[main] Channel watcher configured")
+
+	// 配置发生变化时将变更摘要发送到管理频道
+	configService.AddChangeListener(mattermostService.NotifyConfigChange)
+
 	// 13. 注册事件处理器
+	eventSource.RegisterProcessor("task_commands", scheduler.NewTaskCommandProcessor(repo, mattermostService))
 	eventSource.RegisterProcessor("posted_messages", scheduler.NewPostedMessageProcessor([]string{
 		"task", "schedule", "urgent", "important",
 	}))
 	eventSource.RegisterProcessor("user_added", scheduler.NewUserAddedProcessor())
+	eventSource.RegisterProcessor("post_edited", scheduler.NewPostEditedProcessor(repo, eventSource.PostTaskIndex()))
+	eventSource.RegisterProcessor("post_deleted", scheduler.NewPostDeletedProcessor(repo, eventSource.PostTaskIndex(), schedService))
 	log.Println("[main] Event processors registered")
 
-	// 14. 创建Mattermost任务处理器，但目前暂不使用
-	// 在完整实现中，这里会注册任务处理器到执行器
-	_ = service.NewMattermostTaskHandler(mattermostService, appConfig)
+	// 14. 创建Mattermost任务处理器
+	mattermostTaskHandler := service.NewMattermostTaskHandler(mattermostService, appConfig)
 	log.Println("[main] Mattermost task handler created")
 
-	// 15. 任务处理器配置
+	// 15. 任务处理器配置: 注册处理器，并在任务失败或等待审批时发送交互消息
+	executor.RegisterHandler("MATTERMOST", mattermostTaskHandler.HandleTask)
+	executor.RegisterHandler("MATTERMOST_EVENT", mattermostTaskHandler.HandleTask)
+	executor.AddStatusListener(mattermostTaskHandler.OnTaskStatusChanged)
 	log.Println("[main] Task handlers configured")
 
 	// 16. 启动各服务
@@ -108,13 +171,26 @@ func main() {
 	reportingService.Start()
 	log.Println("[main] Result reporting service started")
 
-	// 18. 开发模式下创建示例任务
-	if appConfig.Environment == "development" {
-		createExampleTasks(schedService)
+	// 由jira_sync和report_schedule配置生成定时任务，配置变化时同步；Jira导出结果保存为任务产物
+	artifactStore := repository.NewArtifactStore(filepath.Join(appConfig.Storage.Path, "artifacts"), appConfig.Storage.MaxArtifactsPerTask, repo)
+	jiraExportHandler := service.NewJiraExportHandler(jiraService, artifactStore, appConfig.Jira.Export)
+	executor.RegisterHandler(scheduler.TaskTagJiraExport, jiraExportHandler.HandleTask)
+	executor.RegisterHandler(scheduler.TaskTagReport, reportingService.HandleReportTask)
+	scheduler.NewConfigJobSync(configService, schedService)
+	log.Println("[main] Configuration job sync started")
+
+	// 18. 开发模式下创建示例任务，并在模拟服务器上回放场景
+	if devEnv != nil {
+		devEnv.Start(appConfig, schedService, configService)
 	}
 
 	// 19. 设置HTTP服务器和API路由
-	router := api.SetupRouter(repo, schedService, reportingService)
+	router := api.SetupRouter(repo, schedService, reportingService, appConfig,
+		api.WithEventListener(eventListener),
+		api.WithConfigurationService(configService),
+		api.WithJiraService(jiraService),
+		api.WithArtifactStore(artifactStore),
+	)
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -144,6 +220,11 @@ func main() {
 	eventSource.Stop()
 	log.Println("[main] Mattermost event source stopped")
 
+	// 停止模拟服务器
+	if devEnv != nil {
+		devEnv.Close()
+	}
+
 	// 停止配置服务
 	configService.Stop()
 	log.Println("[main] Configuration service stopped")
@@ -173,53 +254,3 @@ func setupLogging(appConfig *config.AppConfig) {
 
 	// TODO: 根据配置实现基于文件的日志记录
 }
-
-// createExampleTasks 创建一些示例任务用于开发目的
-func createExampleTasks(sched *scheduler.SchedulerService) {
-	// Example 1: 即时Mattermost任务
-	mattermostTask := &models.Task{
-		Name:     "Mattermost消息处理示例",
-		TaskType: models.TypeImmediate,
-		Status:   models.StatusPending,
-		Priority: models.PriorityHigh,
-		Tags:     []string{"MATTERMOST"},
-		Parameters: map[string]interface{}{
-			"channel_id":   "channel1",
-			"message":      "这是一条测试消息",
-			"forward_type": "notification",
-			"event_type":   "posted",
-			"channel_name": "测试频道",
-			"username":     "测试用户",
-			"notify_admin": "true",
-		},
-	}
-
-	// Example 2: 定时Mattermost任务
-	mattermostScheduledTask := &models.Task{
-		Name:     "Mattermost定时通知示例",
-		TaskType: models.TypeScheduled,
-		CronExpr: "0 0 9 * * *", // 每天早上9点
-		Status:   models.StatusPending,
-		Priority: models.PriorityMedium,
-		Tags:     []string{"MATTERMOST"},
-		Parameters: map[string]interface{}{
-			"channel_id":        "channel1",
-			"message":           "这是一条定时发送的通知",
-			"forward_type":      "channel_message",
-			"target_channel_id": "channel456",
-		},
-	}
-
-	// 添加任务
-	err := sched.AddTask(mattermostTask)
-	if err != nil {
-		log.Printf("[main] Failed to add example task 1: %v", err)
-	}
-
-	err = sched.AddTask(mattermostScheduledTask)
-	if err != nil {
-		log.Printf("[main] Failed to add example task 2: %v", err)
-	}
-
-	log.Println("[main] Example tasks created")
-}